@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-kit/log"
@@ -17,12 +18,33 @@ import (
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/peterbourgon/ff"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/solher/hunterio-test/entities/apikeys"
 	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/privacy"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/lib/cassette"
+	"github.com/solher/hunterio-test/lib/httputil"
+	"github.com/solher/hunterio-test/lib/otelutil"
+	"github.com/solher/hunterio-test/lib/promutil"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/postgres"
+	"github.com/solher/hunterio-test/services/auth"
 	"github.com/solher/hunterio-test/services/dataextraction"
-	"github.com/solher/toolbox/api"
+	"github.com/solher/hunterio-test/services/dataprotection"
+	"github.com/solher/hunterio-test/services/monitoring"
+	"github.com/solher/hunterio-test/services/notifications"
+	"github.com/solher/hunterio-test/services/retention"
+	"github.com/solher/toolbox"
 	_ "go.uber.org/automaxprocs"
 )
 
+// metricsNamespace prefixes the name of every Prometheus metric.
+const metricsNamespace = "hunterio"
+
 func main() {
 	if err := run(os.Args, os.Stdout); err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
@@ -34,14 +56,93 @@ func run(args []string, stdout io.Writer) error {
 	fs := flag.NewFlagSet("hunterio-test", flag.ExitOnError)
 	environment := fs.String("environment", "develop", "The deploy environment")
 	httpAddr := fs.String("http-addr", ":8080", "HTTP listen address")
+	maxBodyBytes := fs.Int64("max-body-bytes", 1<<20, "The maximum size of request bodies")
+	historyMaxPageSize := fs.Int("history-max-page-size", 100, "The maximum number of runs per history page, also the default")
+	adminAddr := fs.String("admin-addr", ":9090", "Admin HTTP listen address, serving the metrics")
 	postgresHost := fs.String("postgres-host", "localhost", "The Postgres database host")
 	postgresPort := fs.String("postgres-port", "5432", "The Postgres database port")
 	postgresDatabase := fs.String("postgres-database", "hunterio", "The Postgres database name")
 	postgresUser := fs.String("postgres-user", "hunterio", "The Postgres user")
 	postgresPassword := fs.String("postgres-password", "hunterio", "The Postgres user password")
+	postgresMaxConns := fs.Int("postgres-max-conns", 10, "The maximum number of Postgres connections serving the requests")
+	postgresJobsMaxConns := fs.Int("postgres-jobs-max-conns", 3, "The maximum number of Postgres connections of the background jobs: the webhook dispatcher, the watch scheduler and the purger")
+	autoMigrate := fs.Bool("auto-migrate", false, "Apply the pending database migrations at startup")
 	openAISecretKey := fs.String("openai-secret-key", "", "The OpenAI secret key")
+	openAIModel := fs.String("openai-model", dataextraction.DefaultModel, "The OpenAI model used for extraction")
+	bootstrapAdminKey := fs.String("bootstrap-admin-key", "", "An API key granted the admin scope without being stored, to create the first keys")
+	rateLimitPerMinute := fs.Int("rate-limit-per-minute", 60, "The requests allowed per minute and per API key, unless set on the key (unlimited if 0)")
+	rateLimitBurst := fs.Int("rate-limit-burst", 10, "The requests an API key can make in a burst")
+	budgetDailyTokens := fs.Int64("budget-daily-tokens", 0, "The OpenAI tokens allowed per day (unlimited if 0)")
+	budgetDailyCost := fs.Float64("budget-daily-cost", 0, "The OpenAI spending allowed per day in USD (unlimited if 0)")
+	budgetMonthlyTokens := fs.Int64("budget-monthly-tokens", 0, "The OpenAI tokens allowed per month (unlimited if 0)")
+	budgetMonthlyCost := fs.Float64("budget-monthly-cost", 0, "The OpenAI spending allowed per month in USD (unlimited if 0)")
+	quotaDailyTokens := fs.Int64("quota-daily-tokens", 0, "The OpenAI tokens allowed per client and per day (unlimited if 0)")
+	quotaDailyCost := fs.Float64("quota-daily-cost", 0, "The OpenAI spending allowed per client and per day in USD (unlimited if 0)")
+	quotaMonthlyTokens := fs.Int64("quota-monthly-tokens", 0, "The OpenAI tokens allowed per client and per month (unlimited if 0)")
+	quotaMonthlyCost := fs.Float64("quota-monthly-cost", 0, "The OpenAI spending allowed per client and per month in USD (unlimited if 0)")
+	budgetTruncate := fs.Bool("budget-truncate", false, "Truncate the pages exceeding the remaining budget instead of refusing them")
+	webhookPollInterval := fs.Duration("webhook-poll-interval", time.Second, "The time between two lookups of the webhook deliveries to send")
+	webhookBatchSize := fs.Int("webhook-batch-size", 10, "The maximum number of webhook deliveries sent per lookup")
+	webhookTimeout := fs.Duration("webhook-timeout", 10*time.Second, "The timeout of each webhook request")
+	webhookMaxAttempts := fs.Int("webhook-max-attempts", 8, "The number of attempts after which a webhook delivery fails")
+	webhookBackoff := fs.Duration("webhook-backoff", 30*time.Second, "The delay before the first webhook retry, doubled at each attempt")
+	webhookMaxBackoff := fs.Duration("webhook-max-backoff", time.Hour, "The maximum delay between two webhook attempts")
+	watchPollInterval := fs.Duration("watch-poll-interval", 10*time.Second, "The time between two lookups of the watches to run")
+	watchBatchSize := fs.Int("watch-batch-size", 5, "The maximum number of watches run per lookup")
+	watchLease := fs.Duration("watch-lease", 10*time.Minute, "The time a batch of watches has to run before another instance can run them again")
+	retentionRawDays := fs.Int("retention-raw-days", 0, "The days every run is kept, the latest run of each URL excepted (forever if 0)")
+	retentionLatestDays := fs.Int("retention-latest-days", 0, "The days the latest run of each URL is kept (forever if 0, refused otherwise until the entities are kept aggregated)")
+	retentionInterval := fs.Duration("retention-interval", time.Hour, "The time between two purges of the runs past their retention")
+	retentionBatchSize := fs.Int("retention-batch-size", 1000, "The maximum number of runs deleted per transaction by the purges")
+	partitionsAhead := fs.Int("partitions-ahead", 3, "The number of monthly partitions of the runs created ahead of the current month")
+	traceExporter := fs.String("trace-exporter", "", "The trace exporter: otlp or stdout (disabled if empty)")
+	traceOTLPEndpoint := fs.String("trace-otlp-endpoint", "", "The host:port of the OTLP HTTP collector (defaults to the OTEL_EXPORTER_OTLP_* variables)")
+	traceOTLPInsecure := fs.Bool("trace-otlp-insecure", false, "Disable TLS on the OTLP connection")
+	traceFile := fs.String("trace-file", "", "The file the stdout trace exporter writes to (stdout if empty)")
+	traceSampleRatio := fs.Float64("trace-sample-ratio", 1, "The ratio of traces recorded")
+	cassettePath := fs.String("cassette-path", "", "The file HTTP interactions are recorded to or replayed from")
+	cassetteMode := fs.String("cassette-mode", "", "The cassette mode: record or replay (disabled if empty)")
 	ff.Parse(fs, args[1:], ff.WithEnvVarNoPrefix())
 
+	if *postgresMaxConns <= 0 {
+		return fmt.Errorf("invalid postgres max conns %d", *postgresMaxConns)
+	}
+	if *postgresJobsMaxConns <= 0 {
+		return fmt.Errorf("invalid postgres jobs max conns %d", *postgresJobsMaxConns)
+	}
+	if *historyMaxPageSize <= 0 {
+		return fmt.Errorf("invalid history max page size %d", *historyMaxPageSize)
+	}
+	purgerConfig := retention.PurgerConfig{
+		RawRuns:         time.Duration(*retentionRawDays) * 24 * time.Hour,
+		LatestRuns:      time.Duration(*retentionLatestDays) * 24 * time.Hour,
+		Interval:        *retentionInterval,
+		BatchSize:       *retentionBatchSize,
+		PartitionsAhead: *partitionsAhead,
+	}
+	if err := purgerConfig.Validate(); err != nil {
+		return err
+	}
+	dispatcherConfig := notifications.DispatcherConfig{
+		PollInterval: *webhookPollInterval,
+		BatchSize:    *webhookBatchSize,
+		Timeout:      *webhookTimeout,
+		MaxAttempts:  *webhookMaxAttempts,
+		Backoff:      *webhookBackoff,
+		MaxBackoff:   *webhookMaxBackoff,
+	}
+	if err := dispatcherConfig.Validate(); err != nil {
+		return err
+	}
+	schedulerConfig := monitoring.SchedulerConfig{
+		PollInterval: *watchPollInterval,
+		BatchSize:    *watchBatchSize,
+		Lease:        *watchLease,
+	}
+	if err := schedulerConfig.Validate(); err != nil {
+		return err
+	}
+
 	// Infrastructure
 	ctx := context.Background()
 	g := okrun.Group{}
@@ -56,43 +157,174 @@ func run(args []string, stdout io.Writer) error {
 	}
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
 
+	// Tracing
+	shutdownTracing, err := otelutil.Setup(ctx, otelutil.Config{
+		ServiceName:  "hunterio-test-api",
+		Environment:  *environment,
+		Exporter:     *traceExporter,
+		OTLPEndpoint: *traceOTLPEndpoint,
+		OTLPInsecure: *traceOTLPInsecure,
+		File:         *traceFile,
+		SampleRatio:  *traceSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
+	// Metrics
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics := dataextraction.NewMetrics(registry, metricsNamespace)
+
 	// Encoders
-	jsonRenderer := api.NewJSON(logger, (*environment != "prod"))
+	negotiator := render.NewNegotiator(logger, (*environment != "prod"))
 
 	// Databases
+	// The background jobs get their own pool, so that the purges and the partition DDL
+	// never wait behind the requests, nor hold the connections the requests need.
 	config, err := pgxpool.ParseConfig(fmt.Sprintf(
-		"user=%s password=%s dbname=%s port=%s sslmode=disable pool_min_conns=2 pool_max_conns=2",
-		*postgresUser, *postgresPassword, *postgresDatabase, *postgresPort,
+		"user=%s password=%s dbname=%s port=%s sslmode=disable pool_min_conns=2 pool_max_conns=%d",
+		*postgresUser, *postgresPassword, *postgresDatabase, *postgresPort, *postgresMaxConns,
 	))
 	if err != nil {
 		return err
 	}
 	config.ConnConfig.Host = *postgresHost
+	config.ConnConfig.RuntimeParams["search_path"] = postgres.Schema
 	db, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
+	registry.MustRegister(promutil.NewPgxPoolCollector(db, metricsNamespace, "requests"))
+	jobsConfig := config.Copy()
+	jobsConfig.MinConns = 0
+	jobsConfig.MaxConns = int32(*postgresJobsMaxConns)
+	jobsDB, err := pgxpool.NewWithConfig(ctx, jobsConfig)
+	if err != nil {
+		return err
+	}
+	defer jobsDB.Close()
+	registry.MustRegister(promutil.NewPgxPoolCollector(jobsDB, metricsNamespace, "jobs"))
+
+	// Migrations
+	if fs.Arg(0) == "migrate" {
+		return runMigrate(ctx, db, fs.Args()[1:], stdout)
+	}
+	if *autoMigrate {
+		migrator, err := postgres.NewMigrator(db)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range applied {
+			logger.Log("msg", fmt.Sprintf("applied migration %03d_%s", migration.Version, migration.Name))
+		}
+	}
+
+	// HTTP clients
+	httpCli := &http.Client{}
+	if *cassetteMode != "" {
+		transport, err := cassette.New(*cassettePath, cassette.Mode(*cassetteMode), nil)
+		if err != nil {
+			return err
+		}
+		httpCli.Transport = transport
+	}
+
+	// The webhooks are not recorded by the cassettes.
+	webhookHTTPCli := &http.Client{}
 
 	// OpenAI
 	openAICli := openai.NewClient(
 		option.WithAPIKey(*openAISecretKey),
+		option.WithHTTPClient(httpCli),
 	)
 
 	// Repositories
-	extractedDataRepo := extracteddata.NewPostgresRepository(db)
+	extractedDataRepo := extracteddata.NewTracingRepository(extracteddata.NewPostgresRepository(db), "postgresql")
+	apiKeysRepo := apikeys.NewPostgresRepository(db)
+	webhooksRepo := webhooks.NewPostgresRepository(db)
+	watchesRepo := watches.NewPostgresRepository(db)
+	privacyRepo := privacy.NewPostgresRepository(db)
+	jobsExtractedDataRepo := extracteddata.NewTracingRepository(extracteddata.NewPostgresRepository(jobsDB), "postgresql")
+	jobsWebhooksRepo := webhooks.NewPostgresRepository(jobsDB)
+	jobsWatchesRepo := watches.NewPostgresRepository(jobsDB)
+
+	// The data protection service is needed by the extractors, to drop the suppressed people.
+	dataProtectionService := dataprotection.NewService(logger, extractedDataRepo, watchesRepo, webhooksRepo, privacyRepo)
+
+	// Extractors
+	extractor := dataextraction.NewOpenAIExtractor(&openAICli, *openAIModel)
+	extractor = dataextraction.NewInstrumentingExtractor(extractor, *openAIModel, metrics)
+	extractor = dataextraction.NewSuppressingExtractor(extractor, dataProtectionService)
+	budget := dataextraction.Budget{
+		Daily:         dataextraction.Limits{Tokens: *budgetDailyTokens, CostUSD: *budgetDailyCost},
+		Monthly:       dataextraction.Limits{Tokens: *budgetMonthlyTokens, CostUSD: *budgetMonthlyCost},
+		ClientDaily:   dataextraction.Limits{Tokens: *quotaDailyTokens, CostUSD: *quotaDailyCost},
+		ClientMonthly: dataextraction.Limits{Tokens: *quotaMonthlyTokens, CostUSD: *quotaMonthlyCost},
+		Truncate:      *budgetTruncate,
+	}
+	if budget != (dataextraction.Budget{Truncate: budget.Truncate}) {
+		extractor = dataextraction.NewBudgetExtractor(logger, extractor, extractedDataRepo, *openAIModel, budget)
+	}
 
 	// Services
-	dataExtractionService := dataextraction.NewService(logger, &openAICli, extractedDataRepo)
+	notificationsService := notifications.NewService(logger, webhooksRepo)
+	dataExtractionService := dataextraction.NewService(logger, httpCli, extractor, extractedDataRepo, metrics)
+	dataExtractionService = dataextraction.NewNotifyingService(logger, dataExtractionService, notificationsService)
+	authService := auth.NewService(logger, apiKeysRepo, *bootstrapAdminKey)
+	monitoringService := monitoring.NewService(logger, watchesRepo)
+
+	// Webhook dispatcher
+	dispatcher := notifications.NewDispatcher(logger, webhookHTTPCli, jobsWebhooksRepo, dispatcherConfig)
+	dispatcherCtx, cancelDispatcher := context.WithCancel(ctx)
+	g.Add(func() error { return dispatcher.Run(dispatcherCtx) }, func(error) { cancelDispatcher() })
+
+	// Watch scheduler
+	scheduler := monitoring.NewScheduler(logger, dataExtractionService, notificationsService, jobsWatchesRepo, schedulerConfig)
+	schedulerCtx, cancelScheduler := context.WithCancel(ctx)
+	g.Add(func() error { return scheduler.Run(schedulerCtx) }, func(error) { cancelScheduler() })
+
+	// Retention purger, also creating the partitions of the runs ahead
+	purger := retention.NewPurger(logger, jobsExtractedDataRepo, postgres.NewPartitions(jobsDB), purgerConfig)
+	purgerCtx, cancelPurger := context.WithCancel(ctx)
+	g.Add(func() error { return purger.Run(purgerCtx) }, func(error) { cancelPurger() })
 
 	// App router
 	httpRouter := chi.NewRouter()
-	httpRouter.Mount("/extract", dataextraction.NewHTTPHandler(dataExtractionService, jsonRenderer))
+	httpRouter.Use(httputil.NewRequestID())
+	httpRouter.Use(toolbox.NewRequestContext())
+	httpRouter.Use(otelutil.NewHTTPMiddleware("github.com/solher/hunterio-test/cmd/api"))
+	httpRouter.Use(promutil.NewHTTPMiddleware(registry, metricsNamespace))
+	httpRouter.Use(httputil.NewAccessLogger(logger))
+	httpRouter.Use(httputil.NewRecoverer(logger, negotiator))
+	httpRouter.Use(httputil.NewBodyLimiter(*maxBodyBytes))
+	httpRouter.Use(auth.NewAuthMiddleware(authService, negotiator, *rateLimitPerMinute, *rateLimitBurst))
+	httpRouter.Mount("/extract", dataextraction.NewHTTPHandler(dataExtractionService, negotiator, *historyMaxPageSize))
+	httpRouter.Mount("/usage", dataextraction.NewUsageHTTPHandler(dataExtractionService, negotiator))
+	httpRouter.Mount("/search", dataextraction.NewSearchHTTPHandler(dataExtractionService, negotiator, *historyMaxPageSize))
+	httpRouter.Mount("/admin/keys", auth.NewHTTPHandler(authService, negotiator))
+	httpRouter.Mount("/webhooks", notifications.NewHTTPHandler(notificationsService, negotiator))
+	httpRouter.Mount("/watches", monitoring.NewHTTPHandler(monitoringService, negotiator))
+	httpRouter.Mount("/privacy", dataprotection.NewHTTPHandler(dataProtectionService, negotiator))
 
 	logger.Log("msg", fmt.Sprintf("listening on %s (HTTP)", *httpAddr))
 	httpServer := &http.Server{Addr: *httpAddr, Handler: httpRouter}
 	g.Add(func() error { return httpServer.ListenAndServe() }, func(error) { httpServer.Shutdown(ctx) })
 
+	// Admin router
+	adminRouter := chi.NewRouter()
+	adminRouter.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+
+	logger.Log("msg", fmt.Sprintf("listening on %s (admin HTTP)", *adminAddr))
+	adminServer := &http.Server{Addr: *adminAddr, Handler: adminRouter}
+	g.Add(func() error { return adminServer.ListenAndServe() }, func(error) { adminServer.Shutdown(ctx) })
+
 	g.Add(func() error {
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
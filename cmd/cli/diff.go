@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// runDiff runs the `diff` subcommand, printing what changed between two runs of a URL.
+func runDiff(ctx context.Context, service dataextraction.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fromID := fs.Uint64("from-id", 0, "The ID of the base run (defaults to the run preceding the target)")
+	toID := fs.Uint64("to-id", 0, "The ID of the target run (defaults to the most recent run)")
+	asJSON := fs.Bool("json", false, "Print the diff as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		return fmt.Errorf("url is required as first argument")
+	}
+	diff, err := service.DiffExtractedData(ctx, fs.Arg(0), *fromID, *toID)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		prettyDiff, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", prettyDiff)
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%s: run %d (%s) -> run %d (%s)\n",
+		diff.URL, diff.FromID, diff.FromCreatedAt.Format(time.RFC3339), diff.ToID, diff.ToCreatedAt.Format(time.RFC3339))
+	if diff.Empty() {
+		fmt.Fprintln(stdout, "no changes")
+		return nil
+	}
+	for _, person := range diff.People.Added {
+		fmt.Fprintf(stdout, "+ person %s\n", person.FullName)
+	}
+	for _, person := range diff.People.Removed {
+		fmt.Fprintf(stdout, "- person %s\n", person.FullName)
+	}
+	for _, change := range diff.People.Changed {
+		printFieldChanges(stdout, "person "+change.To.FullName, change.Fields)
+	}
+	for _, company := range diff.Companies.Added {
+		fmt.Fprintf(stdout, "+ company %s\n", company.Name)
+	}
+	for _, company := range diff.Companies.Removed {
+		fmt.Fprintf(stdout, "- company %s\n", company.Name)
+	}
+	for _, change := range diff.Companies.Changed {
+		printFieldChanges(stdout, "company "+change.To.Name, change.Fields)
+	}
+	return nil
+}
+
+func printFieldChanges(w io.Writer, entity string, fields []dataextraction.FieldChange) {
+	for _, field := range fields {
+		fmt.Fprintf(w, "~ %s: %s %s -> %s\n", entity, field.Field, jsonValue(field.From), jsonValue(field.To))
+	}
+}
+
+// jsonValue formats a field value as in the JSON output, so that empty strings remain visible.
+func jsonValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// runExport runs the `export` subcommand, streaming the extracted people and companies to stdout or a file.
+func runExport(ctx context.Context, service dataextraction.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	kind := fs.String("kind", dataextraction.ExportHistory, "What to export: history (every run), people or companies (each once, as last found)")
+	format := fs.String("format", dataextraction.FormatCSV, "The format: csv, ndjson or xlsx")
+	columns := fs.String("columns", "", "The comma separated columns to export (every column of the kind if empty)")
+	url := fs.String("url", "", "Only export the runs of this URL")
+	from := fs.String("from", "", "The start of the period, as a date or a RFC 3339 timestamp")
+	to := fs.String("to", "", "The end of the period, as a date or a RFC 3339 timestamp")
+	output := fs.String("output", "", "The file to write to (stdout if empty)")
+	fs.Parse(args)
+
+	req := dataextraction.ExportRequest{
+		Kind:   *kind,
+		Format: *format,
+		URL:    *url,
+	}
+	if *columns != "" {
+		req.Columns = strings.Split(*columns, ",")
+	}
+	var err error
+	if req.CreatedAtFrom, err = parseTime(*from); err != nil {
+		return err
+	}
+	if req.CreatedAtTo, err = parseTime(*to); err != nil {
+		return err
+	}
+	if len(*to) == len(time.DateOnly) {
+		// A date includes the whole day.
+		req.CreatedAtTo = req.CreatedAtTo.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	if *output == "" {
+		return service.ExportExtractedData(ctx, stdout, req)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := service.ExportExtractedData(ctx, f, req); err != nil {
+		f.Close()
+		os.Remove(*output)
+		return err
+	}
+	return f.Close()
+}
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 
 	"github.com/go-kit/log"
@@ -15,7 +16,17 @@ import (
 	"github.com/openai/openai-go/option"
 	"github.com/peterbourgon/ff"
 	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/privacy"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/cassette"
+	"github.com/solher/hunterio-test/lib/otelutil"
+	"github.com/solher/hunterio-test/postgres"
 	"github.com/solher/hunterio-test/services/dataextraction"
+	"github.com/solher/hunterio-test/services/dataprotection"
+	"github.com/solher/hunterio-test/services/retention"
+	"github.com/solher/hunterio-test/sqlite"
 )
 
 func main() {
@@ -27,61 +38,163 @@ func main() {
 
 func run(args []string, stdout io.Writer) error {
 	fs := flag.NewFlagSet("hunterio-test", flag.ExitOnError)
+	workspace := fs.String("workspace", workspaces.DefaultID, "The workspace the runs are stored in and read from")
+	storage := fs.String("storage", "postgres", "The storage backend: postgres, sqlite or memory (nothing is kept after the run)")
+	dbPath := fs.String("db-path", "hunterio.db", "The SQLite database file")
 	postgresHost := fs.String("postgres-host", "localhost", "The Postgres database host")
 	postgresPort := fs.String("postgres-port", "5432", "The Postgres database port")
 	postgresDatabase := fs.String("postgres-database", "hunterio", "The Postgres database name")
 	postgresUser := fs.String("postgres-user", "hunterio", "The Postgres user")
 	postgresPassword := fs.String("postgres-password", "hunterio", "The Postgres user password")
 	openAISecretKey := fs.String("openai-secret-key", "", "The OpenAI secret key")
+	openAIModel := fs.String("openai-model", dataextraction.DefaultModel, "The OpenAI model used for extraction")
+	traceExporter := fs.String("trace-exporter", "", "The trace exporter: otlp or stdout (disabled if empty)")
+	traceOTLPEndpoint := fs.String("trace-otlp-endpoint", "", "The host:port of the OTLP HTTP collector (defaults to the OTEL_EXPORTER_OTLP_* variables)")
+	traceOTLPInsecure := fs.Bool("trace-otlp-insecure", false, "Disable TLS on the OTLP connection")
+	traceFile := fs.String("trace-file", "", "The file the stdout trace exporter writes to (stdout if empty)")
+	traceSampleRatio := fs.Float64("trace-sample-ratio", 1, "The ratio of traces recorded")
+	cassettePath := fs.String("cassette-path", "", "The file HTTP interactions are recorded to or replayed from")
+	cassetteMode := fs.String("cassette-mode", "", "The cassette mode: record or replay (disabled if empty)")
+	format := fs.String("format", dataextraction.FormatJSON, "The output format of the extraction: json, vcard, hubspot or salesforce")
+	mapping := fs.String("mapping", "", "The header:field columns of the hubspot and salesforce formats, comma separated (the layout defaults if empty)")
 	ff.Parse(fs, args[1:], ff.WithEnvVarNoPrefix())
 
+	if !workspaces.ValidID(*workspace) {
+		return fmt.Errorf("invalid workspace %q", *workspace)
+	}
+
 	// Infrastructure
-	ctx := context.Background()
+	ctx := workspaces.WithID(context.Background(), *workspace)
 
 	// Loggers
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(stdout))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
 
-	// Databases
-	config, err := pgxpool.ParseConfig(fmt.Sprintf(
-		"user=%s password=%s dbname=%s port=%s sslmode=disable pool_min_conns=2 pool_max_conns=2",
-		*postgresUser, *postgresPassword, *postgresDatabase, *postgresPort,
-	))
+	// Tracing
+	shutdownTracing, err := otelutil.Setup(ctx, otelutil.Config{
+		ServiceName:  "hunterio-test-cli",
+		Environment:  "develop",
+		Exporter:     *traceExporter,
+		OTLPEndpoint: *traceOTLPEndpoint,
+		OTLPInsecure: *traceOTLPInsecure,
+		File:         *traceFile,
+		SampleRatio:  *traceSampleRatio,
+	})
 	if err != nil {
 		return err
 	}
-	config.ConnConfig.Host = *postgresHost
-	db, err := pgxpool.NewWithConfig(ctx, config)
-	if err != nil {
-		return err
+	defer shutdownTracing(context.Background())
+
+	// Databases and repositories. The watches and webhooks are only stored in Postgres.
+	var (
+		extractedDataRepo extracteddata.Repository
+		privacyRepo       privacy.Repository
+		partitions        retention.Partitions
+		watchesRepo       = watches.NewMemoryRepository()
+		webhooksRepo      = webhooks.NewMemoryRepository()
+	)
+	switch *storage {
+	case "postgres":
+		config, err := pgxpool.ParseConfig(fmt.Sprintf(
+			"user=%s password=%s dbname=%s port=%s sslmode=disable pool_min_conns=2 pool_max_conns=2",
+			*postgresUser, *postgresPassword, *postgresDatabase, *postgresPort,
+		))
+		if err != nil {
+			return err
+		}
+		config.ConnConfig.Host = *postgresHost
+		config.ConnConfig.RuntimeParams["search_path"] = postgres.Schema
+		db, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		extractedDataRepo = extracteddata.NewPostgresRepository(db)
+		privacyRepo = privacy.NewPostgresRepository(db)
+		watchesRepo = watches.NewPostgresRepository(db)
+		webhooksRepo = webhooks.NewPostgresRepository(db)
+		partitions = postgres.NewPartitions(db)
+	case "sqlite":
+		db, err := sqlite.Open(ctx, *dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		extractedDataRepo = extracteddata.NewSQLiteRepository(db)
+		privacyRepo = privacy.NewSQLiteRepository(db)
+	case "memory":
+		extractedDataRepo = extracteddata.NewMemoryRepository()
+		privacyRepo = privacy.NewMemoryRepository()
+	default:
+		return fmt.Errorf("unknown storage %q", *storage)
+	}
+	extractedDataRepo = extracteddata.NewTracingRepository(extractedDataRepo, *storage)
+
+	// HTTP clients
+	httpCli := &http.Client{}
+	if *cassetteMode != "" {
+		transport, err := cassette.New(*cassettePath, cassette.Mode(*cassetteMode), nil)
+		if err != nil {
+			return err
+		}
+		httpCli.Transport = transport
 	}
-	defer db.Close()
 
 	// OpenAI
 	openAICli := openai.NewClient(
 		option.WithAPIKey(*openAISecretKey),
+		option.WithHTTPClient(httpCli),
 	)
-	if *openAISecretKey == "" {
-		return errors.New("openai-secret-key is not set")
-	}
 
-	// Repositories
-	extractedDataRepo := extracteddata.NewPostgresRepository(db)
+	// The data protection service is needed by the extractors, to drop the suppressed people.
+	dataProtectionService := dataprotection.NewService(logger, extractedDataRepo, watchesRepo, webhooksRepo, privacyRepo)
+
+	// Extractors
+	extractor := dataextraction.NewOpenAIExtractor(&openAICli, *openAIModel)
+	extractor = dataextraction.NewSuppressingExtractor(extractor, dataProtectionService)
 
 	// Services
-	dataExtractionService := dataextraction.NewService(logger, &openAICli, extractedDataRepo)
+	dataExtractionService := dataextraction.NewService(logger, httpCli, extractor, extractedDataRepo, nil)
+
+	// Subcommands
+	switch fs.Arg(0) {
+	case "usage":
+		return runUsage(ctx, dataExtractionService, fs.Args()[1:], stdout)
+	case "diff":
+		return runDiff(ctx, dataExtractionService, fs.Args()[1:], stdout)
+	case "export":
+		return runExport(ctx, dataExtractionService, fs.Args()[1:], stdout)
+	case "runs":
+		return runRuns(ctx, dataExtractionService, fs.Args()[1:], stdout)
+	case "search":
+		return runSearch(ctx, dataExtractionService, fs.Args()[1:], stdout)
+	case "privacy":
+		return runPrivacy(ctx, dataProtectionService, fs.Args()[1:], stdout)
+	case "purge":
+		return runPurge(ctx, logger, extractedDataRepo, partitions, fs.Args()[1:], stdout)
+	}
 
-	// We read the URL from the first argument
+	// Otherwise, we read the URL from the first argument
+	if *openAISecretKey == "" && *cassetteMode != string(cassette.ModeReplay) {
+		return errors.New("openai-secret-key is not set")
+	}
 	if len(fs.Args()) < 1 {
 		return errors.New("url is required as first argument")
 	}
 	url := fs.Args()[0]
+	encoder, err := dataextraction.NewContactEncoder(*format, *mapping)
+	if err != nil {
+		return err
+	}
 
 	// We extract the data from the URL and print it to stdout.
 	extractedData, err := dataExtractionService.ExtractAndPersistFromURL(ctx, url)
 	if err != nil {
 		return err
 	}
+	if encoder != nil {
+		return encoder.Encode(stdout, []extracteddata.ExtractedData{*extractedData})
+	}
 	prettyData, err := json.MarshalIndent(extractedData, "", "  ")
 	if err != nil {
 		return err
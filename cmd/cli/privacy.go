@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/solher/hunterio-test/services/dataprotection"
+)
+
+// runPrivacy runs the `privacy` subcommand, honoring the requests of the data subjects:
+// `privacy search` and `privacy erase` find and erase a subject, `privacy suppressions` and
+// `privacy unsuppress <id>` manage the suppression list, and `privacy audit` prints the audit log.
+func runPrivacy(ctx context.Context, service dataprotection.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("privacy", flag.ExitOnError)
+	email := fs.String("email", "", "The email of the subject")
+	name := fs.String("name", "", "The full name of the subject")
+	phone := fs.String("phone", "", "The phone of the subject")
+	mode := fs.String("mode", dataprotection.ModeErase, "The erasure mode: erase removes the people, redact keeps their job titles")
+	limit := fs.Int("limit", 0, "The number of audit log entries printed (every entry if zero)")
+	if len(args) < 1 {
+		return fmt.Errorf("usage: privacy search|erase|suppressions|unsuppress|audit")
+	}
+	fs.Parse(args[1:])
+	subject := dataprotection.Subject{Email: *email, Name: *name, Phone: *phone}
+
+	var result any
+	switch args[0] {
+	case "search":
+		records, err := service.SearchSubject(ctx, subject)
+		if err != nil {
+			return err
+		}
+		result = records
+	case "erase":
+		entry, err := service.EraseSubject(ctx, subject, *mode)
+		if err != nil {
+			return err
+		}
+		result = entry
+	case "suppressions":
+		suppressions, err := service.ListSuppressions(ctx)
+		if err != nil {
+			return err
+		}
+		result = suppressions
+	case "unsuppress":
+		if fs.NArg() < 1 {
+			return fmt.Errorf("suppression id is required as first argument")
+		}
+		id, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid suppression id %q", fs.Arg(0))
+		}
+		if err := service.DeleteSuppression(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "suppression %d deleted\n", id)
+		return nil
+	case "audit":
+		entries, err := service.ListAuditEntries(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		result = entries
+	default:
+		return fmt.Errorf("unknown privacy command %q, want search, erase, suppressions, unsuppress or audit", args[0])
+	}
+
+	prettyResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s\n", prettyResult)
+	return nil
+}
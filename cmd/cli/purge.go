@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/services/retention"
+)
+
+// runPurge runs the `purge` subcommand, deleting the runs of every workspace past their retention,
+// or only counting them with --dry-run. The partitions are nil if the storage does not partition the runs.
+func runPurge(
+	ctx context.Context,
+	l log.Logger,
+	extractedDataRepo extracteddata.Repository,
+	partitions retention.Partitions,
+	args []string,
+	stdout io.Writer,
+) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	rawDays := fs.Int("raw-days", 0, "The days every run is kept, the latest run of each URL excepted")
+	latestDays := fs.Int("latest-days", 0, "The days the latest run of each URL is kept (forever if 0, refused otherwise until the entities are kept aggregated)")
+	batchSize := fs.Int("batch-size", 1000, "The maximum number of runs deleted per transaction")
+	dryRun := fs.Bool("dry-run", false, "Count the runs past their retention without deleting them")
+	fs.Parse(args)
+
+	if *rawDays == 0 {
+		return fmt.Errorf("raw-days is required")
+	}
+	config := retention.PurgerConfig{
+		RawRuns:    time.Duration(*rawDays) * 24 * time.Hour,
+		LatestRuns: time.Duration(*latestDays) * 24 * time.Hour,
+		BatchSize:  *batchSize,
+		// The CLI purges once, the interval only paces the API.
+		Interval: time.Hour,
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	report, err := retention.NewPurger(l, extractedDataRepo, partitions, config).Purge(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+	prettyReport, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s\n", prettyReport)
+	return nil
+}
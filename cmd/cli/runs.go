@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// runRuns runs the `runs` subcommand, printing a run by ID with `runs get <id>`,
+// or deleting it with `runs delete <id>`.
+func runRuns(ctx context.Context, service dataextraction.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: runs get|delete <id>")
+	}
+	id, err := strconv.ParseUint(fs.Arg(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid run id %q", fs.Arg(1))
+	}
+
+	switch fs.Arg(0) {
+	case "get":
+		run, err := service.GetExtractedData(ctx, id)
+		if err != nil {
+			return err
+		}
+		prettyRun, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", prettyRun)
+	case "delete":
+		if err := service.DeleteExtractedData(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "run %d deleted\n", id)
+	default:
+		return fmt.Errorf("unknown runs command %q, want get or delete", fs.Arg(0))
+	}
+	return nil
+}
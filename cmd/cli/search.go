@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// runSearch runs the `search` subcommand, printing the people and companies matching a query,
+// the best ranked first.
+func runSearch(ctx context.Context, service dataextraction.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	kind := fs.String("kind", "", "The kind of entities searched: person or company, both when empty")
+	limit := fs.Int("limit", 20, "The maximum number of matches")
+	fs.Parse(args)
+
+	matches, err := service.SearchEntities(ctx, dataextraction.SearchRequest{
+		Query: strings.Join(fs.Args(), " "),
+		Kind:  *kind,
+		Limit: *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "KIND\tNAME\tTITLE\tRANK\tRUN\tURL\n")
+	for _, match := range matches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.3f\t%d\t%s\n", match.Kind, match.Name, match.Title, match.Rank, match.RunID, match.URL)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// runUsage runs the `usage` subcommand, printing a summary of the OpenAI usage.
+func runUsage(ctx context.Context, service dataextraction.Service, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	from := fs.String("from", "", "The start of the period, as a date or a RFC 3339 timestamp")
+	to := fs.String("to", "", "The end of the period, as a date or a RFC 3339 timestamp")
+	groupBy := fs.String("group-by", extracteddata.UsageByDay, "The grouping: day, url or domain")
+	fs.Parse(args)
+
+	fromTime, err := parseTime(*from)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTime(*to)
+	if err != nil {
+		return err
+	}
+	if len(*to) == len(time.DateOnly) {
+		// A date includes the whole day.
+		toTime = toTime.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	aggregates, err := service.GetUsage(ctx, fromTime, toTime, *groupBy)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tRUNS\tPROMPT TOKENS\tCOMPLETION TOKENS\tCOST (USD)\tAVG LATENCY (MS)\t\n", strings.ToUpper(*groupBy))
+	total := extracteddata.UsageAggregate{Key: "total"}
+	for _, aggregate := range aggregates {
+		printUsageAggregate(w, aggregate)
+		total.Runs += aggregate.Runs
+		total.PromptTokens += aggregate.PromptTokens
+		total.CompletionTokens += aggregate.CompletionTokens
+		total.CostUSD += aggregate.CostUSD
+		total.AvgLatencyMs += aggregate.AvgLatencyMs * float64(aggregate.Runs)
+	}
+	if total.Runs > 0 {
+		total.AvgLatencyMs /= float64(total.Runs)
+	}
+	printUsageAggregate(w, total)
+	return w.Flush()
+}
+
+func printUsageAggregate(w io.Writer, aggregate extracteddata.UsageAggregate) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.4f\t%.0f\t\n",
+		aggregate.Key, aggregate.Runs, aggregate.PromptTokens, aggregate.CompletionTokens, aggregate.CostUSD, aggregate.AvgLatencyMs)
+}
+
+// parseTime parses a date or a RFC 3339 timestamp. An empty value returns the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
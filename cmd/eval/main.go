@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/peterbourgon/ff"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+func main() {
+	if err := run(os.Args, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("hunterio-test", flag.ExitOnError)
+	fixturesDir := fs.String("fixtures-dir", "cmd/eval/fixtures", "The directory containing the evaluation fixtures")
+	mode := fs.String("mode", modeReplay, "The extraction mode: replay (offline, from recordings), record or live")
+	format := fs.String("format", "markdown", "The report format: markdown or json")
+	output := fs.String("output", "", "The report output file (defaults to stdout)")
+	openAISecretKey := fs.String("openai-secret-key", "", "The OpenAI secret key")
+	openAIModel := fs.String("openai-model", dataextraction.DefaultModel, "The OpenAI model used for extraction")
+	ff.Parse(fs, args[1:], ff.WithEnvVarNoPrefix())
+
+	// Infrastructure
+	ctx := context.Background()
+
+	switch *mode {
+	case modeReplay:
+	case modeRecord, modeLive:
+		if *openAISecretKey == "" {
+			return errors.New("openai-secret-key is not set")
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", *mode)
+	}
+
+	// OpenAI
+	openAICli := openai.NewClient(
+		option.WithAPIKey(*openAISecretKey),
+	)
+
+	// Extractors
+	extractor := dataextraction.NewOpenAIExtractor(&openAICli, *openAIModel)
+
+	// We load the fixtures, each one being a directory containing a page and its expected extraction.
+	pagePaths, err := filepath.Glob(filepath.Join(*fixturesDir, "*", "page.html"))
+	if err != nil {
+		return err
+	}
+	if len(pagePaths) == 0 {
+		return fmt.Errorf("no fixtures found in %s", *fixturesDir)
+	}
+	sort.Strings(pagePaths)
+
+	report := &Report{
+		Model:         *openAIModel,
+		PromptVersion: dataextraction.PromptVersion,
+		Mode:          *mode,
+		GeneratedAt:   time.Now().UTC(),
+	}
+	total := map[string]Counts{}
+	failed := 0
+	for _, pagePath := range pagePaths {
+		caseDir := filepath.Dir(pagePath)
+		caseReport := CaseReport{Name: filepath.Base(caseDir)}
+
+		counts, err := evaluateCase(ctx, caseDir, newRecordingExtractor(recordingPath(caseDir, *openAIModel), *mode, extractor))
+		if err != nil {
+			caseReport.Error = err.Error()
+			failed++
+		} else {
+			caseReport.Overall, caseReport.Fields = fieldScores(counts)
+			for name, c := range counts {
+				total[name] = total[name].Add(c)
+			}
+		}
+		report.Cases = append(report.Cases, caseReport)
+	}
+	report.Overall, report.Fields = fieldScores(total)
+
+	// We write the report.
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "json":
+		err = writeJSON(w, report)
+	case "markdown":
+		err = writeMarkdown(w, report)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The report is still written, but a failed case must not pass for a low score.
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(report.Cases))
+	}
+	return nil
+}
+
+// evaluateCase runs the extractor on a fixture page and scores it against the expected extraction.
+func evaluateCase(ctx context.Context, caseDir string, extractor dataextraction.Extractor) (map[string]Counts, error) {
+	page, err := os.ReadFile(filepath.Join(caseDir, "page.html"))
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(caseDir, "expected.json"))
+	if err != nil {
+		return nil, err
+	}
+	expected := &dataextraction.Extraction{}
+	if err := json.Unmarshal(content, expected); err != nil {
+		return nil, err
+	}
+
+	predicted, err := extractor.ExtractDataFromString(ctx, string(page))
+	if err != nil {
+		return nil, err
+	}
+	return score(expected, predicted), nil
+}
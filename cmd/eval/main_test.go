@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestRunReplay replays the committed recordings of the default model, so that a fixture missing its
+// recording fails the tests rather than the offline evaluation.
+func TestRunReplay(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"eval", "--fixtures-dir=fixtures", "--format=json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	report := &Report{}
+	if err := json.Unmarshal(out.Bytes(), report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Cases) != 3 {
+		t.Fatalf("cases = %d, want 3", len(report.Cases))
+	}
+	for _, c := range report.Cases {
+		if c.Error != "" {
+			t.Errorf("%s: %s", c.Name, c.Error)
+		}
+		if c.Overall.Counts.TP == 0 {
+			t.Errorf("%s: no field matched", c.Name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+const (
+	modeReplay = "replay"
+	modeRecord = "record"
+	modeLive   = "live"
+)
+
+// newRecordingExtractor returns an extractor replaying the model responses saved at path,
+// or calling next and saving its responses there, depending on the mode.
+func newRecordingExtractor(path string, mode string, next dataextraction.Extractor) dataextraction.Extractor {
+	return &recordingExtractor{
+		path: path,
+		mode: mode,
+		next: next,
+	}
+}
+
+type recordingExtractor struct {
+	path string
+	mode string
+	next dataextraction.Extractor
+}
+
+func (e *recordingExtractor) ExtractDataFromString(ctx context.Context, data string) (*dataextraction.Extraction, error) {
+	if e.mode == modeReplay {
+		content, err := os.ReadFile(e.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("no recording found at %s, run with --mode=record first", e.path)
+			}
+			return nil, err
+		}
+		extraction := &dataextraction.Extraction{}
+		if err := json.Unmarshal(content, extraction); err != nil {
+			return nil, err
+		}
+		return extraction, nil
+	}
+
+	extraction, err := e.next.ExtractDataFromString(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if e.mode == modeRecord {
+		content, err := json.MarshalIndent(extraction, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(e.path, append(content, '\n'), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return extraction, nil
+}
+
+// recordingPath returns where the responses of a model are saved for a fixture.
+func recordingPath(caseDir string, model string) string {
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(model)
+	return filepath.Join(caseDir, "recordings", name+".json")
+}
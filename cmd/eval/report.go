@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report is the outcome of an evaluation run.
+type Report struct {
+	Model         string       `json:"model"`
+	PromptVersion string       `json:"prompt_version"`
+	Mode          string       `json:"mode"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+	Overall       FieldScore   `json:"overall"`
+	Fields        []FieldScore `json:"fields"`
+	Cases         []CaseReport `json:"cases"`
+}
+
+// CaseReport is the outcome of a single fixture.
+type CaseReport struct {
+	Name    string       `json:"name"`
+	Error   string       `json:"error,omitempty"`
+	Overall FieldScore   `json:"overall"`
+	Fields  []FieldScore `json:"fields,omitempty"`
+}
+
+// FieldScore holds the scores of a field.
+type FieldScore struct {
+	Field     string  `json:"field"`
+	Counts    Counts  `json:"counts"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+func newFieldScore(name string, c Counts) FieldScore {
+	return FieldScore{
+		Field:     name,
+		Counts:    c,
+		Precision: c.Precision(),
+		Recall:    c.Recall(),
+		F1:        c.F1(),
+	}
+}
+
+// fieldScores turns counts into scores ordered by field, along with their micro-averaged total.
+func fieldScores(counts map[string]Counts) (overall FieldScore, scores []FieldScore) {
+	total := Counts{}
+	for _, name := range fieldNames() {
+		c := counts[name]
+		total = total.Add(c)
+		scores = append(scores, newFieldScore(name, c))
+	}
+	return newFieldScore("overall", total), scores
+}
+
+func writeJSON(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeMarkdown(w io.Writer, report *Report) error {
+	fmt.Fprintf(w, "# Extraction evaluation\n\n")
+	fmt.Fprintf(w, "- Model: `%s`\n", report.Model)
+	fmt.Fprintf(w, "- Prompt version: `%s`\n", report.PromptVersion)
+	fmt.Fprintf(w, "- Mode: `%s`\n", report.Mode)
+	fmt.Fprintf(w, "- Generated at: %s\n\n", report.GeneratedAt.Format(time.RFC3339))
+
+	fmt.Fprintf(w, "## Fields\n\n")
+	fmt.Fprintf(w, "| Field | TP | FP | FN | Precision | Recall | F1 |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, s := range append(report.Fields, report.Overall) {
+		fmt.Fprintf(w, "| %s | %d | %d | %d | %.3f | %.3f | %.3f |\n",
+			s.Field, s.Counts.TP, s.Counts.FP, s.Counts.FN, s.Precision, s.Recall, s.F1)
+	}
+
+	fmt.Fprintf(w, "\n## Cases\n\n")
+	fmt.Fprintf(w, "| Case | Precision | Recall | F1 | Error |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---|\n")
+	for _, c := range report.Cases {
+		fmt.Fprintf(w, "| %s | %.3f | %.3f | %.3f | %s |\n",
+			c.Name, c.Overall.Precision, c.Overall.Recall, c.Overall.F1, c.Error)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// Counts holds the confusion counts of a field.
+type Counts struct {
+	TP int `json:"tp"`
+	FP int `json:"fp"`
+	FN int `json:"fn"`
+}
+
+// Add sums two counts.
+func (c Counts) Add(o Counts) Counts {
+	return Counts{TP: c.TP + o.TP, FP: c.FP + o.FP, FN: c.FN + o.FN}
+}
+
+// Precision returns the share of predicted values that were expected.
+func (c Counts) Precision() float64 {
+	if c.TP+c.FP == 0 {
+		return 0
+	}
+	return float64(c.TP) / float64(c.TP+c.FP)
+}
+
+// Recall returns the share of expected values that were predicted.
+func (c Counts) Recall() float64 {
+	if c.TP+c.FN == 0 {
+		return 0
+	}
+	return float64(c.TP) / float64(c.TP+c.FN)
+}
+
+// F1 returns the harmonic mean of precision and recall.
+func (c Counts) F1() float64 {
+	p, r := c.Precision(), c.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+type field[T any] struct {
+	name   string
+	values func(v T) []string
+}
+
+var companyFields = []field[companies.Company]{
+	{"company.name", func(c companies.Company) []string { return str(c.Name) }},
+	{"company.founded_year", func(c companies.Company) []string { return num(c.FoundedYear) }},
+	{"company.industry", func(c companies.Company) []string { return str(c.Industry) }},
+	{"company.revenue", func(c companies.Company) []string { return num(c.Revenue) }},
+	{"company.employees", func(c companies.Company) []string { return num(c.Employees) }},
+	{"company.locations", func(c companies.Company) []string { return str(c.Locations...) }},
+	{"company.tech_stack", func(c companies.Company) []string { return str(c.TechStack...) }},
+}
+
+var personFields = []field[people.Person]{
+	{"person.full_name", func(p people.Person) []string { return str(p.FullName) }},
+	{"person.job_title", func(p people.Person) []string { return str(p.JobTitle) }},
+	{"person.contact.email", func(p people.Person) []string { return str(p.Contact.Email) }},
+	{"person.contact.phone", func(p people.Person) []string { return phone(p.Contact.Phone) }},
+	{"person.contact.linkedin_url", func(p people.Person) []string { return link(p.Contact.LinkedinURL) }},
+	{"person.contact.x_url", func(p people.Person) []string { return link(p.Contact.XURL) }},
+	{"person.contact.instagram_url", func(p people.Person) []string { return link(p.Contact.InstagramURL) }},
+	{"person.contact.facebook_url", func(p people.Person) []string { return link(p.Contact.FacebookURL) }},
+}
+
+// fieldNames lists every scored field, in report order.
+func fieldNames() []string {
+	names := []string{}
+	for _, f := range companyFields {
+		names = append(names, f.name)
+	}
+	for _, f := range personFields {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// score compares an extraction against the expected one, field by field.
+func score(expected, predicted *dataextraction.Extraction) map[string]Counts {
+	counts := map[string]Counts{}
+	scoreEntities(counts, companyFields, expected.Companies, predicted.Companies, func(c companies.Company) string { return c.Name })
+	scoreEntities(counts, personFields, expected.People, predicted.People, func(p people.Person) string { return p.FullName })
+	return counts
+}
+
+// scoreEntities matches entities by normalized name, then compares each of their fields.
+// Unmatched predicted entities count as false positives, unmatched expected ones as false negatives.
+func scoreEntities[T any](counts map[string]Counts, fields []field[T], expected, predicted []T, name func(T) string) {
+	used := make([]bool, len(predicted))
+	for _, e := range expected {
+		match := -1
+		for i, p := range predicted {
+			if !used[i] && normalize(name(p)) == normalize(name(e)) {
+				match = i
+				break
+			}
+		}
+
+		for _, f := range fields {
+			if match < 0 {
+				counts[f.name] = counts[f.name].Add(Counts{FN: len(f.values(e))})
+				continue
+			}
+			counts[f.name] = counts[f.name].Add(compare(f.values(e), f.values(predicted[match])))
+		}
+		if match >= 0 {
+			used[match] = true
+		}
+	}
+
+	for i, p := range predicted {
+		if used[i] {
+			continue
+		}
+		for _, f := range fields {
+			counts[f.name] = counts[f.name].Add(Counts{FP: len(f.values(p))})
+		}
+	}
+}
+
+// compare computes the confusion counts of two sets of values.
+func compare(expected, predicted []string) Counts {
+	c := Counts{}
+	remaining := map[string]int{}
+	for _, v := range predicted {
+		remaining[v]++
+	}
+	for _, v := range expected {
+		if remaining[v] > 0 {
+			remaining[v]--
+			c.TP++
+		} else {
+			c.FN++
+		}
+	}
+	for _, n := range remaining {
+		c.FP += n
+	}
+	return c
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func str(values ...string) []string {
+	res := []string{}
+	for _, v := range values {
+		if v = normalize(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func num(v int) []string {
+	if v == 0 {
+		return []string{}
+	}
+	return []string{strconv.Itoa(v)}
+}
+
+func phone(v string) []string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, v)
+	return str(digits)
+}
+
+func link(v string) []string {
+	v = normalize(v)
+	v = strings.TrimPrefix(v, "https://")
+	v = strings.TrimPrefix(v, "http://")
+	v = strings.TrimPrefix(v, "www.")
+	return str(strings.TrimSuffix(v, "/"))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+func TestCounts(t *testing.T) {
+	for _, tc := range []struct {
+		counts                Counts
+		precision, recall, f1 float64
+	}{
+		{Counts{}, 0, 0, 0},
+		{Counts{TP: 3}, 1, 1, 1},
+		{Counts{TP: 2, FP: 2}, 0.5, 1, 2.0 / 3},
+		{Counts{TP: 1, FN: 3}, 1, 0.25, 0.4},
+		{Counts{FP: 1, FN: 1}, 0, 0, 0},
+	} {
+		for name, got := range map[string][2]float64{
+			"precision": {tc.counts.Precision(), tc.precision},
+			"recall":    {tc.counts.Recall(), tc.recall},
+			"f1":        {tc.counts.F1(), tc.f1},
+		} {
+			if math.Abs(got[0]-got[1]) > 1e-9 {
+				t.Errorf("%+v: %s = %f, want %f", tc.counts, name, got[0], got[1])
+			}
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		expected, predicted []string
+		want                Counts
+	}{
+		{[]string{}, []string{}, Counts{}},
+		{[]string{"go", "rust"}, []string{"rust", "go"}, Counts{TP: 2}},
+		{[]string{"go", "go"}, []string{"go"}, Counts{TP: 1, FN: 1}},
+		{[]string{"go"}, []string{"go", "go", "java"}, Counts{TP: 1, FP: 2}},
+	} {
+		if got := compare(tc.expected, tc.predicted); got != tc.want {
+			t.Errorf("compare(%v, %v) = %+v, want %+v", tc.expected, tc.predicted, got, tc.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	expected := &dataextraction.Extraction{
+		Companies: []companies.Company{
+			{Name: "Acme Robotics", FoundedYear: 2010, TechStack: []string{"Go", "Postgres"}},
+			{Name: "Globex"},
+		},
+		People: []people.Person{
+			{FullName: "John Smith", JobTitle: "CTO", Contact: people.Contact{
+				Phone:       "+33 1 23 45 67 89",
+				LinkedinURL: "https://www.linkedin.com/in/jsmith/",
+			}},
+		},
+	}
+	predicted := &dataextraction.Extraction{
+		Companies: []companies.Company{
+			// Matched by name regardless of case and spacing.
+			{Name: " acme  ROBOTICS", FoundedYear: 2011, TechStack: []string{"go", "Kubernetes"}},
+			{Name: "Initech", FoundedYear: 1999},
+		},
+		People: []people.Person{
+			{FullName: "john smith", JobTitle: "cto", Contact: people.Contact{
+				Phone:       "33123456789",
+				LinkedinURL: "linkedin.com/in/jsmith",
+			}},
+		},
+	}
+
+	counts := score(expected, predicted)
+	for name, want := range map[string]Counts{
+		// Globex is missed, Initech is made up.
+		"company.name":                Counts{TP: 1, FP: 1, FN: 1},
+		"company.founded_year":        Counts{FP: 2, FN: 1},
+		"company.tech_stack":          Counts{TP: 1, FP: 1, FN: 1},
+		"company.industry":            Counts{},
+		"person.full_name":            Counts{TP: 1},
+		"person.job_title":            Counts{TP: 1},
+		"person.contact.email":        Counts{},
+		"person.contact.phone":        Counts{TP: 1},
+		"person.contact.linkedin_url": Counts{TP: 1},
+	} {
+		if got := counts[name]; got != want {
+			t.Errorf("%s = %+v, want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Scopes grant access to the API endpoints.
+const (
+	ScopeExtract     = "extract"
+	ScopeReadHistory = "read-history"
+	ScopeWebhooks    = "webhooks"
+	ScopeWatches     = "watches"
+	ScopeAdmin       = "admin"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeExtract, ScopeReadHistory, ScopeWebhooks, ScopeWatches, ScopeAdmin}
+
+// APIKey represents a key granting access to the API. Only the hash of the key is stored.
+type APIKey struct {
+	ID   uint64 `json:"id" xml:"id" db:"id"`
+	Name string `json:"name" xml:"name" db:"name"`
+	// Prefix is the beginning of the key, for the holders to tell their keys apart.
+	Prefix string   `json:"prefix" xml:"prefix" db:"prefix"`
+	Hash   string   `json:"-" xml:"-" db:"hash"`
+	Scopes []string `json:"scopes" xml:"scopes>scope" db:"scopes"`
+	// WorkspaceID is the workspace the key works in, unless an admin key picks another one.
+	WorkspaceID string `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	// RateLimitPerMinute overrides the default rate limit of the key if positive.
+	RateLimitPerMinute int        `json:"rate_limit_per_minute" xml:"rate_limit_per_minute" db:"rate_limit_per_minute"`
+	CreatedAt          time.Time  `json:"created_at" xml:"created_at" db:"created_at"`
+	RevokedAt          *time.Time `json:"revoked_at" xml:"revoked_at" db:"revoked_at"`
+}
+
+// HasScope tells whether the key grants a scope. The admin scope grants every scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+var ErrNotFound = errors.New("api key not found")
+
+// Repository provides access to an APIKey store.
+type Repository interface {
+	Insert(ctx context.Context, apiKey *APIKey) (*APIKey, error)
+	Find(ctx context.Context) ([]APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+	Revoke(ctx context.Context, id uint64) (*APIKey, error)
+}
+
+type contextKey string
+
+const apiKeyContextKey contextKey = "apikeys_api_key"
+
+// WithAPIKey returns a context holding the key the request was authenticated with.
+func WithAPIKey(ctx context.Context, apiKey *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, apiKey)
+}
+
+// FromContext returns the key set by WithAPIKey, if any.
+func FromContext(ctx context.Context) *APIKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey).(*APIKey)
+	return apiKey
+}
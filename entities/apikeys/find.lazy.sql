@@ -0,0 +1,19 @@
+SELECT
+  ak.id
+, ak.name
+, ak.prefix
+, ak.hash
+, ak.scopes
+, ak.workspace_id
+, ak.rate_limit_per_minute
+, ak.created_at
+, ak.revoked_at
+FROM api_keys ak
+WHERE TRUE
+{{if .Hash -}}
+ AND ak.hash = @hash
+{{end -}}
+{{if .ID -}}
+ AND ak.id = @id
+{{end -}}
+ORDER BY ak.id
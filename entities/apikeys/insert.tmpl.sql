@@ -0,0 +1,19 @@
+INSERT INTO api_keys (
+  name
+, prefix
+, hash
+, scopes
+, workspace_id
+, rate_limit_per_minute
+, created_at
+)
+VALUES (
+  @name
+, @prefix
+, @hash
+, @scopes
+, @workspace_id
+, @rate_limit_per_minute
+, @created_at
+)
+returning id
@@ -0,0 +1,90 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepository returns an in-memory repository, for tests and ephemeral runs.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+type memoryRepository struct {
+	mu     sync.RWMutex
+	lastID uint64
+	rows   []APIKey
+}
+
+func (r *memoryRepository) Insert(ctx context.Context, apiKey *APIKey) (*APIKey, error) {
+	if apiKey.Hash == "" {
+		return nil, errors.New("hash cannot be empty")
+	}
+	if apiKey.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *apiKey
+	apiKey = &cpy
+	apiKey.Scopes = slices.Clone(apiKey.Scopes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.rows {
+		if row.Hash == apiKey.Hash {
+			return nil, errors.New("hash already exists")
+		}
+	}
+	r.lastID++
+	apiKey.ID = r.lastID
+	apiKey.CreatedAt = time.Now().UTC()
+	apiKey.RevokedAt = nil
+	r.rows = append(r.rows, *apiKey)
+
+	return apiKey, nil
+}
+
+func (r *memoryRepository) Find(ctx context.Context) ([]APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	apiKeys := []APIKey{}
+	for _, row := range r.rows {
+		apiKeys = append(apiKeys, row)
+	}
+	return apiKeys, nil
+}
+
+func (r *memoryRepository) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if row.Hash == hash {
+			return &row, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepository) Revoke(ctx context.Context, id uint64) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID != id {
+			continue
+		}
+		if r.rows[i].RevokedAt == nil {
+			now := time.Now().UTC()
+			r.rows[i].RevokedAt = &now
+		}
+		apiKey := r.rows[i]
+		return &apiKey, nil
+	}
+	return nil, ErrNotFound
+}
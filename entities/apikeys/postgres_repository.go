@@ -0,0 +1,94 @@
+package apikeys
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/lib/pgutil"
+)
+
+// NewPostgresRepository returns a Postgres backed repository.
+func NewPostgresRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// search filters the keys of the find query.
+type search struct {
+	ID   uint64 `db:"id"`
+	Hash string `db:"hash"`
+}
+
+func (r *postgresRepository) Insert(ctx context.Context, apiKey *APIKey) (*APIKey, error) {
+	if apiKey.Hash == "" {
+		return nil, errors.New("hash cannot be empty")
+	}
+	if apiKey.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *apiKey
+	apiKey = &cpy
+
+	apiKey.CreatedAt = time.Now().UTC()
+	apiKey.RevokedAt = nil
+	if apiKey.Scopes == nil {
+		apiKey.Scopes = []string{}
+	}
+
+	if err := r.db.QueryRow(ctx, files.File("insert.tmpl.sql"), pgutil.ToNamedArgs(apiKey)).Scan(&apiKey.ID); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+func (r *postgresRepository) Find(ctx context.Context) ([]APIKey, error) {
+	return r.find(ctx, search{})
+}
+
+func (r *postgresRepository) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	if hash == "" {
+		return nil, errors.New("hash cannot be empty")
+	}
+	return r.get(ctx, search{Hash: hash})
+}
+
+func (r *postgresRepository) Revoke(ctx context.Context, id uint64) (*APIKey, error) {
+	if _, err := r.db.Exec(ctx, files.File("revoke.tmpl.sql"), pgx.NamedArgs{
+		"id":         id,
+		"revoked_at": time.Now().UTC(),
+	}); err != nil {
+		return nil, err
+	}
+	return r.get(ctx, search{ID: id})
+}
+
+func (r *postgresRepository) get(ctx context.Context, search search) (*APIKey, error) {
+	apiKeys, err := r.find(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) == 0 {
+		return nil, ErrNotFound
+	}
+	return &apiKeys[0], nil
+}
+
+func (r *postgresRepository) find(ctx context.Context, search search) ([]APIKey, error) {
+	rows, err := r.db.Query(ctx, files.Template("find.lazy.sql", search), pgutil.ToNamedArgs(search))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[APIKey])
+}
@@ -0,0 +1,3 @@
+UPDATE api_keys
+SET revoked_at = COALESCE(revoked_at, @revoked_at)
+WHERE id = @id
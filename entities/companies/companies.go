@@ -2,11 +2,11 @@ package companies
 
 // Company represents a company.
 type Company struct {
-	Name        string   `json:"name"`
-	FoundedYear int      `json:"founded_year"`
-	Industry    string   `json:"industry"`
-	Revenue     int      `json:"revenue"`
-	Employees   int      `json:"employees"`
-	Locations   []string `json:"locations"`
-	TechStack   []string `json:"tech_stack"`
+	Name        string   `json:"name" xml:"name"`
+	FoundedYear int      `json:"founded_year" xml:"founded_year"`
+	Industry    string   `json:"industry" xml:"industry"`
+	Revenue     int      `json:"revenue" xml:"revenue"`
+	Employees   int      `json:"employees" xml:"employees"`
+	Locations   []string `json:"locations" xml:"locations>location"`
+	TechStack   []string `json:"tech_stack" xml:"tech_stack>technology"`
 }
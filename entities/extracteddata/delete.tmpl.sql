@@ -0,0 +1,8 @@
+UPDATE extracted_data
+SET
+  deleted_at = @deleted_at
+, people = '[]'
+, companies = '[]'
+WHERE workspace_id = @workspace_id
+ AND id = @id
+ AND deleted_at IS NULL
@@ -0,0 +1,192 @@
+package extracteddata
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// similarityThreshold is the trigram similarity above which a name matches a search, as the default
+// threshold of pg_trgm.
+const similarityThreshold = 0.3
+
+// entityMatcher searches the people and companies of runs without an index, for the memory and SQLite
+// repositories. It approximates the Postgres search: every word of the query has to start a word of the name or
+// title of an entity, or its name has to be similar to the whole query.
+type entityMatcher struct {
+	search   EntitySearch
+	terms    []string
+	trigrams map[string]bool
+	seen     map[string]bool
+	matches  []EntityMatch
+}
+
+func newEntityMatcher(search EntitySearch) *entityMatcher {
+	return &entityMatcher{
+		search:   search,
+		terms:    words(search.Query),
+		trigrams: trigrams(search.Query),
+		seen:     map[string]bool{},
+	}
+}
+
+// add matches the entities of a run. The runs are added most recent first, so that each entity is found by
+// its most recent run.
+func (m *entityMatcher) add(run *ExtractedData) {
+	if m.search.Kind != EntityCompany {
+		for i := range run.People {
+			person := run.People[i]
+			if match, ok := m.match(run, EntityPerson, person.FullName, person.JobTitle); ok {
+				match.Person = &person
+				m.matches = append(m.matches, match)
+			}
+		}
+	}
+	if m.search.Kind != EntityPerson {
+		for i := range run.Companies {
+			company := run.Companies[i]
+			if match, ok := m.match(run, EntityCompany, company.Name, company.Industry); ok {
+				match.Company = &company
+				m.matches = append(m.matches, match)
+			}
+		}
+	}
+}
+
+// match ranks an entity of a run, and tells whether it matches the search and was not found by a more recent run.
+func (m *entityMatcher) match(run *ExtractedData, kind, name, title string) (EntityMatch, bool) {
+	key := kind + ":" + strings.ToLower(name)
+	if m.seen[key] {
+		return EntityMatch{}, false
+	}
+
+	nameWords, titleWords := words(name), words(title)
+	var nameHits, titleHits int
+	for _, term := range m.terms {
+		switch {
+		case hasWordPrefix(nameWords, term):
+			nameHits++
+		case hasWordPrefix(titleWords, term):
+			titleHits++
+		}
+	}
+	nameSimilarity := similarity(m.trigrams, trigrams(name))
+	if (len(m.terms) == 0 || nameHits+titleHits < len(m.terms)) && nameSimilarity < similarityThreshold {
+		return EntityMatch{}, false
+	}
+	m.seen[key] = true
+
+	rank := nameSimilarity
+	if len(m.terms) > 0 {
+		rank += (float64(nameHits) + 0.4*float64(titleHits)) / float64(len(m.terms))
+	}
+	text := name
+	if title != "" {
+		text = strings.TrimPrefix(text+" - "+title, " - ")
+	}
+	return EntityMatch{
+		Kind:      kind,
+		Name:      name,
+		Title:     title,
+		Highlight: highlight(text, m.terms),
+		Rank:      rank,
+		RunID:     run.ID,
+		URL:       run.URL,
+		CreatedAt: run.CreatedAt,
+	}, true
+}
+
+// result returns the matches, the best ranked first, and the most recent first among equals.
+func (m *entityMatcher) result() []EntityMatch {
+	sort.SliceStable(m.matches, func(i, j int) bool {
+		if m.matches[i].Rank != m.matches[j].Rank {
+			return m.matches[i].Rank > m.matches[j].Rank
+		}
+		if !m.matches[i].CreatedAt.Equal(m.matches[j].CreatedAt) {
+			return m.matches[i].CreatedAt.After(m.matches[j].CreatedAt)
+		}
+		return m.matches[i].RunID > m.matches[j].RunID
+	})
+	if len(m.matches) > m.search.Limit {
+		m.matches = m.matches[:m.search.Limit]
+	}
+	if m.matches == nil {
+		return []EntityMatch{}
+	}
+	return m.matches
+}
+
+// isWordSeparator tells whether a rune separates the words of a text.
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// words returns the lowercased words of a text.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), isWordSeparator)
+}
+
+// hasWordPrefix tells whether a word starts with a term.
+func hasWordPrefix(words []string, term string) bool {
+	return slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) })
+}
+
+// trigrams returns the trigrams of the words of a text, padded as by pg_trgm.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range words(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// similarity returns the share of trigrams two texts have in common.
+func similarity(a, b map[string]bool) float64 {
+	var common int
+	for trigram := range a {
+		if b[trigram] {
+			common++
+		}
+	}
+	if total := len(a) + len(b) - common; total > 0 {
+		return float64(common) / float64(total)
+	}
+	return 0
+}
+
+// highlight wraps the words of a text starting with a term in <mark> tags.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	rest := text
+	for rest != "" {
+		start := strings.IndexFunc(rest, func(r rune) bool { return !isWordSeparator(r) })
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:start])
+		rest = rest[start:]
+		end := strings.IndexFunc(rest, isWordSeparator)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		if matchesAnyTerm(word, terms) {
+			b.WriteString("<mark>" + word + "</mark>")
+		} else {
+			b.WriteString(word)
+		}
+		rest = rest[end:]
+	}
+	return b.String()
+}
+
+// matchesAnyTerm tells whether a word starts with any of the terms.
+func matchesAnyTerm(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	return slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(word, term) })
+}
@@ -2,24 +2,250 @@ package extracteddata
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/solher/hunterio-test/entities/companies"
 	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
 )
 
 // ExtractedData represents an extraction run.
 type ExtractedData struct {
-	ID        uint64              `json:"id" db:"id"`
-	URL       string              `json:"url" db:"url"`
-	People    []people.Person     `json:"people" db:"people"`
-	Companies []companies.Company `json:"companies" db:"companies"`
-	CreatedAt time.Time           `json:"created_at" db:"created_at"`
+	ID               uint64              `json:"id" xml:"id" db:"id"`
+	URL              string              `json:"url" xml:"url" db:"url"`
+	People           []people.Person     `json:"people" xml:"people>person" db:"people"`
+	Companies        []companies.Company `json:"companies" xml:"companies>company" db:"companies"`
+	Model            string              `json:"model" xml:"model" db:"model"`
+	PromptTokens     int64               `json:"prompt_tokens" xml:"prompt_tokens" db:"prompt_tokens"`
+	CompletionTokens int64               `json:"completion_tokens" xml:"completion_tokens" db:"completion_tokens"`
+	LatencyMs        int64               `json:"latency_ms" xml:"latency_ms" db:"latency_ms"`
+	CostUSD          float64             `json:"cost_usd" xml:"cost_usd" db:"cost_usd"`
+	ClientID         string              `json:"client_id" xml:"client_id" db:"client_id"`
+	APIKeyID         uint64              `json:"api_key_id" xml:"api_key_id" db:"api_key_id"`
+	WorkspaceID      string              `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	CreatedAt        time.Time           `json:"created_at" xml:"created_at" db:"created_at"`
 }
 
+var (
+	ErrNotFound          = errors.New("extracted data not found")
+	ErrWorkspaceRequired = errors.New("workspace cannot be empty")
+)
+
 // Repository provides access to an ExtractedData store.
 type Repository interface {
 	Insert(ctx context.Context, extractedData *ExtractedData) (*ExtractedData, error)
 	Find(ctx context.Context, search Search) ([]ExtractedData, error)
-	GetLastByURL(ctx context.Context, url string) (*ExtractedData, error)
+	// Stream calls fn with each run matching the search, most recent first, without loading them all in memory.
+	// It stops at the first error returned by fn.
+	Stream(ctx context.Context, search Search, fn func(extractedData *ExtractedData) error) error
+	GetLastByURL(ctx context.Context, workspaceID string, url string) (*ExtractedData, error)
+	GetByID(ctx context.Context, workspaceID string, id uint64) (*ExtractedData, error)
+	// Delete erases the people and companies of a run, which is not found anymore. Its usage is still
+	// aggregated, as the budgets and quotas depend on it.
+	Delete(ctx context.Context, workspaceID string, id uint64) error
+	// UpdateEntities replaces the people and companies of a run, to erase or redact personal data.
+	UpdateEntities(ctx context.Context, extractedData *ExtractedData) error
+	AggregateUsage(ctx context.Context, search UsageSearch) ([]UsageAggregate, error)
+	// Purge permanently deletes a batch of runs selected by a retention policy, and returns how many were deleted,
+	// less than the limit once there are none left. With DryRun, it counts every run selected instead.
+	Purge(ctx context.Context, purge Purge) (int64, error)
+	// SearchEntities returns the people and companies of the runs matching a search, the best ranked first.
+	SearchEntities(ctx context.Context, search EntitySearch) ([]EntityMatch, error)
+}
+
+// Search allows object searching. The workspace is required, workspaces.AllID lifts the restriction.
+// The runs are sorted by creation date and ID, most recent first, and BeforeCreatedAt and BeforeID
+// restrict them to the ones after a run in this order, for keyset pagination. DomainPrefix matches the
+// runs whose URL domain starts with it, and cannot hold a slash. PersonEmail, PersonName and PersonPhone
+// match the runs holding a person with any of them, regardless of case and of the phone formatting
+// (PersonPhone holds digits only, see people.NormalizePhone). The other entity filters each match the runs holding
+// a person or a company with the exact value, except EmailDomain which ignores the case, and TechStack matches
+// the companies using the technology.
+type Search struct {
+	WorkspaceID     string    `db:"workspace_id"`
+	ID              uint64    `db:"id"`
+	URL             string    `db:"url"`
+	DomainPrefix    string    `db:"domain_prefix"`
+	Limit           int       `db:"limit"`
+	Offset          int       `db:"offset"`
+	CreatedAtFrom   time.Time `db:"created_at_from"`
+	CreatedAtTo     time.Time `db:"created_at_to"`
+	BeforeCreatedAt time.Time `db:"before_created_at"`
+	BeforeID        uint64    `db:"before_id"`
+	PersonEmail     string    `db:"person_email"`
+	PersonName      string    `db:"person_name"`
+	PersonPhone     string    `db:"person_phone"`
+	JobTitle        string    `db:"job_title"`
+	EmailDomain     string    `db:"email_domain"`
+	CompanyName     string    `db:"company_name"`
+	Industry        string    `db:"industry"`
+	TechStack       string    `db:"tech_stack"`
+}
+
+// workspaces.AllID tells whether the search spans every workspace.
+func (s Search) AllWorkspaces() bool {
+	return s.WorkspaceID == workspaces.AllID
+}
+
+// matchesPerson tells whether a person matches the person filters of the search.
+func (s *Search) matchesPerson(person people.Person) bool {
+	return (s.PersonEmail != "" && strings.EqualFold(person.Contact.Email, s.PersonEmail)) ||
+		(s.PersonName != "" && strings.EqualFold(person.FullName, s.PersonName)) ||
+		(s.PersonPhone != "" && people.NormalizePhone(person.Contact.Phone) == s.PersonPhone)
+}
+
+// matchesEntities tells whether a run holds the people and companies of the entity filters of the search.
+func (s *Search) matchesEntities(run *ExtractedData) bool {
+	matchesPerson := func(match func(person people.Person) bool) bool {
+		return slices.ContainsFunc(run.People, match)
+	}
+	matchesCompany := func(match func(company companies.Company) bool) bool {
+		return slices.ContainsFunc(run.Companies, match)
+	}
+	return (s.JobTitle == "" || matchesPerson(func(person people.Person) bool {
+		return strings.EqualFold(person.JobTitle, s.JobTitle)
+	})) && (s.EmailDomain == "" || matchesPerson(func(person people.Person) bool {
+		_, domain, ok := strings.Cut(person.Contact.Email, "@")
+		return ok && strings.EqualFold(domain, s.EmailDomain)
+	})) && (s.CompanyName == "" || matchesCompany(func(company companies.Company) bool {
+		return strings.EqualFold(company.Name, s.CompanyName)
+	})) && (s.Industry == "" || matchesCompany(func(company companies.Company) bool {
+		return strings.EqualFold(company.Industry, s.Industry)
+	})) && (s.TechStack == "" || matchesCompany(func(company companies.Company) bool {
+		return slices.ContainsFunc(company.TechStack, func(tech string) bool {
+			return strings.EqualFold(tech, s.TechStack)
+		})
+	}))
+}
+
+// Purge kinds.
+const (
+	// PurgeSuperseded selects the runs followed by a more recent run of their URL, and the deleted runs.
+	PurgeSuperseded = "superseded"
+	// PurgeLatest selects the most recent run of each URL, holding its latest people and companies.
+	PurgeLatest = "latest"
+)
+
+// Purge selects the runs of a kind created before CreatedBefore, the oldest first.
+// The workspace is required, workspaces.AllID lifts the restriction.
+type Purge struct {
+	WorkspaceID   string    `db:"workspace_id"`
+	Kind          string    `db:"kind"`
+	CreatedBefore time.Time `db:"created_before"`
+	Limit         int       `db:"limit"`
+	DryRun        bool      `db:"dry_run"`
+}
+
+// workspaces.AllID tells whether the purge spans every workspace.
+func (p Purge) AllWorkspaces() bool {
+	return p.WorkspaceID == workspaces.AllID
+}
+
+// validate checks the purge can be run.
+func (p *Purge) validate() error {
+	if p.WorkspaceID == "" {
+		return ErrWorkspaceRequired
+	}
+	if p.Kind != PurgeSuperseded && p.Kind != PurgeLatest {
+		return fmt.Errorf("unknown purge kind %q", p.Kind)
+	}
+	if !p.DryRun && p.Limit <= 0 {
+		return errors.New("purge limit must be positive")
+	}
+	return nil
+}
+
+// Entity kinds.
+const (
+	EntityPerson  = "person"
+	EntityCompany = "company"
+)
+
+// EntitySearch allows searching the people and companies of the runs, by full text on their names, job titles
+// and industries, and by similarity on their names to find the misspelled ones. Kind restricts the search to
+// an entity kind, both are searched when empty. The workspace is required, workspaces.AllID lifts the restriction.
+type EntitySearch struct {
+	WorkspaceID string `db:"workspace_id"`
+	Query       string `db:"query"`
+	Kind        string `db:"kind"`
+	Limit       int    `db:"limit"`
+}
+
+// workspaces.AllID tells whether the search spans every workspace.
+func (e EntitySearch) AllWorkspaces() bool {
+	return e.WorkspaceID == workspaces.AllID
+}
+
+// validate checks the search can be run.
+func (s *EntitySearch) validate() error {
+	if s.WorkspaceID == "" {
+		return ErrWorkspaceRequired
+	}
+	if s.Kind != "" && s.Kind != EntityPerson && s.Kind != EntityCompany {
+		return fmt.Errorf("unknown entity kind %q", s.Kind)
+	}
+	if s.Limit <= 0 {
+		return errors.New("entity search limit must be positive")
+	}
+	return nil
+}
+
+// EntityMatch represents a person or a company matching a search, as found by its most recent run.
+// Title is the job title of the people and the industry of the companies, and Highlight marks the words
+// of the name and title matching the search with <mark> tags.
+type EntityMatch struct {
+	Kind      string             `json:"kind" xml:"kind"`
+	Name      string             `json:"name" xml:"name"`
+	Title     string             `json:"title" xml:"title"`
+	Highlight string             `json:"highlight" xml:"highlight"`
+	Rank      float64            `json:"rank" xml:"rank"`
+	Person    *people.Person     `json:"person,omitempty" xml:"person,omitempty"`
+	Company   *companies.Company `json:"company,omitempty" xml:"company,omitempty"`
+	RunID     uint64             `json:"run_id" xml:"run_id"`
+	URL       string             `json:"url" xml:"url"`
+	CreatedAt time.Time          `json:"created_at" xml:"created_at"`
+}
+
+// Usage groupings.
+const (
+	UsageByDay    = "day"
+	UsageByURL    = "url"
+	UsageByDomain = "domain"
+)
+
+// UsageSearch allows usage aggregation. The workspace is required, workspaces.AllID lifts the restriction.
+type UsageSearch struct {
+	WorkspaceID   string    `db:"workspace_id"`
+	GroupBy       string    `db:"group_by"`
+	ClientID      string    `db:"client_id"`
+	CreatedAtFrom time.Time `db:"created_at_from"`
+	CreatedAtTo   time.Time `db:"created_at_to"`
+}
+
+// workspaces.AllID tells whether the search spans every workspace.
+func (u UsageSearch) AllWorkspaces() bool {
+	return u.WorkspaceID == workspaces.AllID
+}
+
+// UsageAggregate represents the OpenAI usage of a group of extraction runs.
+type UsageAggregate struct {
+	Key              string  `json:"key" xml:"key" db:"key"`
+	Runs             int64   `json:"runs" xml:"runs" db:"runs"`
+	PromptTokens     int64   `json:"prompt_tokens" xml:"prompt_tokens" db:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens" xml:"completion_tokens" db:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd" xml:"cost_usd" db:"cost_usd"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms" xml:"avg_latency_ms" db:"avg_latency_ms"`
+}
+
+// Domain returns the host part of a URL, as grouped by the usage aggregation.
+func Domain(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	}
+	domain, _, _ := strings.Cut(url, "/")
+	return domain
 }
@@ -3,19 +3,74 @@ SELECT
 , ed.url
 , ed.people
 , ed.companies
+, ed.model
+, ed.prompt_tokens
+, ed.completion_tokens
+, ed.latency_ms
+, ed.cost_usd
+, ed.client_id
+, ed.api_key_id
+, ed.workspace_id
 , ed.created_at
 FROM extracted_data ed
-WHERE TRUE
+WHERE ed.deleted_at IS NULL
+{{if not .AllWorkspaces -}}
+ AND ed.workspace_id = @workspace_id
+{{end -}}
+{{if .ID -}}
+ AND ed.id = @id
+{{end -}}
 {{if .URL -}}
  AND ed.url = @url
 {{end -}}
+{{if .DomainPrefix -}}
+ AND starts_with(split_part(ed.url, '://', 2), @domain_prefix)
+{{end -}}
 {{if not .CreatedAtFrom.IsZero -}}
  AND ed.created_at >= @created_at_from
 {{end -}}
 {{if not .CreatedAtTo.IsZero -}}
  AND ed.created_at <= @created_at_to
 {{end -}}
-ORDER BY ed.created_at DESC
+{{if not .BeforeCreatedAt.IsZero -}}
+ AND (ed.created_at, ed.id) < (@before_created_at, @before_id)
+{{end -}}
+{{if or .PersonEmail .PersonName .PersonPhone -}}
+ AND EXISTS (
+  SELECT 1
+  FROM jsonb_array_elements(CASE WHEN jsonb_typeof(ed.people) = 'array' THEN ed.people ELSE '[]' END) AS p (person)
+  WHERE FALSE
+  {{if .PersonEmail -}}
+   OR lower(person->'contact'->>'email') = lower(@person_email)
+  {{end -}}
+  {{if .PersonName -}}
+   OR lower(person->>'full_name') = lower(@person_name)
+  {{end -}}
+  {{if .PersonPhone -}}
+   OR regexp_replace(person->'contact'->>'phone', '[^0-9]', '', 'g') = @person_phone
+  {{end -}}
+ )
+{{end -}}
+{{if .JobTitle -}}
+ AND lower(ed.people::text)::jsonb @? format('$[*] ? (@.job_title == %s)', to_jsonb(lower(@job_title::text)))::jsonpath
+{{end -}}
+{{if .EmailDomain -}}
+ AND EXISTS (
+  SELECT 1
+  FROM jsonb_path_query(ed.people, '$[*].contact.email ? (@.type() == "string")') AS e (email)
+  WHERE lower(split_part(e.email #>> '{}', '@', 2)) = lower(@email_domain)
+ )
+{{end -}}
+{{if .CompanyName -}}
+ AND lower(ed.companies::text)::jsonb @? format('$[*] ? (@.name == %s)', to_jsonb(lower(@company_name::text)))::jsonpath
+{{end -}}
+{{if .Industry -}}
+ AND lower(ed.companies::text)::jsonb @? format('$[*] ? (@.industry == %s)', to_jsonb(lower(@industry::text)))::jsonpath
+{{end -}}
+{{if .TechStack -}}
+ AND lower(ed.companies::text)::jsonb @? format('$[*].tech_stack[*] ? (@ == %s)', to_jsonb(lower(@tech_stack::text)))::jsonpath
+{{end -}}
+ORDER BY ed.created_at DESC, ed.id DESC
 {{if .Limit -}}
  LIMIT @limit
 {{end -}}
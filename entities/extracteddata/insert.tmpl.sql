@@ -2,12 +2,28 @@ INSERT INTO extracted_data (
   url
 , people
 , companies
+, model
+, prompt_tokens
+, completion_tokens
+, latency_ms
+, cost_usd
+, client_id
+, api_key_id
+, workspace_id
 , created_at
 )
 VALUES (
   @url
 , @people
 , @companies
+, @model
+, @prompt_tokens
+, @completion_tokens
+, @latency_ms
+, @cost_usd
+, @client_id
+, @api_key_id
+, @workspace_id
 , @created_at
 )
 returning id
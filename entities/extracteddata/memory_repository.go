@@ -0,0 +1,309 @@
+package extracteddata
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+// NewMemoryRepository returns an in-memory repository, for tests and ephemeral runs.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+type memoryRepository struct {
+	mu      sync.RWMutex
+	lastID  uint64
+	rows    []ExtractedData
+	deleted map[uint64]bool
+}
+
+func (r *memoryRepository) Insert(ctx context.Context, extractedData *ExtractedData) (*ExtractedData, error) {
+	if extractedData.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *extractedData
+	extractedData = &cpy
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	extractedData.ID = r.lastID
+	extractedData.CreatedAt = time.Now().UTC()
+	r.rows = append(r.rows, *extractedData)
+
+	return extractedData, nil
+}
+
+func (r *memoryRepository) Find(ctx context.Context, search Search) ([]ExtractedData, error) {
+	return r.find(search, false)
+}
+
+// find returns the runs matching the search, with the deleted ones if requested.
+func (r *memoryRepository) find(search Search, withDeleted bool) ([]ExtractedData, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	extractedDataList := []ExtractedData{}
+	for _, row := range r.rows {
+		if search.WorkspaceID != workspaces.AllID && row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if !withDeleted && r.deleted[row.ID] {
+			continue
+		}
+		if search.ID != 0 && row.ID != search.ID {
+			continue
+		}
+		if search.URL != "" && row.URL != search.URL {
+			continue
+		}
+		if search.DomainPrefix != "" && !strings.HasPrefix(Domain(row.URL), search.DomainPrefix) {
+			continue
+		}
+		if !search.CreatedAtFrom.IsZero() && row.CreatedAt.Before(search.CreatedAtFrom) {
+			continue
+		}
+		if !search.CreatedAtTo.IsZero() && row.CreatedAt.After(search.CreatedAtTo) {
+			continue
+		}
+		if !search.BeforeCreatedAt.IsZero() && !row.CreatedAt.Before(search.BeforeCreatedAt) &&
+			(!row.CreatedAt.Equal(search.BeforeCreatedAt) || row.ID >= search.BeforeID) {
+			continue
+		}
+		if (search.PersonEmail != "" || search.PersonName != "" || search.PersonPhone != "") &&
+			!slices.ContainsFunc(row.People, search.matchesPerson) {
+			continue
+		}
+		if !search.matchesEntities(&row) {
+			continue
+		}
+		extractedDataList = append(extractedDataList, row)
+	}
+
+	// Most recent first, as in the Postgres repository.
+	sort.SliceStable(extractedDataList, func(i, j int) bool {
+		if extractedDataList[i].CreatedAt.Equal(extractedDataList[j].CreatedAt) {
+			return extractedDataList[i].ID > extractedDataList[j].ID
+		}
+		return extractedDataList[i].CreatedAt.After(extractedDataList[j].CreatedAt)
+	})
+
+	if search.Offset > 0 {
+		if search.Offset >= len(extractedDataList) {
+			return []ExtractedData{}, nil
+		}
+		extractedDataList = extractedDataList[search.Offset:]
+	}
+	if search.Limit > 0 && search.Limit < len(extractedDataList) {
+		extractedDataList = extractedDataList[:search.Limit]
+	}
+	return extractedDataList, nil
+}
+
+func (r *memoryRepository) Stream(ctx context.Context, search Search, fn func(extractedData *ExtractedData) error) error {
+	extractedDataList, err := r.Find(ctx, search)
+	if err != nil {
+		return err
+	}
+	for i := range extractedDataList {
+		if err := fn(&extractedDataList[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetLastByURL(ctx context.Context, workspaceID string, url string) (*ExtractedData, error) {
+	if url == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, URL: url, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(extractedDataList) == 0 {
+		return nil, ErrNotFound
+	}
+	return &extractedDataList[0], nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, workspaceID string, id uint64) (*ExtractedData, error) {
+	// A zero ID would not filter the search, which would return the most recent run.
+	if id == 0 {
+		return nil, ErrNotFound
+	}
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(extractedDataList) == 0 {
+		return nil, ErrNotFound
+	}
+	return &extractedDataList[0], nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, workspaceID string, id uint64) error {
+	if workspaceID == "" || workspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, row := range r.rows {
+		if row.ID != id || row.WorkspaceID != workspaceID || r.deleted[row.ID] {
+			continue
+		}
+		r.rows[i].People = []people.Person{}
+		r.rows[i].Companies = []companies.Company{}
+		if r.deleted == nil {
+			r.deleted = map[uint64]bool{}
+		}
+		r.deleted[row.ID] = true
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (r *memoryRepository) UpdateEntities(ctx context.Context, extractedData *ExtractedData) error {
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, row := range r.rows {
+		if row.ID != extractedData.ID || row.WorkspaceID != extractedData.WorkspaceID || r.deleted[row.ID] {
+			continue
+		}
+		r.rows[i].People = slices.Clone(extractedData.People)
+		r.rows[i].Companies = slices.Clone(extractedData.Companies)
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (r *memoryRepository) AggregateUsage(ctx context.Context, search UsageSearch) ([]UsageAggregate, error) {
+	// The deleted runs are still aggregated, as in the Postgres repository.
+	extractedDataList, err := r.find(Search{
+		WorkspaceID:   search.WorkspaceID,
+		CreatedAtFrom: search.CreatedAtFrom,
+		CreatedAtTo:   search.CreatedAtTo,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string]*UsageAggregate{}
+	latencies := map[string]int64{}
+	for _, extractedData := range extractedDataList {
+		if search.ClientID != "" && extractedData.ClientID != search.ClientID {
+			continue
+		}
+
+		var key string
+		switch search.GroupBy {
+		case UsageByURL:
+			key = extractedData.URL
+		case UsageByDomain:
+			key = Domain(extractedData.URL)
+		default:
+			key = extractedData.CreatedAt.Format(time.DateOnly)
+		}
+
+		aggregate, ok := byKey[key]
+		if !ok {
+			aggregate = &UsageAggregate{Key: key}
+			byKey[key] = aggregate
+		}
+		aggregate.Runs++
+		aggregate.PromptTokens += extractedData.PromptTokens
+		aggregate.CompletionTokens += extractedData.CompletionTokens
+		aggregate.CostUSD += extractedData.CostUSD
+		latencies[key] += extractedData.LatencyMs
+	}
+
+	aggregates := []UsageAggregate{}
+	for key, aggregate := range byKey {
+		aggregate.AvgLatencyMs = float64(latencies[key]) / float64(aggregate.Runs)
+		aggregates = append(aggregates, *aggregate)
+	}
+	sort.Slice(aggregates, func(i, j int) bool { return aggregates[i].Key < aggregates[j].Key })
+	return aggregates, nil
+}
+
+func (r *memoryRepository) Purge(ctx context.Context, purge Purge) (int64, error) {
+	if err := purge.validate(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// superseded tells whether a run is followed by a more recent run of its URL, or deleted.
+	superseded := func(run ExtractedData) bool {
+		return r.deleted[run.ID] || slices.ContainsFunc(r.rows, func(newer ExtractedData) bool {
+			return newer.WorkspaceID == run.WorkspaceID && newer.URL == run.URL && !r.deleted[newer.ID] &&
+				(newer.CreatedAt.After(run.CreatedAt) || (newer.CreatedAt.Equal(run.CreatedAt) && newer.ID > run.ID))
+		})
+	}
+	// The rows are in insertion order, the oldest first as in the Postgres repository.
+	purged := map[uint64]bool{}
+	for _, row := range r.rows {
+		if !purge.DryRun && len(purged) == purge.Limit {
+			break
+		}
+		if purge.WorkspaceID != workspaces.AllID && row.WorkspaceID != purge.WorkspaceID {
+			continue
+		}
+		if !row.CreatedAt.Before(purge.CreatedBefore) || superseded(row) != (purge.Kind == PurgeSuperseded) {
+			continue
+		}
+		purged[row.ID] = true
+	}
+	if purge.DryRun {
+		return int64(len(purged)), nil
+	}
+
+	r.rows = slices.DeleteFunc(r.rows, func(row ExtractedData) bool { return purged[row.ID] })
+	for id := range purged {
+		delete(r.deleted, id)
+	}
+	return int64(len(purged)), nil
+}
+
+func (r *memoryRepository) SearchEntities(ctx context.Context, search EntitySearch) ([]EntityMatch, error) {
+	if err := search.validate(); err != nil {
+		return nil, err
+	}
+
+	runs, err := r.find(Search{WorkspaceID: search.WorkspaceID}, false)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newEntityMatcher(search)
+	for i := range runs {
+		matcher.add(&runs[i])
+	}
+	return matcher.result(), nil
+}
@@ -2,17 +2,19 @@ package extracteddata
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
 	"github.com/solher/hunterio-test/lib/pgutil"
 )
 
-var ErrNotFound = errors.New("extracted data not found")
-
 // NewPostgresRepository returns a Postgres backed repository.
 func NewPostgresRepository(db *pgxpool.Pool) Repository {
 	return &postgresRepository{
@@ -24,47 +26,76 @@ type postgresRepository struct {
 	db *pgxpool.Pool
 }
 
-// Search allows object searching.
-type Search struct {
-	URL           string    `db:"url"`
-	Limit         int       `db:"limit"`
-	Offset        int       `db:"offset"`
-	CreatedAtFrom time.Time `db:"created_at_from"`
-	CreatedAtTo   time.Time `db:"created_at_to"`
-}
-
 func (r *postgresRepository) Insert(ctx context.Context, extractedData *ExtractedData) (*ExtractedData, error) {
 	if extractedData.URL == "" {
 		return nil, errors.New("url cannot be empty")
 	}
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return nil, ErrWorkspaceRequired
+	}
 
 	cpy := *extractedData
 	extractedData = &cpy
 
 	extractedData.CreatedAt = time.Now().UTC()
 
-	if err := r.db.QueryRow(ctx, files.File("insert.tmpl.sql"), pgutil.ToNamedArgs(extractedData)).Scan(&extractedData.ID); err != nil {
+	if err := pgutil.InWorkspace(ctx, r.db, extractedData.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert.tmpl.sql"), pgutil.ToNamedArgs(extractedData)).Scan(&extractedData.ID)
+	}); err != nil {
 		return nil, err
 	}
 	return extractedData, nil
 }
 
 func (r *postgresRepository) Find(ctx context.Context, search Search) (extractedDataList []ExtractedData, err error) {
-	rows, err := r.db.Query(ctx, files.Template("find.lazy.sql", search), pgutil.ToNamedArgs(search))
-	if err != nil {
-		return nil, err
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
 	}
-	defer rows.Close()
 
-	return pgx.CollectRows(rows, pgx.RowToStructByName[ExtractedData])
+	err = pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		extractedDataList, err = pgx.CollectRows(rows, pgx.RowToStructByName[ExtractedData])
+		return err
+	})
+	return extractedDataList, err
 }
 
-func (r *postgresRepository) GetLastByURL(ctx context.Context, url string) (*ExtractedData, error) {
+func (r *postgresRepository) Stream(ctx context.Context, search Search, fn func(extractedData *ExtractedData) error) error {
+	if search.WorkspaceID == "" {
+		return ErrWorkspaceRequired
+	}
+
+	return pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		// The rows are decoded one at a time as the server sends them through the portal of the query,
+		// its backpressure keeping the memory flat whatever the number of runs.
+		rows, err := tx.Query(ctx, files.Template("find.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			extractedData, err := pgx.RowToStructByName[ExtractedData](rows)
+			if err != nil {
+				return err
+			}
+			if err := fn(&extractedData); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+func (r *postgresRepository) GetLastByURL(ctx context.Context, workspaceID string, url string) (*ExtractedData, error) {
 	if url == "" {
 		return nil, errors.New("url cannot be empty")
 	}
 
-	extractedDataList, err := r.Find(ctx, Search{URL: url, Limit: 1})
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, URL: url, Limit: 1})
 	if err != nil {
 		return nil, err
 	}
@@ -73,3 +104,151 @@ func (r *postgresRepository) GetLastByURL(ctx context.Context, url string) (*Ext
 	}
 	return &extractedDataList[0], nil
 }
+
+func (r *postgresRepository) GetByID(ctx context.Context, workspaceID string, id uint64) (*ExtractedData, error) {
+	// A zero ID would not filter the search, which would return the most recent run.
+	if id == 0 {
+		return nil, ErrNotFound
+	}
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(extractedDataList) == 0 {
+		return nil, ErrNotFound
+	}
+	return &extractedDataList[0], nil
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, workspaceID string, id uint64) error {
+	if workspaceID == "" || workspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	return pgutil.InWorkspace(ctx, r.db, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("delete.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": workspaceID,
+			"id":           id,
+			"deleted_at":   time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) UpdateEntities(ctx context.Context, extractedData *ExtractedData) error {
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	return pgutil.InWorkspace(ctx, r.db, extractedData.WorkspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("update_entities.tmpl.sql"), pgutil.ToNamedArgs(extractedData))
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) AggregateUsage(ctx context.Context, search UsageSearch) (aggregates []UsageAggregate, err error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	err = pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("aggregate_usage.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		aggregates, err = pgx.CollectRows(rows, pgx.RowToStructByName[UsageAggregate])
+		return err
+	})
+	return aggregates, err
+}
+
+func (r *postgresRepository) Purge(ctx context.Context, purge Purge) (n int64, err error) {
+	if err := purge.validate(); err != nil {
+		return 0, err
+	}
+
+	// Each batch is its own transaction, holding its locks briefly.
+	err = pgutil.InWorkspace(ctx, r.db, purge.WorkspaceID, func(tx pgx.Tx) error {
+		query := files.Template("purge.lazy.sql", purge)
+		if purge.DryRun {
+			return tx.QueryRow(ctx, query, pgutil.ToNamedArgs(purge)).Scan(&n)
+		}
+		tag, err := tx.Exec(ctx, query, pgutil.ToNamedArgs(purge))
+		n = tag.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+func (r *postgresRepository) SearchEntities(ctx context.Context, search EntitySearch) (matches []EntityMatch, err error) {
+	if err := search.validate(); err != nil {
+		return nil, err
+	}
+
+	err = pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("search_entities.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		entityRows, err := pgx.CollectRows(rows, pgx.RowToStructByName[entityRow])
+		if err != nil {
+			return err
+		}
+		matches = make([]EntityMatch, len(entityRows))
+		for i, row := range entityRows {
+			if matches[i], err = row.match(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// entityRow is a row of the entity search, holding the entity as stored.
+type entityRow struct {
+	Kind      string          `db:"kind"`
+	Entity    json.RawMessage `db:"entity"`
+	Name      string          `db:"name"`
+	Title     string          `db:"title"`
+	Highlight string          `db:"highlight"`
+	Rank      float64         `db:"rank"`
+	RunID     uint64          `db:"run_id"`
+	URL       string          `db:"url"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+// match decodes the entity of the row into a match.
+func (row *entityRow) match() (EntityMatch, error) {
+	match := EntityMatch{
+		Kind:      row.Kind,
+		Name:      row.Name,
+		Title:     row.Title,
+		Highlight: row.Highlight,
+		Rank:      row.Rank,
+		RunID:     row.RunID,
+		URL:       row.URL,
+		CreatedAt: row.CreatedAt,
+	}
+	var err error
+	if row.Kind == EntityPerson {
+		match.Person = &people.Person{}
+		err = json.Unmarshal(row.Entity, match.Person)
+	} else {
+		match.Company = &companies.Company{}
+		err = json.Unmarshal(row.Entity, match.Company)
+	}
+	return match, err
+}
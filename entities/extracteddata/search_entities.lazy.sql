@@ -0,0 +1,76 @@
+WITH query AS (
+  SELECT websearch_to_tsquery('simple', @query) || websearch_to_tsquery('english', @query) AS tsquery
+), runs AS (
+  SELECT
+    ed.id
+  , ed.url
+  , ed.people
+  , ed.companies
+  , ed.created_at
+  FROM extracted_data ed, query q
+  WHERE ed.deleted_at IS NULL
+  {{if not .AllWorkspaces -}}
+   AND ed.workspace_id = @workspace_id
+  {{end -}}
+   AND (ed.search_vector @@ q.tsquery OR @query <% ed.search_names)
+), entities AS (
+  {{if ne .Kind "company" -}}
+  SELECT
+    'person' AS kind
+  , e.entity
+  , coalesce(e.entity->>'full_name', '') AS name
+  , coalesce(e.entity->>'job_title', '') AS title
+  , r.id AS run_id
+  , r.url
+  , r.created_at
+  FROM runs r, jsonb_array_elements(r.people) AS e (entity)
+  WHERE jsonb_typeof(r.people) = 'array'
+  {{end -}}
+  {{if not .Kind -}}
+  UNION ALL
+  {{end -}}
+  {{if ne .Kind "person" -}}
+  SELECT
+    'company' AS kind
+  , e.entity
+  , coalesce(e.entity->>'name', '') AS name
+  , coalesce(e.entity->>'industry', '') AS title
+  , r.id AS run_id
+  , r.url
+  , r.created_at
+  FROM runs r, jsonb_array_elements(r.companies) AS e (entity)
+  WHERE jsonb_typeof(r.companies) = 'array'
+  {{end -}}
+), matches AS (
+  -- Each entity is found once, by its most recent run.
+  SELECT DISTINCT ON (m.kind, lower(m.name))
+    m.*
+  FROM (
+    SELECT
+      en.*
+    , (ts_rank(setweight(to_tsvector('simple', en.name), 'A') || setweight(to_tsvector('english', en.title), 'B'), q.tsquery)
+      + word_similarity(@query, en.name))::float8 AS rank
+    FROM entities en, query q
+    WHERE (setweight(to_tsvector('simple', en.name), 'A') || setweight(to_tsvector('english', en.title), 'B')) @@ q.tsquery
+     OR @query <% en.name
+  ) m
+  ORDER BY m.kind, lower(m.name), m.created_at DESC, m.run_id DESC
+)
+SELECT
+  m.kind
+, m.entity
+, m.name
+, m.title
+, ts_headline(
+    'english',
+    concat_ws(' - ', nullif(m.name, ''), nullif(m.title, '')),
+    q.tsquery,
+    'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+  ) AS highlight
+, m.rank
+, m.run_id
+, m.url
+, m.created_at
+FROM matches m, query q
+ORDER BY m.rank DESC, m.created_at DESC, m.run_id DESC
+LIMIT @limit
@@ -0,0 +1,33 @@
+SELECT
+{{if eq .GroupBy "url" -}}
+  ed.url AS key
+{{else if eq .GroupBy "domain" -}}
+  substr(
+    substr(ed.url, instr(ed.url, '://') + 3),
+    1,
+    instr(substr(ed.url, instr(ed.url, '://') + 3) || '/', '/') - 1
+  ) AS key
+{{else -}}
+  substr(ed.created_at, 1, 10) AS key
+{{end -}}
+, count(*) AS runs
+, coalesce(sum(ed.prompt_tokens), 0) AS prompt_tokens
+, coalesce(sum(ed.completion_tokens), 0) AS completion_tokens
+, coalesce(sum(ed.cost_usd), 0.0) AS cost_usd
+, coalesce(avg(ed.latency_ms), 0.0) AS avg_latency_ms
+FROM extracted_data ed
+WHERE TRUE
+{{if not .AllWorkspaces -}}
+ AND ed.workspace_id = @workspace_id
+{{end -}}
+{{if .ClientID -}}
+ AND ed.client_id = @client_id
+{{end -}}
+{{if not .CreatedAtFrom.IsZero -}}
+ AND ed.created_at >= @created_at_from
+{{end -}}
+{{if not .CreatedAtTo.IsZero -}}
+ AND ed.created_at <= @created_at_to
+{{end -}}
+GROUP BY 1
+ORDER BY 1
@@ -0,0 +1,9 @@
+UPDATE extracted_data
+SET
+  deleted_at = @deleted_at
+, people = '[]'
+, person_phones = '[]'
+, companies = '[]'
+WHERE workspace_id = @workspace_id
+ AND id = @id
+ AND deleted_at IS NULL
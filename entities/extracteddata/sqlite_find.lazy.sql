@@ -0,0 +1,98 @@
+SELECT
+  ed.id
+, ed.url
+, ed.people
+, ed.companies
+, ed.model
+, ed.prompt_tokens
+, ed.completion_tokens
+, ed.latency_ms
+, ed.cost_usd
+, ed.client_id
+, ed.api_key_id
+, ed.workspace_id
+, ed.created_at
+FROM extracted_data ed
+WHERE ed.deleted_at IS NULL
+{{if not .AllWorkspaces -}}
+ AND ed.workspace_id = @workspace_id
+{{end -}}
+{{if .ID -}}
+ AND ed.id = @id
+{{end -}}
+{{if .URL -}}
+ AND ed.url = @url
+{{end -}}
+{{if .DomainPrefix -}}
+ AND substr(ed.url, instr(ed.url, '://') + 3, length(@domain_prefix)) = @domain_prefix
+{{end -}}
+{{if not .CreatedAtFrom.IsZero -}}
+ AND ed.created_at >= @created_at_from
+{{end -}}
+{{if not .CreatedAtTo.IsZero -}}
+ AND ed.created_at <= @created_at_to
+{{end -}}
+{{if not .BeforeCreatedAt.IsZero -}}
+ AND (ed.created_at, ed.id) < (@before_created_at, @before_id)
+{{end -}}
+{{if or .PersonEmail .PersonName .PersonPhone -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.people) = 'array' THEN ed.people ELSE '[]' END) p
+  WHERE FALSE
+  {{if .PersonEmail -}}
+   OR lower(json_extract(p.value, '$.contact.email')) = lower(@person_email)
+  {{end -}}
+  {{if .PersonName -}}
+   OR lower(json_extract(p.value, '$.full_name')) = lower(@person_name)
+  {{end -}}
+  {{if .PersonPhone -}}
+   OR @person_phone IN (SELECT value FROM json_each(ed.person_phones))
+  {{end -}}
+ )
+{{end -}}
+{{if .JobTitle -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.people) = 'array' THEN ed.people ELSE '[]' END) p
+  WHERE lower(json_extract(p.value, '$.job_title')) = lower(@job_title)
+ )
+{{end -}}
+{{if .EmailDomain -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.people) = 'array' THEN ed.people ELSE '[]' END) p
+  WHERE instr(json_extract(p.value, '$.contact.email'), '@') > 0
+   AND lower(substr(json_extract(p.value, '$.contact.email'), instr(json_extract(p.value, '$.contact.email'), '@') + 1))
+    = lower(@email_domain)
+ )
+{{end -}}
+{{if .CompanyName -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.companies) = 'array' THEN ed.companies ELSE '[]' END) c
+  WHERE lower(json_extract(c.value, '$.name')) = lower(@company_name)
+ )
+{{end -}}
+{{if .Industry -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.companies) = 'array' THEN ed.companies ELSE '[]' END) c
+  WHERE lower(json_extract(c.value, '$.industry')) = lower(@industry)
+ )
+{{end -}}
+{{if .TechStack -}}
+ AND EXISTS (
+  SELECT 1
+  FROM json_each(CASE WHEN json_type(ed.companies) = 'array' THEN ed.companies ELSE '[]' END) c,
+   json_each(CASE WHEN json_type(c.value, '$.tech_stack') = 'array' THEN json_extract(c.value, '$.tech_stack') ELSE '[]' END) t
+  WHERE lower(t.value) = lower(@tech_stack)
+ )
+{{end -}}
+ORDER BY ed.created_at DESC, ed.id DESC
+{{if or .Limit .Offset -}}
+ LIMIT {{if .Limit}}@limit{{else}}-1{{end}}
+{{end -}}
+{{if .Offset -}}
+ OFFSET @offset
+{{end -}}
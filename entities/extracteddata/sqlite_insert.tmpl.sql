@@ -0,0 +1,31 @@
+INSERT INTO extracted_data (
+  url
+, people
+, person_phones
+, companies
+, model
+, prompt_tokens
+, completion_tokens
+, latency_ms
+, cost_usd
+, client_id
+, api_key_id
+, workspace_id
+, created_at
+)
+VALUES (
+  @url
+, json(@people)
+, json(@person_phones)
+, json(@companies)
+, @model
+, @prompt_tokens
+, @completion_tokens
+, @latency_ms
+, @cost_usd
+, @client_id
+, @api_key_id
+, @workspace_id
+, @created_at
+)
+returning id
@@ -0,0 +1,28 @@
+{{if .DryRun -}}
+SELECT count(*)
+{{- else -}}
+DELETE FROM extracted_data
+WHERE id IN (
+SELECT ed.id
+{{- end}}
+FROM extracted_data ed
+WHERE ed.created_at < @created_before
+{{if not .AllWorkspaces -}}
+ AND ed.workspace_id = @workspace_id
+{{end -}}
+ AND {{if eq .Kind "latest"}}NOT {{end}}(
+  ed.deleted_at IS NOT NULL
+  OR EXISTS (
+    SELECT 1
+    FROM extracted_data newer
+    WHERE newer.workspace_id = ed.workspace_id
+     AND newer.url = ed.url
+     AND newer.deleted_at IS NULL
+     AND (newer.created_at, newer.id) > (ed.created_at, ed.id)
+  )
+)
+{{if not .DryRun -}}
+ORDER BY ed.created_at, ed.id
+LIMIT @limit
+)
+{{end -}}
@@ -0,0 +1,339 @@
+package extracteddata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/sqliteutil"
+)
+
+// sqliteTimeLayout is a fixed width layout, so that timestamps stored as text sort chronologically.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// NewSQLiteRepository returns a SQLite backed repository.
+func NewSQLiteRepository(db *sql.DB) Repository {
+	return &sqliteRepository{
+		db: db,
+	}
+}
+
+type sqliteRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteRepository) Insert(ctx context.Context, extractedData *ExtractedData) (*ExtractedData, error) {
+	if extractedData.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *extractedData
+	extractedData = &cpy
+
+	extractedData.CreatedAt = time.Now().UTC()
+
+	peopleJSON, err := json.Marshal(extractedData.People)
+	if err != nil {
+		return nil, err
+	}
+	companiesJSON, err := json.Marshal(extractedData.Companies)
+	if err != nil {
+		return nil, err
+	}
+	phonesJSON, err := json.Marshal(personPhones(extractedData.People))
+	if err != nil {
+		return nil, err
+	}
+
+	query := files.File("sqlite_insert.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"url":               extractedData.URL,
+		"people":            string(peopleJSON),
+		"person_phones":     string(phonesJSON),
+		"companies":         string(companiesJSON),
+		"model":             extractedData.Model,
+		"prompt_tokens":     extractedData.PromptTokens,
+		"completion_tokens": extractedData.CompletionTokens,
+		"latency_ms":        extractedData.LatencyMs,
+		"cost_usd":          extractedData.CostUSD,
+		"client_id":         extractedData.ClientID,
+		"api_key_id":        extractedData.APIKeyID,
+		"workspace_id":      extractedData.WorkspaceID,
+		"created_at":        extractedData.CreatedAt.Format(sqliteTimeLayout),
+	})
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&extractedData.ID); err != nil {
+		return nil, err
+	}
+	return extractedData, nil
+}
+
+func (r *sqliteRepository) Find(ctx context.Context, search Search) ([]ExtractedData, error) {
+	extractedDataList := []ExtractedData{}
+	if err := r.Stream(ctx, search, func(extractedData *ExtractedData) error {
+		extractedDataList = append(extractedDataList, *extractedData)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return extractedDataList, nil
+}
+
+func (r *sqliteRepository) Stream(ctx context.Context, search Search, fn func(extractedData *ExtractedData) error) error {
+	if search.WorkspaceID == "" {
+		return ErrWorkspaceRequired
+	}
+
+	query := files.Template("sqlite_find.lazy.sql", search)
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id":      search.WorkspaceID,
+		"id":                search.ID,
+		"url":               search.URL,
+		"domain_prefix":     search.DomainPrefix,
+		"limit":             search.Limit,
+		"offset":            search.Offset,
+		"created_at_from":   search.CreatedAtFrom.UTC().Format(sqliteTimeLayout),
+		"created_at_to":     search.CreatedAtTo.UTC().Format(sqliteTimeLayout),
+		"before_created_at": search.BeforeCreatedAt.UTC().Format(sqliteTimeLayout),
+		"before_id":         search.BeforeID,
+		"person_email":      search.PersonEmail,
+		"person_name":       search.PersonName,
+		"person_phone":      search.PersonPhone,
+		"job_title":         search.JobTitle,
+		"email_domain":      search.EmailDomain,
+		"company_name":      search.CompanyName,
+		"industry":          search.Industry,
+		"tech_stack":        search.TechStack,
+	})
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			extractedData                        ExtractedData
+			peopleJSON, companiesJSON, createdAt string
+		)
+		if err := rows.Scan(
+			&extractedData.ID,
+			&extractedData.URL,
+			&peopleJSON,
+			&companiesJSON,
+			&extractedData.Model,
+			&extractedData.PromptTokens,
+			&extractedData.CompletionTokens,
+			&extractedData.LatencyMs,
+			&extractedData.CostUSD,
+			&extractedData.ClientID,
+			&extractedData.APIKeyID,
+			&extractedData.WorkspaceID,
+			&createdAt,
+		); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(peopleJSON), &extractedData.People); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(companiesJSON), &extractedData.Companies); err != nil {
+			return err
+		}
+		if extractedData.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return err
+		}
+		if err := fn(&extractedData); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *sqliteRepository) GetLastByURL(ctx context.Context, workspaceID string, url string) (*ExtractedData, error) {
+	if url == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, URL: url, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(extractedDataList) == 0 {
+		return nil, ErrNotFound
+	}
+	return &extractedDataList[0], nil
+}
+
+func (r *sqliteRepository) GetByID(ctx context.Context, workspaceID string, id uint64) (*ExtractedData, error) {
+	// A zero ID would not filter the search, which would return the most recent run.
+	if id == 0 {
+		return nil, ErrNotFound
+	}
+	extractedDataList, err := r.Find(ctx, Search{WorkspaceID: workspaceID, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(extractedDataList) == 0 {
+		return nil, ErrNotFound
+	}
+	return &extractedDataList[0], nil
+}
+
+func (r *sqliteRepository) Delete(ctx context.Context, workspaceID string, id uint64) error {
+	if workspaceID == "" || workspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	query := files.File("sqlite_delete.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id": workspaceID,
+		"id":           id,
+		"deleted_at":   time.Now().UTC().Format(sqliteTimeLayout),
+	})
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteRepository) UpdateEntities(ctx context.Context, extractedData *ExtractedData) error {
+	if extractedData.WorkspaceID == "" || extractedData.WorkspaceID == workspaces.AllID {
+		return ErrWorkspaceRequired
+	}
+
+	peopleJSON, err := json.Marshal(extractedData.People)
+	if err != nil {
+		return err
+	}
+	companiesJSON, err := json.Marshal(extractedData.Companies)
+	if err != nil {
+		return err
+	}
+	phonesJSON, err := json.Marshal(personPhones(extractedData.People))
+	if err != nil {
+		return err
+	}
+
+	query := files.File("sqlite_update_entities.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id":  extractedData.WorkspaceID,
+		"id":            extractedData.ID,
+		"people":        string(peopleJSON),
+		"person_phones": string(phonesJSON),
+		"companies":     string(companiesJSON),
+	})
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteRepository) AggregateUsage(ctx context.Context, search UsageSearch) ([]UsageAggregate, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	query := files.Template("sqlite_aggregate_usage.lazy.sql", search)
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id":    search.WorkspaceID,
+		"client_id":       search.ClientID,
+		"created_at_from": search.CreatedAtFrom.UTC().Format(sqliteTimeLayout),
+		"created_at_to":   search.CreatedAtTo.UTC().Format(sqliteTimeLayout),
+	})
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := []UsageAggregate{}
+	for rows.Next() {
+		var aggregate UsageAggregate
+		if err := rows.Scan(
+			&aggregate.Key,
+			&aggregate.Runs,
+			&aggregate.PromptTokens,
+			&aggregate.CompletionTokens,
+			&aggregate.CostUSD,
+			&aggregate.AvgLatencyMs,
+		); err != nil {
+			return nil, err
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+	return aggregates, rows.Err()
+}
+
+func (r *sqliteRepository) Purge(ctx context.Context, purge Purge) (int64, error) {
+	if err := purge.validate(); err != nil {
+		return 0, err
+	}
+
+	query := files.Template("sqlite_purge.lazy.sql", purge)
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id":   purge.WorkspaceID,
+		"created_before": purge.CreatedBefore.UTC().Format(sqliteTimeLayout),
+		"limit":          purge.Limit,
+	})
+	if purge.DryRun {
+		var n int64
+		err := r.db.QueryRowContext(ctx, query, args...).Scan(&n)
+		return n, err
+	}
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sqliteRepository) SearchEntities(ctx context.Context, search EntitySearch) ([]EntityMatch, error) {
+	if err := search.validate(); err != nil {
+		return nil, err
+	}
+
+	// SQLite has no full-text index over the JSON entities, the runs are matched one by one.
+	matcher := newEntityMatcher(search)
+	if err := r.Stream(ctx, Search{WorkspaceID: search.WorkspaceID}, func(extractedData *ExtractedData) error {
+		matcher.add(extractedData)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return matcher.result(), nil
+}
+
+// personPhones returns the normalized phone numbers of people, stored alongside them as SQLite has no function
+// keeping the digits of a text.
+func personPhones(persons []people.Person) []string {
+	phones := make([]string, 0, len(persons))
+	for _, person := range persons {
+		if phone := people.NormalizePhone(person.Contact.Phone); phone != "" {
+			phones = append(phones, phone)
+		}
+	}
+	return phones
+}
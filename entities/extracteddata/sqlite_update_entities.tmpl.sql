@@ -0,0 +1,8 @@
+UPDATE extracted_data
+SET
+  people = @people
+, person_phones = @person_phones
+, companies = @companies
+WHERE workspace_id = @workspace_id
+ AND id = @id
+ AND deleted_at IS NULL
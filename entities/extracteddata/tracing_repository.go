@@ -0,0 +1,126 @@
+package extracteddata
+
+import (
+	"context"
+
+	"github.com/solher/hunterio-test/lib/otelutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/solher/hunterio-test/entities/extracteddata")
+
+// NewTracingRepository returns a repository recording a span for each query made to next.
+// The system names the underlying database, like postgresql or sqlite.
+func NewTracingRepository(next Repository, system string) Repository {
+	return &tracingRepository{
+		next:   next,
+		system: system,
+	}
+}
+
+type tracingRepository struct {
+	next   Repository
+	system string
+}
+
+func (r *tracingRepository) Insert(ctx context.Context, extractedData *ExtractedData) (_ *ExtractedData, err error) {
+	ctx, span := r.start(ctx, "Insert")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	return r.next.Insert(ctx, extractedData)
+}
+
+func (r *tracingRepository) Find(ctx context.Context, search Search) (_ []ExtractedData, err error) {
+	ctx, span := r.start(ctx, "Find")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	extractedDataList, err := r.next.Find(ctx, search)
+	span.SetAttributes(attribute.Int("db.rows", len(extractedDataList)))
+	return extractedDataList, err
+}
+
+func (r *tracingRepository) Stream(ctx context.Context, search Search, fn func(extractedData *ExtractedData) error) (err error) {
+	ctx, span := r.start(ctx, "Stream")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	rows := 0
+	err = r.next.Stream(ctx, search, func(extractedData *ExtractedData) error {
+		rows++
+		return fn(extractedData)
+	})
+	span.SetAttributes(attribute.Int("db.rows", rows))
+	return err
+}
+
+func (r *tracingRepository) GetLastByURL(ctx context.Context, workspaceID string, url string) (_ *ExtractedData, err error) {
+	ctx, span := r.start(ctx, "GetLastByURL")
+	defer func() {
+		// Not finding a run is the expected outcome of a cache miss.
+		if err != ErrNotFound {
+			otelutil.RecordError(span, err)
+		}
+		span.End()
+	}()
+
+	return r.next.GetLastByURL(ctx, workspaceID, url)
+}
+
+func (r *tracingRepository) GetByID(ctx context.Context, workspaceID string, id uint64) (_ *ExtractedData, err error) {
+	ctx, span := r.start(ctx, "GetByID")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	return r.next.GetByID(ctx, workspaceID, id)
+}
+
+func (r *tracingRepository) Delete(ctx context.Context, workspaceID string, id uint64) (err error) {
+	ctx, span := r.start(ctx, "Delete")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	return r.next.Delete(ctx, workspaceID, id)
+}
+
+func (r *tracingRepository) UpdateEntities(ctx context.Context, extractedData *ExtractedData) (err error) {
+	ctx, span := r.start(ctx, "UpdateEntities")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	return r.next.UpdateEntities(ctx, extractedData)
+}
+
+func (r *tracingRepository) AggregateUsage(ctx context.Context, search UsageSearch) (_ []UsageAggregate, err error) {
+	ctx, span := r.start(ctx, "AggregateUsage")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	return r.next.AggregateUsage(ctx, search)
+}
+
+func (r *tracingRepository) Purge(ctx context.Context, purge Purge) (n int64, err error) {
+	ctx, span := r.start(ctx, "Purge")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	n, err = r.next.Purge(ctx, purge)
+	span.SetAttributes(attribute.Int64("db.rows", n))
+	return n, err
+}
+
+func (r *tracingRepository) SearchEntities(ctx context.Context, search EntitySearch) (matches []EntityMatch, err error) {
+	ctx, span := r.start(ctx, "SearchEntities")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
+	matches, err = r.next.SearchEntities(ctx, search)
+	span.SetAttributes(attribute.Int("db.rows", len(matches)))
+	return matches, err
+}
+
+// start starts a client span named after the repository method.
+func (r *tracingRepository) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "extracteddata."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(r.system),
+			semconv.DBOperationName(method),
+		),
+	)
+}
@@ -0,0 +1,7 @@
+UPDATE extracted_data
+SET
+  people = @people
+, companies = @companies
+WHERE workspace_id = @workspace_id
+ AND id = @id
+ AND deleted_at IS NULL
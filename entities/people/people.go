@@ -1,18 +1,30 @@
 package people
 
+import "strings"
+
 // Person represents a person.
 type Person struct {
-	FullName string  `json:"full_name"`
-	JobTitle string  `json:"job_title"`
-	Contact  Contact `json:"contact"`
+	FullName string  `json:"full_name" xml:"full_name"`
+	JobTitle string  `json:"job_title" xml:"job_title"`
+	Contact  Contact `json:"contact" xml:"contact"`
 }
 
 // Contact represents a person's contact information.
 type Contact struct {
-	Email        string `json:"email"`
-	Phone        string `json:"phone"`
-	LinkedinURL  string `json:"linkedin_url"`
-	XURL         string `json:"x_url"`
-	InstagramURL string `json:"instagram_url"`
-	FacebookURL  string `json:"facebook_url"`
+	Email        string `json:"email" xml:"email"`
+	Phone        string `json:"phone" xml:"phone"`
+	LinkedinURL  string `json:"linkedin_url" xml:"linkedin_url"`
+	XURL         string `json:"x_url" xml:"x_url"`
+	InstagramURL string `json:"instagram_url" xml:"instagram_url"`
+	FacebookURL  string `json:"facebook_url" xml:"facebook_url"`
+}
+
+// NormalizePhone returns the digits of a phone number, so that its formattings compare equal.
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
 }
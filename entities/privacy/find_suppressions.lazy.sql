@@ -0,0 +1,15 @@
+SELECT
+  ps.id
+, ps.workspace_id
+, ps.kind
+, ps.hash
+, ps.created_at
+FROM privacy_suppressions ps
+WHERE ps.workspace_id = @workspace_id
+{{if .ID -}}
+ AND ps.id = @id
+{{end -}}
+{{if .Hashes -}}
+ AND ps.hash = ANY(@hashes)
+{{end -}}
+ORDER BY ps.id DESC
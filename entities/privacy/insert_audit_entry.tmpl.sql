@@ -0,0 +1,21 @@
+INSERT INTO privacy_audit_log (
+  workspace_id
+, action
+, subject_hashes
+, api_key_id
+, runs
+, watch_changes
+, webhook_deliveries
+, created_at
+)
+VALUES (
+  @workspace_id
+, @action
+, @subject_hashes
+, @api_key_id
+, @runs
+, @watch_changes
+, @webhook_deliveries
+, @created_at
+)
+returning id
@@ -0,0 +1,131 @@
+package privacy
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepository returns an in-memory repository, for tests and ephemeral runs.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+type memoryRepository struct {
+	mu                sync.RWMutex
+	lastSuppressionID uint64
+	lastAuditEntryID  uint64
+	suppressions      []Suppression
+	auditEntries      []AuditEntry
+}
+
+func (r *memoryRepository) InsertSuppression(ctx context.Context, suppression *Suppression) (*Suppression, error) {
+	if suppression.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.suppressions {
+		if row.WorkspaceID == suppression.WorkspaceID && row.Hash == suppression.Hash {
+			return &row, nil
+		}
+	}
+
+	cpy := *suppression
+	suppression = &cpy
+
+	r.lastSuppressionID++
+	suppression.ID = r.lastSuppressionID
+	suppression.CreatedAt = time.Now().UTC()
+	r.suppressions = append(r.suppressions, *suppression)
+
+	return suppression, nil
+}
+
+func (r *memoryRepository) FindSuppressions(ctx context.Context, search SuppressionSearch) ([]Suppression, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	suppressions := []Suppression{}
+	for _, row := range r.suppressions {
+		if row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if search.ID != 0 && row.ID != search.ID {
+			continue
+		}
+		if len(search.Hashes) > 0 && !slices.Contains(search.Hashes, row.Hash) {
+			continue
+		}
+		suppressions = append(suppressions, row)
+	}
+
+	// Most recent first, as in the Postgres repository.
+	sort.SliceStable(suppressions, func(i, j int) bool { return suppressions[i].ID > suppressions[j].ID })
+	return suppressions, nil
+}
+
+func (r *memoryRepository) DeleteSuppression(ctx context.Context, workspaceID string, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.suppressions, func(row Suppression) bool {
+		return row.WorkspaceID == workspaceID && row.ID == id
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.suppressions = slices.Delete(r.suppressions, i, i+1)
+	return nil
+}
+
+func (r *memoryRepository) InsertAuditEntry(ctx context.Context, entry *AuditEntry) (*AuditEntry, error) {
+	if entry.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *entry
+	entry = &cpy
+	entry.SubjectHashes = slices.Clone(entry.SubjectHashes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastAuditEntryID++
+	entry.ID = r.lastAuditEntryID
+	entry.CreatedAt = time.Now().UTC()
+	r.auditEntries = append(r.auditEntries, *entry)
+
+	return entry, nil
+}
+
+func (r *memoryRepository) FindAuditEntries(ctx context.Context, search AuditSearch) ([]AuditEntry, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []AuditEntry{}
+	for _, row := range r.auditEntries {
+		if row.WorkspaceID == search.WorkspaceID {
+			entries = append(entries, row)
+		}
+	}
+
+	// Most recent first, as in the Postgres repository.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	if search.Limit > 0 && search.Limit < len(entries) {
+		entries = entries[:search.Limit]
+	}
+	return entries, nil
+}
@@ -0,0 +1,115 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/lib/pgutil"
+)
+
+// NewPostgresRepository returns a Postgres backed repository.
+func NewPostgresRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func (r *postgresRepository) InsertSuppression(ctx context.Context, suppression *Suppression) (*Suppression, error) {
+	if suppression.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *suppression
+	suppression = &cpy
+
+	suppression.CreatedAt = time.Now().UTC()
+
+	// The creation date is the one of the existing suppression, if any.
+	if err := pgutil.InWorkspace(ctx, r.db, suppression.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert_suppression.tmpl.sql"), pgutil.ToNamedArgs(suppression)).Scan(
+			&suppression.ID,
+			&suppression.CreatedAt,
+		)
+	}); err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+func (r *postgresRepository) FindSuppressions(ctx context.Context, search SuppressionSearch) ([]Suppression, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	var suppressions []Suppression
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find_suppressions.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		suppressions, err = pgx.CollectRows(rows, pgx.RowToStructByName[Suppression])
+		return err
+	})
+	return suppressions, err
+}
+
+func (r *postgresRepository) DeleteSuppression(ctx context.Context, workspaceID string, id uint64) error {
+	return pgutil.InWorkspace(ctx, r.db, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("delete_suppression.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": workspaceID,
+			"id":           id,
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) InsertAuditEntry(ctx context.Context, entry *AuditEntry) (*AuditEntry, error) {
+	if entry.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *entry
+	entry = &cpy
+
+	entry.CreatedAt = time.Now().UTC()
+	if entry.SubjectHashes == nil {
+		entry.SubjectHashes = []string{}
+	}
+
+	if err := pgutil.InWorkspace(ctx, r.db, entry.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert_audit_entry.tmpl.sql"), pgutil.ToNamedArgs(entry)).Scan(&entry.ID)
+	}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *postgresRepository) FindAuditEntries(ctx context.Context, search AuditSearch) ([]AuditEntry, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	var entries []AuditEntry
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find_audit_entries.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		entries, err = pgx.CollectRows(rows, pgx.RowToStructByName[AuditEntry])
+		return err
+	})
+	return entries, err
+}
@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+// Kinds of the identifiers of a data subject.
+const (
+	KindEmail = "email"
+	KindName  = "name"
+	KindPhone = "phone"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionSearch     = "search"
+	ActionErase      = "erase"
+	ActionRedact     = "redact"
+	ActionUnsuppress = "unsuppress"
+)
+
+// Suppression prevents the people holding an identifier from being ingested again.
+// The identifier is only kept hashed, see Hash.
+type Suppression struct {
+	ID          uint64    `json:"id" xml:"id" db:"id"`
+	WorkspaceID string    `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	Kind        string    `json:"kind" xml:"kind" db:"kind"`
+	Hash        string    `json:"hash" xml:"hash" db:"hash"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at" db:"created_at"`
+}
+
+// AuditEntry records an action on the personal data of a subject, along with the number of runs,
+// watch changes and webhook deliveries it found or changed. The subject is only kept hashed.
+type AuditEntry struct {
+	ID                uint64    `json:"id" xml:"id" db:"id"`
+	WorkspaceID       string    `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	Action            string    `json:"action" xml:"action" db:"action"`
+	SubjectHashes     []string  `json:"subject_hashes" xml:"subject_hashes>hash" db:"subject_hashes"`
+	APIKeyID          uint64    `json:"api_key_id" xml:"api_key_id" db:"api_key_id"`
+	Runs              int       `json:"runs" xml:"runs" db:"runs"`
+	WatchChanges      int       `json:"watch_changes" xml:"watch_changes" db:"watch_changes"`
+	WebhookDeliveries int       `json:"webhook_deliveries" xml:"webhook_deliveries" db:"webhook_deliveries"`
+	CreatedAt         time.Time `json:"created_at" xml:"created_at" db:"created_at"`
+}
+
+var (
+	ErrNotFound          = errors.New("suppression not found")
+	ErrWorkspaceRequired = errors.New("workspace cannot be empty")
+)
+
+// Repository provides access to a Suppression and AuditEntry store.
+type Repository interface {
+	// InsertSuppression returns the suppression of the hash if there is one already.
+	InsertSuppression(ctx context.Context, suppression *Suppression) (*Suppression, error)
+	FindSuppressions(ctx context.Context, search SuppressionSearch) ([]Suppression, error)
+	DeleteSuppression(ctx context.Context, workspaceID string, id uint64) error
+	InsertAuditEntry(ctx context.Context, entry *AuditEntry) (*AuditEntry, error)
+	FindAuditEntries(ctx context.Context, search AuditSearch) ([]AuditEntry, error)
+}
+
+// SuppressionSearch allows suppression searching, most recent first. The workspace is required.
+// Hashes restricts the suppressions to the ones of these hashes, if not empty.
+type SuppressionSearch struct {
+	WorkspaceID string   `db:"workspace_id"`
+	ID          uint64   `db:"id"`
+	Hashes      []string `db:"hashes"`
+}
+
+// AuditSearch allows audit log searching, most recent first. The workspace is required.
+type AuditSearch struct {
+	WorkspaceID string `db:"workspace_id"`
+	Limit       int    `db:"limit"`
+}
+
+// Hash returns the hash of an identifier, normalized so that its spellings hash alike: emails and names
+// regardless of case, phones regardless of formatting. It is empty for an empty identifier.
+func Hash(kind string, value string) string {
+	switch kind {
+	case KindPhone:
+		value = people.NormalizePhone(value)
+	default:
+		value = strings.ToLower(strings.TrimSpace(value))
+	}
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(kind + ":" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// PersonHashes returns the hashes of the identifiers of a person.
+func PersonHashes(person people.Person) []string {
+	hashes := []string{}
+	for _, identifier := range [][2]string{
+		{KindEmail, person.Contact.Email},
+		{KindName, person.FullName},
+		{KindPhone, person.Contact.Phone},
+	} {
+		if hash := Hash(identifier[0], identifier[1]); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
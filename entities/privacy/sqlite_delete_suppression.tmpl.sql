@@ -0,0 +1,3 @@
+DELETE FROM privacy_suppressions
+WHERE workspace_id = @workspace_id
+ AND id = @id
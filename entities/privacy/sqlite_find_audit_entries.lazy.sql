@@ -0,0 +1,16 @@
+SELECT
+  pal.id
+, pal.workspace_id
+, pal.action
+, pal.subject_hashes
+, pal.api_key_id
+, pal.runs
+, pal.watch_changes
+, pal.webhook_deliveries
+, pal.created_at
+FROM privacy_audit_log pal
+WHERE pal.workspace_id = @workspace_id
+ORDER BY pal.id DESC
+{{if .Limit -}}
+LIMIT @limit
+{{end -}}
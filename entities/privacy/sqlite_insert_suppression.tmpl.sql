@@ -0,0 +1,14 @@
+INSERT INTO privacy_suppressions (
+  workspace_id
+, kind
+, hash
+, created_at
+)
+VALUES (
+  @workspace_id
+, @kind
+, @hash
+, @created_at
+)
+ON CONFLICT (workspace_id, hash) DO UPDATE SET hash = EXCLUDED.hash
+returning id, created_at
@@ -0,0 +1,198 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/lib/sqliteutil"
+)
+
+// sqliteTimeLayout is a fixed width layout, so that timestamps stored as text sort chronologically.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// NewSQLiteRepository returns a SQLite backed repository.
+func NewSQLiteRepository(db *sql.DB) Repository {
+	return &sqliteRepository{
+		db: db,
+	}
+}
+
+type sqliteRepository struct {
+	db *sql.DB
+}
+
+func (r *sqliteRepository) InsertSuppression(ctx context.Context, suppression *Suppression) (*Suppression, error) {
+	if suppression.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *suppression
+	suppression = &cpy
+
+	query := files.File("sqlite_insert_suppression.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id": suppression.WorkspaceID,
+		"kind":         suppression.Kind,
+		"hash":         suppression.Hash,
+		"created_at":   time.Now().UTC().Format(sqliteTimeLayout),
+	})
+	// The creation date is the one of the existing suppression, if any.
+	var createdAt string
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&suppression.ID, &createdAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if suppression.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+func (r *sqliteRepository) FindSuppressions(ctx context.Context, search SuppressionSearch) ([]Suppression, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	hashesJSON, err := json.Marshal(search.Hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := files.Template("sqlite_find_suppressions.lazy.sql", search)
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id": search.WorkspaceID,
+		"id":           search.ID,
+		"hashes":       string(hashesJSON),
+	})
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressions := []Suppression{}
+	for rows.Next() {
+		var (
+			suppression Suppression
+			createdAt   string
+		)
+		if err := rows.Scan(
+			&suppression.ID,
+			&suppression.WorkspaceID,
+			&suppression.Kind,
+			&suppression.Hash,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		if suppression.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	return suppressions, rows.Err()
+}
+
+func (r *sqliteRepository) DeleteSuppression(ctx context.Context, workspaceID string, id uint64) error {
+	query := files.File("sqlite_delete_suppression.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id": workspaceID,
+		"id":           id,
+	})
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteRepository) InsertAuditEntry(ctx context.Context, entry *AuditEntry) (*AuditEntry, error) {
+	if entry.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	cpy := *entry
+	entry = &cpy
+
+	entry.CreatedAt = time.Now().UTC()
+	if entry.SubjectHashes == nil {
+		entry.SubjectHashes = []string{}
+	}
+
+	subjectHashesJSON, err := json.Marshal(entry.SubjectHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := files.File("sqlite_insert_audit_entry.tmpl.sql")
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id":       entry.WorkspaceID,
+		"action":             entry.Action,
+		"subject_hashes":     string(subjectHashesJSON),
+		"api_key_id":         entry.APIKeyID,
+		"runs":               entry.Runs,
+		"watch_changes":      entry.WatchChanges,
+		"webhook_deliveries": entry.WebhookDeliveries,
+		"created_at":         entry.CreatedAt.Format(sqliteTimeLayout),
+	})
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&entry.ID); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *sqliteRepository) FindAuditEntries(ctx context.Context, search AuditSearch) ([]AuditEntry, error) {
+	if search.WorkspaceID == "" {
+		return nil, ErrWorkspaceRequired
+	}
+
+	query := files.Template("sqlite_find_audit_entries.lazy.sql", search)
+	args := sqliteutil.ToNamedArgs(query, map[string]any{
+		"workspace_id": search.WorkspaceID,
+		"limit":        search.Limit,
+	})
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var (
+			entry                        AuditEntry
+			subjectHashesJSON, createdAt string
+		)
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.WorkspaceID,
+			&entry.Action,
+			&subjectHashesJSON,
+			&entry.APIKeyID,
+			&entry.Runs,
+			&entry.WatchChanges,
+			&entry.WebhookDeliveries,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(subjectHashesJSON), &entry.SubjectHashes); err != nil {
+			return nil, err
+		}
+		if entry.CreatedAt, err = time.Parse(sqliteTimeLayout, createdAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,23 @@
+-- Concurrent schedulers skip the rows locked by each other, then see them postponed by the lease.
+UPDATE watches w
+SET next_run_at = @leased_until
+FROM (
+  SELECT id
+  FROM watches
+  WHERE next_run_at <= @now
+  ORDER BY next_run_at
+  LIMIT @limit
+  FOR UPDATE SKIP LOCKED
+) due
+WHERE w.id = due.id
+RETURNING
+  w.id
+, w.workspace_id
+, w.url
+, w.schedule
+, w.target
+, w.last_run_id
+, w.last_run_at
+, w.last_error
+, w.next_run_at
+, w.created_at
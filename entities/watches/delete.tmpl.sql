@@ -0,0 +1,3 @@
+DELETE FROM watches
+WHERE workspace_id = @workspace_id
+ AND id = @id
@@ -0,0 +1,17 @@
+SELECT
+  w.id
+, w.workspace_id
+, w.url
+, w.schedule
+, w.target
+, w.last_run_id
+, w.last_run_at
+, w.last_error
+, w.next_run_at
+, w.created_at
+FROM watches w
+WHERE w.workspace_id = @workspace_id
+{{if .ID -}}
+ AND w.id = @id
+{{end -}}
+ORDER BY w.id
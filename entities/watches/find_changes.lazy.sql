@@ -0,0 +1,18 @@
+SELECT
+  wc.id
+, wc.watch_id
+, wc.workspace_id
+, wc.url
+, wc.from_id
+, wc.to_id
+, wc.diff
+, wc.created_at
+FROM watch_changes wc
+WHERE wc.workspace_id = @workspace_id
+{{if .WatchID -}}
+ AND wc.watch_id = @watch_id
+{{end -}}
+ORDER BY wc.id DESC
+{{if .Limit -}}
+LIMIT @limit
+{{end -}}
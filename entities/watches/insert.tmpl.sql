@@ -0,0 +1,23 @@
+INSERT INTO watches (
+  workspace_id
+, url
+, schedule
+, target
+, last_run_id
+, last_run_at
+, last_error
+, next_run_at
+, created_at
+)
+VALUES (
+  @workspace_id
+, @url
+, @schedule
+, @target
+, @last_run_id
+, @last_run_at
+, @last_error
+, @next_run_at
+, @created_at
+)
+returning id
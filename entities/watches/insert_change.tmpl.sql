@@ -0,0 +1,19 @@
+INSERT INTO watch_changes (
+  watch_id
+, workspace_id
+, url
+, from_id
+, to_id
+, diff
+, created_at
+)
+VALUES (
+  @watch_id
+, @workspace_id
+, @url
+, @from_id
+, @to_id
+, @diff
+, @created_at
+)
+returning id
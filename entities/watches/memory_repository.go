@@ -0,0 +1,185 @@
+package watches
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepository returns an in-memory repository, for tests and ephemeral runs.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+type memoryRepository struct {
+	mu           sync.RWMutex
+	lastID       uint64
+	lastChangeID uint64
+	rows         []Watch
+	changes      []Change
+}
+
+func (r *memoryRepository) Insert(ctx context.Context, watch *Watch) (*Watch, error) {
+	if watch.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+	if watch.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	cpy := *watch
+	watch = &cpy
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	watch.ID = r.lastID
+	watch.CreatedAt = time.Now().UTC()
+	r.rows = append(r.rows, *watch)
+
+	return watch, nil
+}
+
+func (r *memoryRepository) Find(ctx context.Context, search Search) ([]Watch, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	watches := []Watch{}
+	for _, row := range r.rows {
+		if row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if search.ID != 0 && row.ID != search.ID {
+			continue
+		}
+		watches = append(watches, row)
+	}
+	return watches, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, workspaceID string, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.rows, func(row Watch) bool {
+		return row.WorkspaceID == workspaceID && row.ID == id
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.rows = slices.Delete(r.rows, i, i+1)
+
+	// The changes go with their watch, as in the Postgres repository.
+	r.changes = slices.DeleteFunc(r.changes, func(row Change) bool {
+		return row.WatchID == id
+	})
+	return nil
+}
+
+func (r *memoryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Watch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	watches := []Watch{}
+	for i := range r.rows {
+		if limit > 0 && len(watches) >= limit {
+			break
+		}
+		if r.rows[i].NextRunAt.After(now) {
+			continue
+		}
+		r.rows[i].NextRunAt = now.Add(lease).UTC()
+		watches = append(watches, r.rows[i])
+	}
+	return watches, nil
+}
+
+func (r *memoryRepository) UpdateRun(ctx context.Context, watch *Watch) (*Watch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID != watch.ID {
+			continue
+		}
+		r.rows[i].LastRunID = watch.LastRunID
+		r.rows[i].LastRunAt = watch.LastRunAt
+		r.rows[i].LastError = watch.LastError
+		r.rows[i].NextRunAt = watch.NextRunAt
+		updated := r.rows[i]
+		return &updated, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepository) InsertChange(ctx context.Context, change *Change) (*Change, error) {
+	if change.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *change
+	change = &cpy
+	change.Diff = slices.Clone(change.Diff)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !slices.ContainsFunc(r.rows, func(row Watch) bool { return row.ID == change.WatchID }) {
+		return nil, ErrNotFound
+	}
+	r.lastChangeID++
+	change.ID = r.lastChangeID
+	change.CreatedAt = time.Now().UTC()
+	r.changes = append(r.changes, *change)
+
+	return change, nil
+}
+
+func (r *memoryRepository) FindChanges(ctx context.Context, search ChangeSearch) ([]Change, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := []Change{}
+	for _, row := range r.changes {
+		if row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if search.WatchID != 0 && row.WatchID != search.WatchID {
+			continue
+		}
+		changes = append(changes, row)
+	}
+
+	// Most recent first, as in the Postgres repository.
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ID > changes[j].ID })
+	if search.Limit > 0 && search.Limit < len(changes) {
+		changes = changes[:search.Limit]
+	}
+	return changes, nil
+}
+
+func (r *memoryRepository) UpdateChangeDiff(ctx context.Context, change *Change) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.changes {
+		if r.changes[i].WorkspaceID != change.WorkspaceID || r.changes[i].ID != change.ID {
+			continue
+		}
+		r.changes[i].Diff = slices.Clone(change.Diff)
+		return nil
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,175 @@
+package watches
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/pgutil"
+)
+
+// NewPostgresRepository returns a Postgres backed repository.
+func NewPostgresRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+// foreignKeyViolation is the Postgres error code of a change inserted for a missing watch.
+const foreignKeyViolation = "23503"
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func (r *postgresRepository) Insert(ctx context.Context, watch *Watch) (*Watch, error) {
+	if watch.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+	if watch.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	cpy := *watch
+	watch = &cpy
+
+	watch.CreatedAt = time.Now().UTC()
+
+	if err := pgutil.InWorkspace(ctx, r.db, watch.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert.tmpl.sql"), pgutil.ToNamedArgs(watch)).Scan(&watch.ID)
+	}); err != nil {
+		return nil, err
+	}
+	return watch, nil
+}
+
+func (r *postgresRepository) Find(ctx context.Context, search Search) ([]Watch, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	var watches []Watch
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		watches, err = pgx.CollectRows(rows, pgx.RowToStructByName[Watch])
+		return err
+	})
+	return watches, err
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, workspaceID string, id uint64) error {
+	return pgutil.InWorkspace(ctx, r.db, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("delete.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": workspaceID,
+			"id":           id,
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Watch, error) {
+	// The scheduler leases the watches of every workspace.
+	var watches []Watch
+	err := pgutil.InWorkspace(ctx, r.db, workspaces.AllID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.File("claim_due.tmpl.sql"), pgx.NamedArgs{
+			"now":          now.UTC(),
+			"leased_until": now.Add(lease).UTC(),
+			"limit":        limit,
+		})
+		if err != nil {
+			return err
+		}
+		watches, err = pgx.CollectRows(rows, pgx.RowToStructByName[Watch])
+		return err
+	})
+	return watches, err
+}
+
+func (r *postgresRepository) UpdateRun(ctx context.Context, watch *Watch) (*Watch, error) {
+	var updated Watch
+	err := pgutil.InWorkspace(ctx, r.db, watch.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.File("update_run.tmpl.sql"), pgutil.ToNamedArgs(watch))
+		if err != nil {
+			return err
+		}
+		updated, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[Watch])
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *postgresRepository) InsertChange(ctx context.Context, change *Change) (*Change, error) {
+	if change.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *change
+	change = &cpy
+
+	change.CreatedAt = time.Now().UTC()
+
+	if err := pgutil.InWorkspace(ctx, r.db, change.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert_change.tmpl.sql"), pgutil.ToNamedArgs(change)).Scan(&change.ID)
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return change, nil
+}
+
+func (r *postgresRepository) FindChanges(ctx context.Context, search ChangeSearch) ([]Change, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	var changes []Change
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find_changes.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		changes, err = pgx.CollectRows(rows, pgx.RowToStructByName[Change])
+		return err
+	})
+	return changes, err
+}
+
+func (r *postgresRepository) UpdateChangeDiff(ctx context.Context, change *Change) error {
+	return pgutil.InWorkspace(ctx, r.db, change.WorkspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("update_change_diff.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": change.WorkspaceID,
+			"id":           change.ID,
+			"diff":         change.Diff,
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
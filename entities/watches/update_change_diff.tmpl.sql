@@ -0,0 +1,4 @@
+UPDATE watch_changes
+SET diff = @diff
+WHERE workspace_id = @workspace_id
+ AND id = @id
@@ -0,0 +1,18 @@
+UPDATE watches
+SET
+  last_run_id = @last_run_id
+, last_run_at = @last_run_at
+, last_error = @last_error
+, next_run_at = @next_run_at
+WHERE id = @id
+RETURNING
+  id
+, workspace_id
+, url
+, schedule
+, target
+, last_run_id
+, last_run_at
+, last_error
+, next_run_at
+, created_at
@@ -0,0 +1,79 @@
+package watches
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Targets notified of the changes of a watched URL.
+const (
+	TargetLog     = "log"
+	TargetWebhook = "webhook"
+	TargetFeed    = "feed"
+)
+
+// Targets lists every valid target.
+var Targets = []string{TargetLog, TargetWebhook, TargetFeed}
+
+// Watch represents a URL extracted again on a schedule, to detect its changes.
+type Watch struct {
+	ID          uint64 `json:"id" xml:"id" db:"id"`
+	WorkspaceID string `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	URL         string `json:"url" xml:"url" db:"url"`
+	// Schedule is a cron expression, or an interval like @every 6h.
+	Schedule string `json:"schedule" xml:"schedule" db:"schedule"`
+	Target   string `json:"target" xml:"target" db:"target"`
+	// LastRunID is the run the next one is compared to.
+	LastRunID uint64     `json:"last_run_id" xml:"last_run_id" db:"last_run_id"`
+	LastRunAt *time.Time `json:"last_run_at" xml:"last_run_at" db:"last_run_at"`
+	LastError string     `json:"last_error" xml:"last_error" db:"last_error"`
+	NextRunAt time.Time  `json:"next_run_at" xml:"next_run_at" db:"next_run_at"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at" db:"created_at"`
+}
+
+// Change represents the differences found between two runs of a watched URL.
+type Change struct {
+	ID          uint64          `json:"id" xml:"id" db:"id"`
+	WatchID     uint64          `json:"watch_id" xml:"watch_id" db:"watch_id"`
+	WorkspaceID string          `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	URL         string          `json:"url" xml:"url" db:"url"`
+	FromID      uint64          `json:"from_id" xml:"from_id" db:"from_id"`
+	ToID        uint64          `json:"to_id" xml:"to_id" db:"to_id"`
+	Diff        json.RawMessage `json:"diff" xml:"diff" db:"diff"`
+	CreatedAt   time.Time       `json:"created_at" xml:"created_at" db:"created_at"`
+}
+
+var (
+	ErrNotFound = errors.New("watch not found")
+)
+
+// Repository provides access to a Watch and Change store.
+type Repository interface {
+	Insert(ctx context.Context, watch *Watch) (*Watch, error)
+	Find(ctx context.Context, search Search) ([]Watch, error)
+	Delete(ctx context.Context, workspaceID string, id uint64) error
+	// ClaimDue returns the watches due at now, and postpones their next run by lease
+	// so that concurrent schedulers do not run them too.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Watch, error)
+	// UpdateRun records the outcome of a run, and schedules the next one.
+	UpdateRun(ctx context.Context, watch *Watch) (*Watch, error)
+	InsertChange(ctx context.Context, change *Change) (*Change, error)
+	FindChanges(ctx context.Context, search ChangeSearch) ([]Change, error)
+	// UpdateChangeDiff replaces the diff of a change, to erase or redact personal data.
+	UpdateChangeDiff(ctx context.Context, change *Change) error
+}
+
+// Search allows watch searching. The workspace is required.
+type Search struct {
+	WorkspaceID string `db:"workspace_id"`
+	ID          uint64 `db:"id"`
+}
+
+// ChangeSearch allows change searching, most recent first. The workspace is required.
+type ChangeSearch struct {
+	WorkspaceID string `db:"workspace_id"`
+	WatchID     uint64 `db:"watch_id"`
+	Limit       int    `db:"limit"`
+}
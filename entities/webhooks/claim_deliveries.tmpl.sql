@@ -0,0 +1,28 @@
+-- Concurrent dispatchers skip the rows locked by each other, then see them postponed by the lease.
+UPDATE webhook_deliveries wd
+SET next_attempt_at = @leased_until
+FROM (
+  SELECT id
+  FROM webhook_deliveries
+  WHERE status = 'pending'
+   AND next_attempt_at <= @now
+  ORDER BY next_attempt_at
+  LIMIT @limit
+  FOR UPDATE SKIP LOCKED
+) due
+WHERE wd.id = due.id
+RETURNING
+  wd.id
+, wd.subscription_id
+, wd.workspace_id
+, wd.event_id
+, wd.event
+, wd.payload
+, wd.status
+, wd.attempts
+, wd.response_status
+, wd.last_error
+, wd.replay_of
+, wd.next_attempt_at
+, wd.created_at
+, wd.delivered_at
@@ -0,0 +1,3 @@
+DELETE FROM webhook_subscriptions
+WHERE workspace_id = @workspace_id
+ AND id = @id
@@ -0,0 +1,30 @@
+SELECT
+  wd.id
+, wd.subscription_id
+, wd.workspace_id
+, wd.event_id
+, wd.event
+, wd.payload
+, wd.status
+, wd.attempts
+, wd.response_status
+, wd.last_error
+, wd.replay_of
+, wd.next_attempt_at
+, wd.created_at
+, wd.delivered_at
+FROM webhook_deliveries wd
+WHERE wd.workspace_id = @workspace_id
+{{if .ID -}}
+ AND wd.id = @id
+{{end -}}
+{{if .SubscriptionID -}}
+ AND wd.subscription_id = @subscription_id
+{{end -}}
+{{if .Status -}}
+ AND wd.status = @status
+{{end -}}
+ORDER BY wd.id DESC
+{{if .Limit -}}
+LIMIT @limit
+{{end -}}
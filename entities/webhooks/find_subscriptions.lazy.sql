@@ -0,0 +1,13 @@
+SELECT
+  ws.id
+, ws.workspace_id
+, ws.url
+, ws.secret
+, ws.events
+, ws.created_at
+FROM webhook_subscriptions ws
+WHERE ws.workspace_id = @workspace_id
+{{if .ID -}}
+ AND ws.id = @id
+{{end -}}
+ORDER BY ws.id
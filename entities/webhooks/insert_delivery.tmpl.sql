@@ -0,0 +1,31 @@
+INSERT INTO webhook_deliveries (
+  subscription_id
+, workspace_id
+, event_id
+, event
+, payload
+, status
+, attempts
+, response_status
+, last_error
+, replay_of
+, next_attempt_at
+, created_at
+, delivered_at
+)
+VALUES (
+  @subscription_id
+, @workspace_id
+, @event_id
+, @event
+, @payload
+, @status
+, @attempts
+, @response_status
+, @last_error
+, @replay_of
+, @next_attempt_at
+, @created_at
+, @delivered_at
+)
+returning id
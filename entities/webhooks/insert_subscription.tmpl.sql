@@ -0,0 +1,15 @@
+INSERT INTO webhook_subscriptions (
+  workspace_id
+, url
+, secret
+, events
+, created_at
+)
+VALUES (
+  @workspace_id
+, @url
+, @secret
+, @events
+, @created_at
+)
+returning id
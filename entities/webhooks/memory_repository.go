@@ -0,0 +1,194 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepository returns an in-memory repository, for tests and ephemeral runs.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{}
+}
+
+type memoryRepository struct {
+	mu                 sync.RWMutex
+	lastSubscriptionID uint64
+	lastDeliveryID     uint64
+	subscriptions      []Subscription
+	deliveries         []Delivery
+}
+
+func (r *memoryRepository) InsertSubscription(ctx context.Context, subscription *Subscription) (*Subscription, error) {
+	if subscription.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+	if subscription.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	cpy := *subscription
+	subscription = &cpy
+	subscription.Events = slices.Clone(subscription.Events)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastSubscriptionID++
+	subscription.ID = r.lastSubscriptionID
+	subscription.CreatedAt = time.Now().UTC()
+	r.subscriptions = append(r.subscriptions, *subscription)
+
+	return subscription, nil
+}
+
+func (r *memoryRepository) FindSubscriptions(ctx context.Context, search SubscriptionSearch) ([]Subscription, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscriptions := []Subscription{}
+	for _, row := range r.subscriptions {
+		if row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if search.ID != 0 && row.ID != search.ID {
+			continue
+		}
+		subscriptions = append(subscriptions, row)
+	}
+	return subscriptions, nil
+}
+
+func (r *memoryRepository) DeleteSubscription(ctx context.Context, workspaceID string, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.subscriptions, func(row Subscription) bool {
+		return row.WorkspaceID == workspaceID && row.ID == id
+	})
+	if i < 0 {
+		return ErrSubscriptionNotFound
+	}
+	r.subscriptions = slices.Delete(r.subscriptions, i, i+1)
+
+	// The deliveries go with their subscription, as in the Postgres repository.
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(row Delivery) bool {
+		return row.SubscriptionID == id
+	})
+	return nil
+}
+
+func (r *memoryRepository) InsertDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	if delivery.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *delivery
+	delivery = &cpy
+	delivery.Payload = slices.Clone(delivery.Payload)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !slices.ContainsFunc(r.subscriptions, func(row Subscription) bool { return row.ID == delivery.SubscriptionID }) {
+		return nil, ErrSubscriptionNotFound
+	}
+	r.lastDeliveryID++
+	delivery.ID = r.lastDeliveryID
+	delivery.CreatedAt = time.Now().UTC()
+	r.deliveries = append(r.deliveries, *delivery)
+
+	return delivery, nil
+}
+
+func (r *memoryRepository) FindDeliveries(ctx context.Context, search DeliverySearch) ([]Delivery, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []Delivery{}
+	for _, row := range r.deliveries {
+		if row.WorkspaceID != search.WorkspaceID {
+			continue
+		}
+		if search.ID != 0 && row.ID != search.ID {
+			continue
+		}
+		if search.SubscriptionID != 0 && row.SubscriptionID != search.SubscriptionID {
+			continue
+		}
+		if search.Status != "" && row.Status != search.Status {
+			continue
+		}
+		deliveries = append(deliveries, row)
+	}
+
+	// Most recent first, as in the Postgres repository.
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if search.Limit > 0 && search.Limit < len(deliveries) {
+		deliveries = deliveries[:search.Limit]
+	}
+	return deliveries, nil
+}
+
+func (r *memoryRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := []Delivery{}
+	for i := range r.deliveries {
+		if limit > 0 && len(deliveries) >= limit {
+			break
+		}
+		if r.deliveries[i].Status != StatusPending || r.deliveries[i].NextAttemptAt.After(now) {
+			continue
+		}
+		r.deliveries[i].NextAttemptAt = now.Add(lease).UTC()
+		deliveries = append(deliveries, r.deliveries[i])
+	}
+	return deliveries, nil
+}
+
+func (r *memoryRepository) UpdateDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].ID != delivery.ID {
+			continue
+		}
+		r.deliveries[i].Status = delivery.Status
+		r.deliveries[i].Attempts = delivery.Attempts
+		r.deliveries[i].ResponseStatus = delivery.ResponseStatus
+		r.deliveries[i].LastError = delivery.LastError
+		r.deliveries[i].NextAttemptAt = delivery.NextAttemptAt
+		r.deliveries[i].DeliveredAt = delivery.DeliveredAt
+		updated := r.deliveries[i]
+		return &updated, nil
+	}
+	return nil, ErrDeliveryNotFound
+}
+
+func (r *memoryRepository) UpdateDeliveryPayload(ctx context.Context, delivery *Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].WorkspaceID != delivery.WorkspaceID || r.deliveries[i].ID != delivery.ID {
+			continue
+		}
+		r.deliveries[i].Payload = slices.Clone(delivery.Payload)
+		return nil
+	}
+	return ErrDeliveryNotFound
+}
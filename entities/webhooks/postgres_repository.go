@@ -0,0 +1,177 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/solher/forklift/files"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/pgutil"
+)
+
+// NewPostgresRepository returns a Postgres backed repository.
+func NewPostgresRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+// foreignKeyViolation is the Postgres error code of a delivery inserted for a missing subscription.
+const foreignKeyViolation = "23503"
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func (r *postgresRepository) InsertSubscription(ctx context.Context, subscription *Subscription) (*Subscription, error) {
+	if subscription.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+	if subscription.URL == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+
+	cpy := *subscription
+	subscription = &cpy
+
+	subscription.CreatedAt = time.Now().UTC()
+	if subscription.Events == nil {
+		subscription.Events = []string{}
+	}
+
+	if err := pgutil.InWorkspace(ctx, r.db, subscription.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert_subscription.tmpl.sql"), pgutil.ToNamedArgs(subscription)).Scan(&subscription.ID)
+	}); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *postgresRepository) FindSubscriptions(ctx context.Context, search SubscriptionSearch) ([]Subscription, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	var subscriptions []Subscription
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find_subscriptions.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		subscriptions, err = pgx.CollectRows(rows, pgx.RowToStructByName[Subscription])
+		return err
+	})
+	return subscriptions, err
+}
+
+func (r *postgresRepository) DeleteSubscription(ctx context.Context, workspaceID string, id uint64) error {
+	return pgutil.InWorkspace(ctx, r.db, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("delete_subscription.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": workspaceID,
+			"id":           id,
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrSubscriptionNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) InsertDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	if delivery.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	cpy := *delivery
+	delivery = &cpy
+
+	delivery.CreatedAt = time.Now().UTC()
+
+	if err := pgutil.InWorkspace(ctx, r.db, delivery.WorkspaceID, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, files.File("insert_delivery.tmpl.sql"), pgutil.ToNamedArgs(delivery)).Scan(&delivery.ID)
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (r *postgresRepository) FindDeliveries(ctx context.Context, search DeliverySearch) ([]Delivery, error) {
+	if search.WorkspaceID == "" {
+		return nil, errors.New("workspace cannot be empty")
+	}
+
+	var deliveries []Delivery
+	err := pgutil.InWorkspace(ctx, r.db, search.WorkspaceID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.Template("find_deliveries.lazy.sql", search), pgutil.ToNamedArgs(search))
+		if err != nil {
+			return err
+		}
+		deliveries, err = pgx.CollectRows(rows, pgx.RowToStructByName[Delivery])
+		return err
+	})
+	return deliveries, err
+}
+
+func (r *postgresRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	// The dispatcher leases the deliveries of every workspace.
+	var deliveries []Delivery
+	err := pgutil.InWorkspace(ctx, r.db, workspaces.AllID, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, files.File("claim_deliveries.tmpl.sql"), pgx.NamedArgs{
+			"now":          now.UTC(),
+			"leased_until": now.Add(lease).UTC(),
+			"limit":        limit,
+		})
+		if err != nil {
+			return err
+		}
+		deliveries, err = pgx.CollectRows(rows, pgx.RowToStructByName[Delivery])
+		return err
+	})
+	return deliveries, err
+}
+
+func (r *postgresRepository) UpdateDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error) {
+	if err := pgutil.InWorkspace(ctx, r.db, delivery.WorkspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("update_delivery.tmpl.sql"), pgutil.ToNamedArgs(delivery))
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrDeliveryNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	cpy := *delivery
+	return &cpy, nil
+}
+
+func (r *postgresRepository) UpdateDeliveryPayload(ctx context.Context, delivery *Delivery) error {
+	return pgutil.InWorkspace(ctx, r.db, delivery.WorkspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, files.File("update_delivery_payload.tmpl.sql"), pgx.NamedArgs{
+			"workspace_id": delivery.WorkspaceID,
+			"id":           delivery.ID,
+			"payload":      delivery.Payload,
+		})
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrDeliveryNotFound
+		}
+		return nil
+	})
+}
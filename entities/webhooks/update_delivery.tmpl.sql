@@ -0,0 +1,9 @@
+UPDATE webhook_deliveries
+SET
+  status = @status
+, attempts = @attempts
+, response_status = @response_status
+, last_error = @last_error
+, next_attempt_at = @next_attempt_at
+, delivered_at = @delivered_at
+WHERE id = @id
@@ -0,0 +1,4 @@
+UPDATE webhook_deliveries
+SET payload = @payload
+WHERE workspace_id = @workspace_id
+ AND id = @id
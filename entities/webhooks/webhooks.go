@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Events notified to the subscriptions.
+const (
+	EventExtractionCompleted = "extraction.completed"
+	EventExtractionFailed    = "extraction.failed"
+	EventWatchChanged        = "watch.changed"
+)
+
+// Events lists every event a subscription can filter on.
+var Events = []string{EventExtractionCompleted, EventExtractionFailed, EventWatchChanged}
+
+// Statuses of a delivery.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Subscription represents an endpoint notified of the events of a workspace.
+type Subscription struct {
+	ID          uint64 `json:"id" xml:"id" db:"id"`
+	WorkspaceID string `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	URL         string `json:"url" xml:"url" db:"url"`
+	// Secret signs the deliveries. It is only returned at creation.
+	Secret string `json:"-" xml:"-" db:"secret"`
+	// Events filters the events sent to the endpoint, every event if empty.
+	Events    []string  `json:"events" xml:"events>event" db:"events"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at" db:"created_at"`
+}
+
+// Matches tells whether the subscription wants an event.
+func (s *Subscription) Matches(event string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, event)
+}
+
+// Delivery represents the sending of an event to a subscription, along with its attempts.
+type Delivery struct {
+	ID             uint64 `json:"id" xml:"id" db:"id"`
+	SubscriptionID uint64 `json:"subscription_id" xml:"subscription_id" db:"subscription_id"`
+	WorkspaceID    string `json:"workspace_id" xml:"workspace_id" db:"workspace_id"`
+	// EventID identifies the event, shared by its deliveries and their replays so that endpoints can deduplicate them.
+	EventID        string          `json:"event_id" xml:"event_id" db:"event_id"`
+	Event          string          `json:"event" xml:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" xml:"payload" db:"payload"`
+	Status         string          `json:"status" xml:"status" db:"status"`
+	Attempts       int             `json:"attempts" xml:"attempts" db:"attempts"`
+	ResponseStatus int             `json:"response_status" xml:"response_status" db:"response_status"`
+	LastError      string          `json:"last_error" xml:"last_error" db:"last_error"`
+	// ReplayOf is the delivery this one replays, if any.
+	ReplayOf      uint64     `json:"replay_of" xml:"replay_of" db:"replay_of"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" xml:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" xml:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at" xml:"delivered_at" db:"delivered_at"`
+}
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// Repository provides access to a Subscription and Delivery store.
+type Repository interface {
+	InsertSubscription(ctx context.Context, subscription *Subscription) (*Subscription, error)
+	FindSubscriptions(ctx context.Context, search SubscriptionSearch) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, workspaceID string, id uint64) error
+	InsertDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error)
+	FindDeliveries(ctx context.Context, search DeliverySearch) ([]Delivery, error)
+	// ClaimDeliveries returns the pending deliveries due at now, and postpones their next attempt by lease
+	// so that concurrent dispatchers do not send them too.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error)
+	// UpdateDeliveryPayload replaces the payload of a delivery, to erase or redact personal data.
+	UpdateDeliveryPayload(ctx context.Context, delivery *Delivery) error
+}
+
+// SubscriptionSearch allows subscription searching. The workspace is required.
+type SubscriptionSearch struct {
+	WorkspaceID string `db:"workspace_id"`
+	ID          uint64 `db:"id"`
+}
+
+// DeliverySearch allows delivery searching. The workspace is required.
+type DeliverySearch struct {
+	WorkspaceID    string `db:"workspace_id"`
+	ID             uint64 `db:"id"`
+	SubscriptionID uint64 `db:"subscription_id"`
+	Status         string `db:"status"`
+	Limit          int    `db:"limit"`
+}
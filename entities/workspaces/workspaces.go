@@ -0,0 +1,39 @@
+package workspaces
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultID is the workspace of the data created before workspaces existed, and of the callers not
+// bound to a workspace.
+const DefaultID = "default"
+
+// AllID stands for every workspace, in the maintenance and global accounting queries. The row level security
+// policies of the Postgres migrations lift the isolation for it, and it is not a valid workspace ID.
+const AllID = "*"
+
+// validID restricts the workspace IDs to slugs.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidID tells whether a workspace ID is well formed.
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+type contextKey string
+
+const workspaceIDContextKey contextKey = "workspaces_workspace_id"
+
+// WithID returns a context scoped to a workspace.
+func WithID(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, workspaceIDContextKey, workspaceID)
+}
+
+// IDFromContext returns the workspace set by WithID, or DefaultID if none.
+func IDFromContext(ctx context.Context) string {
+	if workspaceID, ok := ctx.Value(workspaceIDContextKey).(string); ok && workspaceID != "" {
+		return workspaceID
+	}
+	return DefaultID
+}
@@ -0,0 +1,183 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Mode defines whether a cassette records or replays interactions.
+type Mode string
+
+const (
+	// ModeRecord forwards requests to the underlying transport and saves the interactions.
+	ModeRecord Mode = "record"
+	// ModeReplay serves the saved interactions without any network access.
+	ModeReplay Mode = "replay"
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Transport is an http.RoundTripper recording interactions into a file, or replaying them from it.
+// Replayed interactions are matched on method and URL, in the order they were recorded.
+type Transport struct {
+	path   string
+	mode   Mode
+	next   http.RoundTripper
+	mu     sync.Mutex
+	tape   []Interaction
+	played []bool
+}
+
+// New returns a new cassette transport backed by the file at path.
+// In record mode, requests are forwarded to next, or http.DefaultTransport if nil.
+func New(path string, mode Mode, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{
+		path: path,
+		mode: mode,
+		next: next,
+	}
+
+	switch mode {
+	case ModeRecord:
+	case ModeReplay:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &t.tape); err != nil {
+			return nil, err
+		}
+		t.played = make([]bool, len(t.tape))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == ModeReplay {
+		return t.replay(req)
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := res.Header.Clone()
+	header.Del("Content-Length")
+	if err := t.record(Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Body:   string(reqBody),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     header,
+			Body:       string(resBody),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	return res, nil
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.tape {
+		if t.played[i] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.String() {
+			continue
+		}
+		t.played[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s: no interaction recorded for %s %s", t.path, req.Method, req.URL)
+}
+
+// record appends an interaction to the tape and saves it, so that nothing is lost if the program stops abruptly.
+func (t *Transport) record(interaction Interaction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tape = append(t.tape, interaction)
+	content, err := json.MarshalIndent(t.tape, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, append(content, '\n'), 0o644)
+}
+
+// readBody reads the request body and restores it so that it can still be sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
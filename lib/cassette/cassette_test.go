@@ -0,0 +1,84 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Call", r.URL.Path)
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "echo:"+string(body))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := &http.Client{Transport: recorder}
+	for _, body := range []string{"first", "second"} {
+		res, err := cli.Post(server.URL+"/echo", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(got) != "echo:"+body {
+			t.Fatalf("recorded body = %q, want %q", got, "echo:"+body)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("server calls = %d, want 2", calls)
+	}
+
+	// Replay
+	server.Close()
+	player, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli = &http.Client{Transport: player}
+	for _, body := range []string{"first", "second"} {
+		res, err := cli.Post(server.URL+"/echo", "text/plain", strings.NewReader("ignored"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusTeapot {
+			t.Errorf("replayed status = %d, want %d", res.StatusCode, http.StatusTeapot)
+		}
+		if res.Header.Get("X-Call") != "/echo" {
+			t.Errorf("replayed header = %q, want %q", res.Header.Get("X-Call"), "/echo")
+		}
+		if string(got) != "echo:"+body {
+			t.Errorf("replayed body = %q, want %q", got, "echo:"+body)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("server calls = %d, want 2", calls)
+	}
+
+	// The tape is exhausted.
+	if _, err := cli.Post(server.URL+"/echo", "text/plain", strings.NewReader("third")); err == nil {
+		t.Fatal("expected an error once the tape is exhausted")
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay, nil); err == nil {
+		t.Fatal("expected an error for a missing cassette")
+	}
+}
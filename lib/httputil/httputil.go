@@ -0,0 +1,107 @@
+package httputil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/lib/logutil"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/toolbox"
+	"github.com/solher/toolbox/api"
+)
+
+// RequestIDHeader is the header carrying the request ID, both in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID restricts the request IDs accepted from clients, so that they can be logged safely.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// NewRequestID returns a middleware identifying each request, with the ID given by the client if valid,
+// or a random one otherwise. The ID is returned in a response header.
+func NewRequestID() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			next.ServeHTTP(w, r.WithContext(logutil.WithRequestID(r.Context(), requestID)))
+		})
+	}
+}
+
+// newRequestID returns a random 128 bits identifier.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewAccessLogger returns a middleware logging every request once served.
+func NewAccessLogger(l log.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logutil.LoggerWithRequestID(r.Context(), l).Log(
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+			)
+		})
+	}
+}
+
+// NewRecoverer returns a middleware recovering from panics, logging them with their stack trace
+// and rendering an internal error in the media type the request prefers.
+func NewRecoverer(l log.Logger, renderer render.Renderer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// Aborting a response is not an error, and must abort the connection.
+					panic(rec)
+				}
+				ctx := r.Context()
+				logger := toolbox.LoggerWithRequestContext(ctx, logutil.LoggerWithRequestID(ctx, l))
+				logger.Log("msg", "recovered from panic", "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+				ctx = render.WithPreferredMediaType(ctx, r.Header.Get("Accept"))
+				renderer.RenderError(ctx, w, api.HTTPInternal, fmt.Errorf("panic: %v", rec))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewBodyLimiter returns a middleware making the reads of request bodies larger than maxBytes fail
+// with a *http.MaxBytesError.
+func NewBodyLimiter(maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
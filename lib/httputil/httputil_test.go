@@ -0,0 +1,90 @@
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/lib/logutil"
+	"github.com/solher/toolbox/api"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	handler := NewRequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logutil.RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{header: "", keep: false},
+		{header: "abc-123", keep: true},
+		{header: "abc 123\n", keep: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(RequestIDHeader, tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got == "" {
+			t.Errorf("header %q: no request ID in the context", tt.header)
+		}
+		if rec.Header().Get(RequestIDHeader) != got {
+			t.Errorf("header %q: response ID = %q, want %q", tt.header, rec.Header().Get(RequestIDHeader), got)
+		}
+		if (got == tt.header) != tt.keep {
+			t.Errorf("header %q: request ID = %q", tt.header, got)
+		}
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := log.NewLogfmtLogger(logs)
+	handler := NewRequestID()(NewRecoverer(logger, api.NewJSON(nil, false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(rec.Body.String(), api.HTTPInternal.ErrorCode) {
+		t.Errorf("body = %s, want an internal error", rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "request_id=abc-123") || !strings.Contains(logs.String(), "panic=boom") {
+		t.Errorf("logs = %s, want the panic with its request ID", logs.String())
+	}
+}
+
+func TestBodyLimiter(t *testing.T) {
+	var err error
+	handler := NewBodyLimiter(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err = io.ReadAll(r.Body)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+	if err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		t.Errorf("err = %v, want a *http.MaxBytesError", err)
+	}
+}
@@ -0,0 +1,30 @@
+package logutil
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+)
+
+type contextKey string
+
+const requestIDContextKey contextKey = "logutil_request_id"
+
+// WithRequestID returns a context holding a request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID set by WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// LoggerWithRequestID wraps next and adds the request ID of the context to log entries when available.
+func LoggerWithRequestID(ctx context.Context, next log.Logger) log.Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return log.With(next, "request_id", requestID)
+	}
+	return next
+}
@@ -1,11 +1,23 @@
 package pgutil
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// InWorkspace runs fn in a transaction restricted to a workspace by the row level security policies.
+func InWorkspace(ctx context.Context, db *pgxpool.Pool, workspaceID string, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT set_config('app.workspace_id', $1, true)", workspaceID); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
 func ToNamedArgs(s any) pgx.NamedArgs {
 	// If it's already a pgx.NamedArgs, return it directly
 	if namedArgs, ok := s.(pgx.NamedArgs); ok {
@@ -0,0 +1,109 @@
+package promutil
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewHTTPMiddleware returns a middleware counting the requests of a chi router and measuring their latency,
+// by route pattern, method and status.
+func NewHTTPMiddleware(reg prometheus.Registerer, namespace string) func(next http.Handler) http.Handler {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests.",
+	}, []string{"route", "method", "status"})
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	reg.MustRegister(requests, latency)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			// The route pattern is only known once the request went through the router.
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(status)}
+			requests.With(labels).Inc()
+			latency.With(labels).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// NewPgxPoolCollector returns a collector exposing the statistics of a Postgres connection pool,
+// labeled with the pool name.
+func NewPgxPoolCollector(db *pgxpool.Pool, namespace, pool string) prometheus.Collector {
+	labels := prometheus.Labels{"pool": pool}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, labels)
+	}
+	return &pgxPoolCollector{
+		db:               db,
+		acquiredConns:    desc("acquired_conns", "Number of connections currently acquired."),
+		idleConns:        desc("idle_conns", "Number of idle connections."),
+		totalConns:       desc("total_conns", "Number of connections, acquired, idle or being established."),
+		maxConns:         desc("max_conns", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Number of successful connection acquisitions."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+		emptyAcquires:    desc("empty_acquires_total", "Number of acquisitions that had to wait for a connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Number of acquisitions canceled by their context."),
+	}
+}
+
+type pgxPoolCollector struct {
+	db               *pgxpool.Pool
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquires         *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+}
+
+// Describe implements prometheus.Collector.
+func (c *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquires
+}
+
+// Collect implements prometheus.Collector, reading the pool statistics at scrape time.
+func (c *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.db.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,184 @@
+package render
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/solher/toolbox/api"
+)
+
+// CSV renders the responses in CSV, a row per item of the lists. The objects are flattened into columns
+// named after their dotted JSON fields, like contact.email, the lists of values are joined with semicolons,
+// and the lists of objects are kept as JSON.
+type CSV struct {
+	logger log.Logger
+	debug  bool
+}
+
+// RenderError renders a HTTPError as a single row, and logs it if it's a 500.
+func (c *CSV) RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error) {
+	e = logError(ctx, c.logger, c.debug, httpError, e)
+	c.renderCSV(w, httpError.Status, debugHTTPError(httpError, e, c.debug))
+}
+
+// Render renders an object to CSV.
+func (c *CSV) Render(ctx context.Context, w http.ResponseWriter, status int, object interface{}) {
+	if object == nil {
+		w.WriteHeader(status)
+		return
+	}
+	c.renderCSV(w, status, object)
+}
+
+func (c *CSV) renderCSV(w http.ResponseWriter, status int, object interface{}) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(status)
+
+	rows := []interface{}{object}
+	header := object
+	if isList(object) {
+		list := reflect.ValueOf(object)
+		rows = make([]interface{}, list.Len())
+		for i := range rows {
+			rows[i] = list.Index(i).Interface()
+		}
+		// The header of the empty lists is the one of their zero item.
+		if len(rows) > 0 {
+			header = rows[0]
+		} else if typ := list.Type().Elem(); typ.Kind() == reflect.Pointer {
+			header = reflect.New(typ.Elem()).Interface()
+		} else {
+			header = reflect.Zero(typ).Interface()
+		}
+	}
+	writeCSV(w, header, rows)
+}
+
+// writeCSV writes the rows under the columns of the header object. The fields of a row missing
+// from the header are dropped.
+func writeCSV(w io.Writer, header interface{}, rows []interface{}) error {
+	columns, err := flatten(header)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.name
+	}
+	cw.Write(record)
+
+	for _, row := range rows {
+		cells, err := flatten(row)
+		if err != nil {
+			return err
+		}
+		values := make(map[string]string, len(cells))
+		for _, cell := range cells {
+			values[cell.name] = cell.value
+		}
+		for i, column := range columns {
+			record[i] = EscapeFormula(values[column.name])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// EscapeFormula keeps spreadsheets from evaluating the texts starting like a formula, by prefixing
+// them with a quote. Phone numbers like "+33 1 23 45 67 89" are kept as is, as they hold nothing
+// to evaluate and CRMs would import the quote.
+func EscapeFormula(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if (s[0] == '+' || s[0] == '-') && strings.Trim(s[1:], "0123456789 .-()") == "" {
+		return s
+	}
+	return "'" + s
+}
+
+// cell is a flattened value of an object.
+type cell struct {
+	name  string
+	value string
+}
+
+// flatten returns the cells of an object, in the order of its JSON fields.
+func flatten(object interface{}) ([]cell, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return flattenRaw(raw, "", nil)
+}
+
+// flattenRaw appends the cells of a JSON value.
+func flattenRaw(raw json.RawMessage, name string, cells []cell) ([]cell, error) {
+	switch {
+	case isScalar(raw):
+		return append(cells, cell{name: name, value: scalarString(raw)}), nil
+	case raw[0] == '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(items, func(item json.RawMessage) bool { return !isScalar(item) }) {
+			return append(cells, cell{name: name, value: string(raw)}), nil
+		}
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = scalarString(item)
+		}
+		return append(cells, cell{name: name, value: strings.Join(values, "; ")}), nil
+	}
+
+	// The fields are decoded one by one, to keep their order.
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		field := token.(string)
+		if name != "" {
+			field = name + "." + field
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if cells, err = flattenRaw(value, field, cells); err != nil {
+			return nil, err
+		}
+	}
+	return cells, nil
+}
+
+// isScalar tells whether a JSON value is neither an object nor an array.
+func isScalar(raw json.RawMessage) bool {
+	return len(raw) > 0 && raw[0] != '{' && raw[0] != '['
+}
+
+// scalarString returns the text of a scalar JSON value, empty for null.
+func scalarString(raw json.RawMessage) string {
+	if string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,27 @@
+package render
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/solher/toolbox/api"
+)
+
+// JSON renders the responses in JSON as *api.JSON does, logging the errors with the request ID.
+type JSON struct {
+	json   *api.JSON
+	logger log.Logger
+	debug  bool
+}
+
+// RenderError renders a HTTPError to JSON, and logs it if it's a 500.
+func (j *JSON) RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error) {
+	e = logError(ctx, j.logger, j.debug, httpError, e)
+	j.json.RenderError(ctx, w, httpError, e)
+}
+
+// Render renders an object to JSON.
+func (j *JSON) Render(ctx context.Context, w http.ResponseWriter, status int, object interface{}) {
+	j.json.Render(ctx, w, status, object)
+}
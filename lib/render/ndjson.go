@@ -0,0 +1,48 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/go-kit/log"
+	"github.com/solher/toolbox/api"
+)
+
+// NDJSON renders the responses in newline delimited JSON, a line per item of the lists.
+type NDJSON struct {
+	logger log.Logger
+	debug  bool
+}
+
+// RenderError renders a HTTPError as a single line, and logs it if it's a 500.
+func (n *NDJSON) RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error) {
+	e = logError(ctx, n.logger, n.debug, httpError, e)
+	n.renderNDJSON(w, httpError.Status, debugHTTPError(httpError, e, n.debug))
+}
+
+// Render renders an object to NDJSON.
+func (n *NDJSON) Render(ctx context.Context, w http.ResponseWriter, status int, object interface{}) {
+	if object == nil {
+		w.WriteHeader(status)
+		return
+	}
+	n.renderNDJSON(w, status, object)
+}
+
+func (n *NDJSON) renderNDJSON(w http.ResponseWriter, status int, object interface{}) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(status)
+
+	// The encoder terminates each value with a newline.
+	enc := json.NewEncoder(w)
+	if !isList(object) {
+		enc.Encode(object)
+		return
+	}
+	list := reflect.ValueOf(object)
+	for i := 0; i < list.Len(); i++ {
+		enc.Encode(list.Index(i).Interface())
+	}
+}
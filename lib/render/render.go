@@ -0,0 +1,216 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/lib/logutil"
+	"github.com/solher/toolbox"
+	"github.com/solher/toolbox/api"
+)
+
+// Media types the responses are negotiated between.
+const (
+	MediaJSON   = "application/json"
+	MediaXML    = "application/xml"
+	MediaNDJSON = "application/x-ndjson"
+	MediaCSV    = "text/csv"
+)
+
+var (
+	// ObjectMediaTypes are the media types an object can be rendered as.
+	ObjectMediaTypes = []string{MediaJSON, MediaXML, MediaCSV}
+	// ListMediaTypes are the media types a list can be rendered as.
+	ListMediaTypes = []string{MediaJSON, MediaXML, MediaNDJSON, MediaCSV}
+)
+
+// HTTPNotAcceptable indicates that none of the media types accepted by the client can be rendered.
+var HTTPNotAcceptable = api.HTTPError{
+	Status:      http.StatusNotAcceptable,
+	Description: "None of the accepted media types can be rendered.",
+	ErrorCode:   "NOT_ACCEPTABLE",
+	Params:      make(map[string]interface{}),
+}
+
+var ErrNotAcceptable = errors.New("none of the accepted media types can be rendered")
+
+// Renderer renders the responses and the errors of the HTTP handlers, as *api.JSON does.
+type Renderer interface {
+	Render(ctx context.Context, w http.ResponseWriter, status int, object interface{})
+	RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error)
+}
+
+type contextKey string
+
+const mediaTypeContextKey contextKey = "render_media_type"
+
+// WithMediaType returns a context holding the media type the responses are rendered as.
+func WithMediaType(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, mediaTypeContextKey, mediaType)
+}
+
+// MediaTypeFromContext returns the media type set by WithMediaType, if any.
+func MediaTypeFromContext(ctx context.Context) string {
+	mediaType, _ := ctx.Value(mediaTypeContextKey).(string)
+	return mediaType
+}
+
+// WithPreferredMediaType returns a context holding the media type the Accept header prefers among ListMediaTypes,
+// unless the context holds one already. The middlewares running before the negotiation render their errors
+// in it, JSON being used if none of them is acceptable.
+func WithPreferredMediaType(ctx context.Context, accept string) context.Context {
+	if MediaTypeFromContext(ctx) != "" {
+		return ctx
+	}
+	return WithMediaType(ctx, Negotiate(accept, ListMediaTypes))
+}
+
+// Negotiator renders the responses in the media type negotiated for the request, JSON by default.
+type Negotiator struct {
+	json      *JSON
+	renderers map[string]Renderer
+}
+
+// NewNegotiator returns a negotiator rendering JSON, XML, NDJSON and CSV.
+// If debug is set, the error locations are rendered in the responses.
+func NewNegotiator(logger log.Logger, debug bool) *Negotiator {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	// The errors are logged by the negotiator, with the request ID.
+	json := &JSON{json: api.NewJSON(nil, debug), logger: logger, debug: debug}
+	return &Negotiator{
+		json: json,
+		renderers: map[string]Renderer{
+			MediaJSON:   json,
+			MediaXML:    &XML{logger: logger, debug: debug},
+			MediaNDJSON: &NDJSON{logger: logger, debug: debug},
+			MediaCSV:    &CSV{logger: logger, debug: debug},
+		},
+	}
+}
+
+// Negotiate returns a middleware picking, among the offered media types in order of preference, the one
+// the Accept header of the request prefers. Requests accepting none of them are rejected in JSON.
+// The media types without a renderer are left to the handlers, and their errors rendered in JSON.
+func (n *Negotiator) Negotiate(offers ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			mediaType := Negotiate(r.Header.Get("Accept"), offers)
+			if mediaType == "" {
+				n.json.RenderError(ctx, w, HTTPNotAcceptable, ErrNotAcceptable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithMediaType(ctx, mediaType)))
+		})
+	}
+}
+
+// Render renders an object in the media type of the context.
+func (n *Negotiator) Render(ctx context.Context, w http.ResponseWriter, status int, object interface{}) {
+	n.renderer(ctx).Render(ctx, w, status, object)
+}
+
+// RenderError renders a HTTPError in the media type of the context, and logs it if it's a 500.
+func (n *Negotiator) RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error) {
+	n.renderer(ctx).RenderError(ctx, w, httpError, e)
+}
+
+func (n *Negotiator) renderer(ctx context.Context) Renderer {
+	if renderer, ok := n.renderers[MediaTypeFromContext(ctx)]; ok {
+		return renderer
+	}
+	return n.json
+}
+
+// Negotiate returns the offered media type the Accept header prefers, the first one if it is empty,
+// or an empty string if none is acceptable. The quality of an offer is the one of the most specific
+// media range matching it, and the ties are broken by the order of the offers.
+func Negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	ranges := []mediaRange{}
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(value, ";")
+		mediaRange := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(mediaType)), quality: 1}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				mediaRange.quality = q
+			}
+		}
+		ranges = append(ranges, mediaRange)
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		typ, _, _ := strings.Cut(offer, "/")
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			var s int
+			switch r.mediaType {
+			case offer:
+				s = 2
+			case typ + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+// logError logs the errors as *api.JSON does, along with the request ID.
+func logError(ctx context.Context, logger log.Logger, debug bool, httpError api.HTTPError, e error) error {
+	if e == nil {
+		e = errors.New("null")
+	}
+	if debug || (httpError.Status >= 500 && httpError.Status < 600) {
+		logger := toolbox.LoggerWithRequestContext(ctx, logutil.LoggerWithRequestID(ctx, logger))
+		logger = toolbox.LoggerWithSentry(ctx, logger)
+		logger.Log("status", httpError.Status, "err", e)
+	}
+	return e
+}
+
+// debugHTTPError returns the error rendered in the responses, located if debug is set.
+func debugHTTPError(httpError api.HTTPError, e error, debug bool) *api.DebugHTTPError {
+	debugHTTPError := &api.DebugHTTPError{HTTPError: httpError, Err: e.Error()}
+	if debug {
+		debugHTTPError.Location, _ = toolbox.HasStack(e)
+	}
+	return debugHTTPError
+}
+
+// isList tells whether an object is rendered as a list of items.
+func isList(object interface{}) bool {
+	kind := reflect.ValueOf(object).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
@@ -0,0 +1,230 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/lib/logutil"
+	"github.com/solher/toolbox/api"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{accept: "", offers: ListMediaTypes, want: MediaJSON},
+		{accept: "*/*", offers: ListMediaTypes, want: MediaJSON},
+		{accept: "application/xml", offers: ListMediaTypes, want: MediaXML},
+		{accept: "text/*", offers: ListMediaTypes, want: MediaCSV},
+		{accept: "application/x-ndjson", offers: ObjectMediaTypes, want: ""},
+		{accept: "application/json;q=0.5, application/xml;q=0.8", offers: ListMediaTypes, want: MediaXML},
+		{accept: "Application/XML; charset=utf-8", offers: ListMediaTypes, want: MediaXML},
+		// The most specific range decides, even with a lower quality.
+		{accept: "*/*;q=0.9, application/json;q=0.1", offers: ListMediaTypes, want: MediaXML},
+		{accept: "*/*, application/json;q=0", offers: ListMediaTypes, want: MediaXML},
+		{accept: "image/png", offers: ListMediaTypes, want: ""},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept, tt.offers); got != tt.want {
+			t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiator(t *testing.T) {
+	type contact struct {
+		Email string `json:"email" xml:"email"`
+	}
+	type person struct {
+		FullName string   `json:"full_name" xml:"full_name"`
+		Contact  contact  `json:"contact" xml:"contact"`
+		Tags     []string `json:"tags" xml:"tags>tag"`
+	}
+	people := []person{
+		{FullName: "Jane Doe", Contact: contact{Email: "jane@acme.test"}, Tags: []string{"cto", "founder"}},
+		{FullName: "=1+1", Tags: []string{}},
+	}
+
+	negotiator := NewNegotiator(nil, false)
+	serve := func(accept string, object interface{}, err error) *httptest.ResponseRecorder {
+		handler := negotiator.Negotiate(ListMediaTypes...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err != nil {
+				negotiator.RenderError(r.Context(), w, api.HTTPValidation, err)
+				return
+			}
+			negotiator.Render(r.Context(), w, http.StatusOK, object)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		accept      string
+		object      interface{}
+		err         error
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json",
+			accept:      "application/json",
+			object:      people[:1],
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body:        `[{"full_name":"Jane Doe","contact":{"email":"jane@acme.test"},"tags":["cto","founder"]}]` + "\n",
+		},
+		{
+			name:        "xml list",
+			accept:      "application/xml",
+			object:      people[:1],
+			status:      http.StatusOK,
+			contentType: "application/xml; charset=utf-8",
+			body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<response><item><full_name>Jane Doe</full_name><contact><email>jane@acme.test</email></contact><tags><tag>cto</tag><tag>founder</tag></tags></item></response>`,
+		},
+		{
+			name:        "xml object",
+			accept:      "application/xml",
+			object:      contact{Email: "jane@acme.test"},
+			status:      http.StatusOK,
+			contentType: "application/xml; charset=utf-8",
+			body:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><email>jane@acme.test</email></response>`,
+		},
+		{
+			name:        "xml error",
+			accept:      "application/xml",
+			err:         errors.New("url is required"),
+			status:      http.StatusBadRequest,
+			contentType: "application/xml; charset=utf-8",
+			body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<error><status>400</status><description>The parameters validation failed.</description><errorCode>VALIDATION_ERROR</errorCode><err>url is required</err></error>`,
+		},
+		{
+			name:        "ndjson",
+			accept:      "application/x-ndjson",
+			object:      people,
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body: `{"full_name":"Jane Doe","contact":{"email":"jane@acme.test"},"tags":["cto","founder"]}` + "\n" +
+				`{"full_name":"=1+1","contact":{"email":""},"tags":[]}` + "\n",
+		},
+		{
+			name:        "ndjson error",
+			accept:      "application/x-ndjson",
+			err:         errors.New("url is required"),
+			status:      http.StatusBadRequest,
+			contentType: "application/x-ndjson",
+			body:        `{"status":400,"description":"The parameters validation failed.","errorCode":"VALIDATION_ERROR","err":"url is required"}` + "\n",
+		},
+		{
+			name:        "csv",
+			accept:      "text/csv",
+			object:      people,
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body:        "full_name,contact.email,tags\nJane Doe,jane@acme.test,cto; founder\n'=1+1,,\n",
+		},
+		{
+			name:        "csv empty list",
+			accept:      "text/csv",
+			object:      []*person{},
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body:        "full_name,contact.email,tags\n",
+		},
+		{
+			name:        "csv error",
+			accept:      "text/csv",
+			err:         errors.New("url is required"),
+			status:      http.StatusBadRequest,
+			contentType: "text/csv; charset=utf-8",
+			body:        "status,description,errorCode,err\n400,The parameters validation failed.,VALIDATION_ERROR,url is required\n",
+		},
+		{
+			name:        "not acceptable",
+			accept:      "image/png",
+			object:      people,
+			status:      http.StatusNotAcceptable,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"status":406,"description":"None of the accepted media types can be rendered.","errorCode":"NOT_ACCEPTABLE","err":"none of the accepted media types can be rendered"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.accept, tt.object, tt.err)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("content type = %q, want %q", got, tt.contentType)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+
+	// Outside of a negotiated route, the responses are rendered in JSON.
+	w := httptest.NewRecorder()
+	negotiator.Render(context.Background(), w, http.StatusOK, people[:1])
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), MediaJSON) {
+		t.Errorf("content type = %q, want JSON", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRenderErrorLogsRequestID(t *testing.T) {
+	var logged []interface{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = keyvals
+		return nil
+	})
+	negotiator := NewNegotiator(logger, false)
+
+	for _, mediaType := range ListMediaTypes {
+		logged = nil
+		ctx := WithMediaType(logutil.WithRequestID(context.Background(), "abc-123"), mediaType)
+		negotiator.RenderError(ctx, httptest.NewRecorder(), api.HTTPInternal, errors.New("boom"))
+
+		if !strings.Contains(fmt.Sprint(logged), "request_id abc-123") {
+			t.Errorf("%s: logged %v, want the request ID", mediaType, logged)
+		}
+	}
+
+	// The client errors are not logged.
+	logged = nil
+	negotiator.RenderError(logutil.WithRequestID(context.Background(), "abc-123"), httptest.NewRecorder(), api.HTTPValidation, errors.New("invalid"))
+	if logged != nil {
+		t.Errorf("logged %v, want nothing", logged)
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"Jane Doe":           "Jane Doe",
+		"=HYPERLINK(\"x\")":  "'=HYPERLINK(\"x\")",
+		"@SUM(A1)":           "'@SUM(A1)",
+		"+33 1 23 45 67 89":  "+33 1 23 45 67 89",
+		"-42.5":              "-42.5",
+		"+1 (555) 010-0000":  "+1 (555) 010-0000",
+		"+cmd|' /C calc'!A0": "'+cmd|' /C calc'!A0",
+	}
+	for s, want := range tests {
+		if got := EscapeFormula(s); got != want {
+			t.Errorf("EscapeFormula(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
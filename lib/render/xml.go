@@ -0,0 +1,95 @@
+package render
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/solher/toolbox/api"
+)
+
+// XML renders the responses in XML, under a response element whose lists have an item element per item.
+// Unlike *api.XML, it renders the params of the errors, which encoding/xml cannot encode as a map.
+type XML struct {
+	logger log.Logger
+	debug  bool
+}
+
+// xmlError is the XML form of an api.DebugHTTPError.
+type xmlError struct {
+	XMLName     xml.Name   `xml:"error"`
+	Status      int        `xml:"status"`
+	Description string     `xml:"description"`
+	ErrorCode   string     `xml:"errorCode"`
+	Params      *xmlParams `xml:"params,omitempty"`
+	Err         string     `xml:"err"`
+	Location    string     `xml:"location,omitempty"`
+}
+
+type xmlParams struct {
+	Params []xmlParam `xml:"param"`
+}
+
+type xmlParam struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// RenderError renders a HTTPError and logs it if it's a 500.
+func (x *XML) RenderError(ctx context.Context, w http.ResponseWriter, httpError api.HTTPError, e error) {
+	e = logError(ctx, x.logger, x.debug, httpError, e)
+	debugHTTPError := debugHTTPError(httpError, e, x.debug)
+
+	xmlError := &xmlError{
+		Status:      debugHTTPError.Status,
+		Description: debugHTTPError.Description,
+		ErrorCode:   debugHTTPError.ErrorCode,
+		Err:         debugHTTPError.Err,
+		Location:    debugHTTPError.Location,
+	}
+	if len(debugHTTPError.Params) > 0 {
+		params := []xmlParam{}
+		for name, value := range debugHTTPError.Params {
+			params = append(params, xmlParam{Name: name, Value: fmt.Sprint(value)})
+		}
+		sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+		xmlError.Params = &xmlParams{Params: params}
+	}
+
+	enc := x.start(w, httpError.Status)
+	enc.Encode(xmlError)
+}
+
+// Render renders an object to XML.
+func (x *XML) Render(ctx context.Context, w http.ResponseWriter, status int, object interface{}) {
+	if object == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	enc := x.start(w, status)
+	response := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if !isList(object) {
+		enc.EncodeElement(object, response)
+		return
+	}
+	enc.EncodeToken(response)
+	list := reflect.ValueOf(object)
+	for i := 0; i < list.Len(); i++ {
+		enc.EncodeElement(list.Index(i).Interface(), xml.StartElement{Name: xml.Name{Local: "item"}})
+	}
+	enc.EncodeToken(response.End())
+	enc.Flush()
+}
+
+// start writes the headers and the XML declaration.
+func (x *XML) start(w http.ResponseWriter, status int) *xml.Encoder {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	return xml.NewEncoder(w)
+}
@@ -0,0 +1,21 @@
+package sqliteutil
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/solher/hunterio-test/lib/pgutil"
+)
+
+// ToNamedArgs converts a struct or a map to the database/sql named arguments used by a query,
+// using the same rules as pgutil.ToNamedArgs.
+// Unused arguments are dropped, as the driver rejects arguments given to a query without parameters.
+func ToNamedArgs(query string, s any) []any {
+	args := []any{}
+	for name, value := range pgutil.ToNamedArgs(s) {
+		if strings.Contains(query, "@"+name) {
+			args = append(args, sql.Named(name, value))
+		}
+	}
+	return args
+}
@@ -0,0 +1,136 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// staticParts are the parts of a single sheet workbook that do not depend on its content.
+var staticParts = []struct {
+	name, content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// Writer streams rows into a single sheet workbook. Strings are written inline, so that nothing
+// but the current row is held in memory, and numbers are written as numeric cells.
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	err   error
+}
+
+// NewWriter starts a workbook whose only sheet is named sheetName.
+// Close must be called to complete it.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	for _, part := range staticParts {
+		if err := writePart(zw, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writePart(zw, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Integers and floats become numbers, and any other value its string form.
+func (w *Writer) WriteRow(values []any) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			w.writeNumber(strconv.Itoa(v))
+		case int64:
+			w.writeNumber(strconv.FormatInt(v, 10))
+		case uint64:
+			w.writeNumber(strconv.FormatUint(v, 10))
+		case float64:
+			w.writeNumber(strconv.FormatFloat(v, 'f', -1, 64))
+		case string:
+			w.writeString(v)
+		default:
+			w.writeString(fmt.Sprint(v))
+		}
+	}
+	_, w.err = w.sheet.WriteString("</row>")
+	return w.err
+}
+
+// Close completes the sheet and the workbook. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.sheet.WriteString("</sheetData></worksheet>")
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+func writePart(zw *zip.Writer, name string, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func (w *Writer) writeNumber(v string) {
+	w.sheet.WriteString("<c><v>" + v + "</v></c>")
+}
+
+func (w *Writer) writeString(v string) {
+	if v == "" {
+		w.sheet.WriteString("<c/>")
+		return
+	}
+	w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">` + escape(v) + "</t></is></c>")
+}
+
+// escape escapes a string for XML, dropping the characters XML cannot hold.
+func escape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != 0xFFFE && r != 0xFFFF) {
+			return r
+		}
+		return -1
+	}, s)
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
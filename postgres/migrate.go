@@ -0,0 +1,285 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Schema is the Postgres schema holding every object of the project.
+const Schema = "hunterio"
+
+// migrationsLockID is the advisory lock key guarding migrations against concurrent startups ("hunter" in ASCII).
+const migrationsLockID = 0x68756e746572
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	up      string
+	down    string
+}
+
+// MigrationStatus tells whether a migration is applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Migrator applies the embedded migrations to a database.
+type Migrator interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Status(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// NewMigrator returns a migrator of the embedded migrations.
+func NewMigrator(db *pgxpool.Pool) (Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+type migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// Up applies every pending migration, in order.
+func (m *migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.withLock(ctx, func(conn *pgxpool.Conn, versions map[int]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.up, "INSERT INTO schema_versions (version, name) VALUES ($1, $2)"); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of applied migrations, most recent first.
+func (m *migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	err = m.withLock(ctx, func(conn *pgxpool.Conn, versions map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.down == "" {
+				return fmt.Errorf("migration %03d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+			if err := m.apply(ctx, conn, migration, migration.down, "DELETE FROM schema_versions WHERE version = $1 AND name = $2"); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every migration along with its application date.
+func (m *migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	err = m.withLock(ctx, func(conn *pgxpool.Conn, versions map[int]time.Time) error {
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// apply runs a migration script and records it in a single transaction.
+func (m *migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration, script string, record string) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return fmt.Errorf("migration %03d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec(ctx, record, migration.Version, migration.Name)
+		return err
+	})
+}
+
+// withLock runs fn on a dedicated connection holding the migrations advisory lock,
+// bootstrapping the schema and the versions table if needed.
+func (m *migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, versions map[int]time.Time) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID)
+
+	var schemaExists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", Schema).Scan(&schemaExists); err != nil {
+		return err
+	}
+	if !schemaExists {
+		bootstrap, err := migrations.ReadFile("migrations/000_init.sql")
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Exec(ctx, string(bootstrap)); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.Exec(ctx, "SET search_path = "+Schema); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS schema_versions (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return err
+	}
+
+	if err := m.adoptLegacyVersion(ctx, conn); err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_versions")
+	if err != nil {
+		return err
+	}
+	versions := map[int]time.Time{}
+	var (
+		version   int
+		appliedAt time.Time
+	)
+	if _, err := pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		versions[version] = appliedAt
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return fn(conn, versions)
+}
+
+// adoptLegacyVersion records the migrations applied by the golang-migrate tool, which was used before
+// migrations were embedded, and only tracks the last applied version in schema_migrations.
+func (m *migrator) adoptLegacyVersion(ctx context.Context, conn *pgxpool.Conn) error {
+	var hasVersions, hasLegacy bool
+	if err := conn.QueryRow(ctx, `
+SELECT
+  EXISTS (SELECT 1 FROM schema_versions)
+, to_regclass('schema_migrations') IS NOT NULL`,
+	).Scan(&hasVersions, &hasLegacy); err != nil {
+		return err
+	}
+	if hasVersions || !hasLegacy {
+		return nil
+	}
+
+	var (
+		version int
+		dirty   bool
+	)
+	if err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("legacy migration %d is dirty, fix it manually before migrating", version)
+	}
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, err := conn.Exec(ctx, "INSERT INTO schema_versions (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMigrations parses the embedded NNN_name.up.sql and NNN_name.down.sql files.
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			// Not a versioned migration, like the 000_init.sql bootstrap script.
+			continue
+		}
+
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", base)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s: %w", base, err)
+		}
+		content, err := migrations.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	res := []Migration{}
+	for _, migration := range byVersion {
+		if migration.up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up script", migration.Version, migration.Name)
+		}
+		res = append(res, *migration)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	return res, nil
+}
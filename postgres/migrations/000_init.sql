@@ -1,4 +1,4 @@
 CREATE SCHEMA IF NOT EXISTS hunterio;
-GRANT USAGE ON SCHEMA hunterio TO "hunterio";
-ALTER SCHEMA hunterio OWNER TO "hunterio";
-ALTER USER "hunterio" SET search_path = hunterio;
+GRANT USAGE ON SCHEMA hunterio TO CURRENT_USER;
+ALTER SCHEMA hunterio OWNER TO CURRENT_USER;
+ALTER USER CURRENT_USER SET search_path = hunterio;
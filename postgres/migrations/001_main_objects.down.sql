@@ -0,0 +1 @@
+DROP TABLE extracted_data;
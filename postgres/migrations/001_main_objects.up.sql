@@ -1,7 +1,3 @@
-SET SCHEMA 'hunterio';
-BEGIN;
-----
-
 CREATE TABLE extracted_data (
   id SERIAL PRIMARY KEY,
   url TEXT NOT NULL,
@@ -11,6 +7,3 @@ CREATE TABLE extracted_data (
 );
 
 CREATE INDEX extracted_data_by_url ON extracted_data (url, created_at);
-
-----
-COMMIT;
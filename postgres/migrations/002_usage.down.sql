@@ -0,0 +1,8 @@
+DROP INDEX extracted_data_by_created_at;
+
+ALTER TABLE extracted_data
+  DROP COLUMN model,
+  DROP COLUMN prompt_tokens,
+  DROP COLUMN completion_tokens,
+  DROP COLUMN latency_ms,
+  DROP COLUMN cost_usd;
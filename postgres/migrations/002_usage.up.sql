@@ -0,0 +1,8 @@
+ALTER TABLE extracted_data
+  ADD COLUMN model TEXT NOT NULL DEFAULT '',
+  ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0;
+
+CREATE INDEX extracted_data_by_created_at ON extracted_data (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX extracted_data_by_client_id;
+
+ALTER TABLE extracted_data DROP COLUMN client_id;
@@ -0,0 +1,5 @@
+DROP INDEX extracted_data_by_api_key_id;
+
+ALTER TABLE extracted_data DROP COLUMN api_key_id;
+
+DROP TABLE api_keys;
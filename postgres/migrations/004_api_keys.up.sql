@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  rate_limit_per_minute INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revoked_at TIMESTAMP
+);
+
+ALTER TABLE extracted_data ADD COLUMN api_key_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX extracted_data_by_api_key_id ON extracted_data (api_key_id, created_at);
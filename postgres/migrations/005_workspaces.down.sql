@@ -0,0 +1,11 @@
+DROP POLICY extracted_data_workspace_isolation ON extracted_data;
+
+ALTER TABLE extracted_data NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE extracted_data DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX extracted_data_by_url;
+CREATE INDEX extracted_data_by_url ON extracted_data (url, created_at);
+
+ALTER TABLE extracted_data DROP COLUMN workspace_id;
+
+ALTER TABLE api_keys DROP COLUMN workspace_id;
@@ -0,0 +1,16 @@
+ALTER TABLE api_keys ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE extracted_data ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default';
+
+DROP INDEX extracted_data_by_url;
+CREATE INDEX extracted_data_by_url ON extracted_data (workspace_id, url, created_at);
+
+-- Defense in depth: the repository sets app.workspace_id in each transaction, and the rows of other
+-- workspaces are invisible even if a query forgets to filter them. '*' is reserved to global queries.
+-- The policies are forced, as the API connects as the table owner. Superusers still bypass them.
+ALTER TABLE extracted_data ENABLE ROW LEVEL SECURITY;
+ALTER TABLE extracted_data FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY extracted_data_workspace_isolation ON extracted_data
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
@@ -0,0 +1,3 @@
+DROP TABLE watch_changes;
+
+DROP TABLE watches;
@@ -0,0 +1,28 @@
+CREATE TABLE watches (
+  id SERIAL PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
+  url TEXT NOT NULL,
+  schedule TEXT NOT NULL,
+  target TEXT NOT NULL,
+  last_run_id INTEGER NOT NULL DEFAULT 0,
+  last_run_at TIMESTAMP,
+  last_error TEXT NOT NULL DEFAULT '',
+  next_run_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX watches_by_workspace_id ON watches (workspace_id);
+CREATE INDEX watches_by_next_run_at ON watches (next_run_at);
+
+CREATE TABLE watch_changes (
+  id SERIAL PRIMARY KEY,
+  watch_id INTEGER NOT NULL REFERENCES watches (id) ON DELETE CASCADE,
+  workspace_id TEXT NOT NULL,
+  url TEXT NOT NULL,
+  from_id INTEGER NOT NULL,
+  to_id INTEGER NOT NULL,
+  diff JSONB NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX watch_changes_by_workspace_id ON watch_changes (workspace_id, watch_id, id);
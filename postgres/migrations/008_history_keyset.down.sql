@@ -0,0 +1 @@
+DROP INDEX extracted_data_by_workspace_id;
@@ -0,0 +1 @@
+ALTER TABLE extracted_data DROP COLUMN deleted_at;
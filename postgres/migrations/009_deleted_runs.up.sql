@@ -0,0 +1,2 @@
+-- The deleted runs lose their people and companies, but are kept for the usage accounting.
+ALTER TABLE extracted_data ADD COLUMN deleted_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP TABLE privacy_audit_log;
+
+DROP TABLE privacy_suppressions;
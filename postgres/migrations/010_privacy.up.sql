@@ -0,0 +1,23 @@
+-- The subjects are only kept hashed, so that honoring an erasure request does not keep their identifiers.
+CREATE TABLE privacy_suppressions (
+  id SERIAL PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (workspace_id, hash)
+);
+
+CREATE TABLE privacy_audit_log (
+  id SERIAL PRIMARY KEY,
+  workspace_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  subject_hashes TEXT[] NOT NULL DEFAULT '{}',
+  api_key_id INTEGER NOT NULL DEFAULT 0,
+  runs INTEGER NOT NULL DEFAULT 0,
+  watch_changes INTEGER NOT NULL DEFAULT 0,
+  webhook_deliveries INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX privacy_audit_log_by_workspace_id ON privacy_audit_log (workspace_id, id);
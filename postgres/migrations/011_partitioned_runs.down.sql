@@ -0,0 +1,49 @@
+DROP FUNCTION extracted_data_create_partitions;
+
+ALTER TABLE extracted_data RENAME TO extracted_data_partitioned;
+ALTER INDEX extracted_data_pkey RENAME TO extracted_data_partitioned_pkey;
+ALTER SEQUENCE extracted_data_id_seq OWNED BY NONE;
+
+CREATE TABLE extracted_data (
+  id INTEGER PRIMARY KEY DEFAULT nextval('extracted_data_id_seq'),
+  url TEXT NOT NULL,
+  people JSONB NOT NULL DEFAULT '{}',
+  companies JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  model TEXT NOT NULL DEFAULT '',
+  prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0,
+  latency_ms INTEGER NOT NULL DEFAULT 0,
+  cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+  client_id TEXT NOT NULL DEFAULT '',
+  api_key_id INTEGER NOT NULL DEFAULT 0,
+  workspace_id TEXT NOT NULL DEFAULT 'default',
+  deleted_at TIMESTAMP
+);
+
+SELECT set_config('app.workspace_id', '*', true);
+
+INSERT INTO extracted_data (
+  id, url, people, companies, created_at, model, prompt_tokens, completion_tokens, latency_ms, cost_usd,
+  client_id, api_key_id, workspace_id, deleted_at
+)
+SELECT
+  id, url, people, companies, created_at, model, prompt_tokens, completion_tokens, latency_ms, cost_usd,
+  client_id, api_key_id, workspace_id, deleted_at
+FROM extracted_data_partitioned;
+
+DROP TABLE extracted_data_partitioned;
+ALTER SEQUENCE extracted_data_id_seq OWNED BY extracted_data.id;
+
+CREATE INDEX extracted_data_by_url ON extracted_data (workspace_id, url, created_at);
+CREATE INDEX extracted_data_by_created_at ON extracted_data (created_at);
+CREATE INDEX extracted_data_by_client_id ON extracted_data (client_id, created_at);
+CREATE INDEX extracted_data_by_api_key_id ON extracted_data (api_key_id, created_at);
+CREATE INDEX extracted_data_by_workspace_id ON extracted_data (workspace_id, created_at, id);
+
+ALTER TABLE extracted_data ENABLE ROW LEVEL SECURITY;
+ALTER TABLE extracted_data FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY extracted_data_workspace_isolation ON extracted_data
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
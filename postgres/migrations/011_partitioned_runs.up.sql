@@ -0,0 +1,88 @@
+-- The runs are partitioned by month of creation: the queries on a period only scan its months, and the expired
+-- months are detached at once instead of being purged row by row. The primary key has to hold the partition key,
+-- the IDs staying unique as they come from the same sequence.
+ALTER TABLE extracted_data RENAME TO extracted_data_unpartitioned;
+ALTER INDEX extracted_data_pkey RENAME TO extracted_data_unpartitioned_pkey;
+ALTER SEQUENCE extracted_data_id_seq OWNED BY NONE;
+
+CREATE TABLE extracted_data (
+  id INTEGER NOT NULL DEFAULT nextval('extracted_data_id_seq'),
+  url TEXT NOT NULL,
+  people JSONB NOT NULL DEFAULT '{}',
+  companies JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  model TEXT NOT NULL DEFAULT '',
+  prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0,
+  latency_ms INTEGER NOT NULL DEFAULT 0,
+  cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+  client_id TEXT NOT NULL DEFAULT '',
+  api_key_id INTEGER NOT NULL DEFAULT 0,
+  workspace_id TEXT NOT NULL DEFAULT 'default',
+  deleted_at TIMESTAMP,
+  PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Creates the missing monthly partitions, named extracted_data_YYYY_MM, from the month of from_date to months_ahead
+-- months after the current one, and returns how many were created. The API calls it regularly, so that the runs
+-- always have a partition to go to. The dates are UTC, as the runs are stored.
+CREATE FUNCTION extracted_data_create_partitions(from_date TIMESTAMP, months_ahead INTEGER) RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+  partition_month TIMESTAMP := date_trunc('month', from_date);
+  last_month TIMESTAMP := date_trunc('month', now() AT TIME ZONE 'UTC') + make_interval(months => months_ahead);
+  partition_name TEXT;
+  created INTEGER := 0;
+BEGIN
+  -- Serializes the instances creating the same partitions.
+  PERFORM pg_advisory_xact_lock(hashtext('extracted_data_create_partitions'));
+
+  WHILE partition_month <= last_month LOOP
+    partition_name := 'extracted_data_' || to_char(partition_month, 'YYYY_MM');
+    IF to_regclass(quote_ident(partition_name)) IS NULL THEN
+      EXECUTE format(
+        'CREATE TABLE %I PARTITION OF extracted_data FOR VALUES FROM (%L) TO (%L)',
+        partition_name, partition_month, partition_month + INTERVAL '1 month'
+      );
+      created := created + 1;
+    END IF;
+    partition_month := partition_month + INTERVAL '1 month';
+  END LOOP;
+  RETURN created;
+END
+$$;
+
+-- The rows of every workspace are copied, the row level security policies being lifted as for the global queries.
+SELECT set_config('app.workspace_id', '*', true);
+
+SELECT extracted_data_create_partitions(
+  coalesce((SELECT min(created_at) FROM extracted_data_unpartitioned), now() AT TIME ZONE 'UTC'),
+  3
+);
+
+INSERT INTO extracted_data (
+  id, url, people, companies, created_at, model, prompt_tokens, completion_tokens, latency_ms, cost_usd,
+  client_id, api_key_id, workspace_id, deleted_at
+)
+SELECT
+  id, url, people, companies, created_at, model, prompt_tokens, completion_tokens, latency_ms, cost_usd,
+  client_id, api_key_id, workspace_id, deleted_at
+FROM extracted_data_unpartitioned;
+
+DROP TABLE extracted_data_unpartitioned;
+ALTER SEQUENCE extracted_data_id_seq OWNED BY extracted_data.id;
+
+-- The index by URL holds the ID, so that the last run of a URL is read from the most recent partition holding it,
+-- walking the index backwards.
+CREATE INDEX extracted_data_by_url ON extracted_data (workspace_id, url, created_at, id);
+CREATE INDEX extracted_data_by_created_at ON extracted_data (created_at);
+CREATE INDEX extracted_data_by_client_id ON extracted_data (client_id, created_at);
+CREATE INDEX extracted_data_by_api_key_id ON extracted_data (api_key_id, created_at);
+CREATE INDEX extracted_data_by_workspace_id ON extracted_data (workspace_id, created_at, id);
+
+ALTER TABLE extracted_data ENABLE ROW LEVEL SECURITY;
+ALTER TABLE extracted_data FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY extracted_data_workspace_isolation ON extracted_data
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
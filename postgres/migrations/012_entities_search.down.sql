@@ -0,0 +1,3 @@
+DROP INDEX extracted_data_by_companies;
+
+DROP INDEX extracted_data_by_people;
@@ -0,0 +1,4 @@
+-- The runs are searched by the people and companies they hold, with JSONB path queries matching exact values,
+-- which the jsonb_path_ops indexes serve.
+CREATE INDEX extracted_data_by_people ON extracted_data USING GIN (people jsonb_path_ops);
+CREATE INDEX extracted_data_by_companies ON extracted_data USING GIN (companies jsonb_path_ops);
@@ -0,0 +1,9 @@
+DROP INDEX extracted_data_by_search_names;
+
+DROP INDEX extracted_data_by_search_vector;
+
+ALTER TABLE extracted_data
+  DROP COLUMN search_names
+, DROP COLUMN search_vector;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,24 @@
+-- The people and companies are searched by full text on their names, job titles and industries, and by trigram
+-- similarity on their names to find the misspelled ones. The columns are generated, so that they are maintained
+-- on insert and whenever the entities are updated.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE extracted_data
+  ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(jsonb_to_tsvector(
+      'simple',
+      jsonb_path_query_array(people, '$[*].full_name') || jsonb_path_query_array(companies, '$[*].name'),
+      '["string"]'
+    ), 'A')
+    || setweight(jsonb_to_tsvector(
+      'english',
+      jsonb_path_query_array(people, '$[*].job_title') || jsonb_path_query_array(companies, '$[*].industry'),
+      '["string"]'
+    ), 'B')
+  ) STORED
+, ADD COLUMN search_names TEXT GENERATED ALWAYS AS (
+    (jsonb_path_query_array(people, '$[*].full_name') || jsonb_path_query_array(companies, '$[*].name'))::text
+  ) STORED;
+
+CREATE INDEX extracted_data_by_search_vector ON extracted_data USING GIN (search_vector);
+CREATE INDEX extracted_data_by_search_names ON extracted_data USING GIN (search_names gin_trgm_ops);
@@ -0,0 +1,6 @@
+DROP INDEX extracted_data_by_lower_companies;
+
+DROP INDEX extracted_data_by_lower_people;
+
+CREATE INDEX extracted_data_by_people ON extracted_data USING GIN (people jsonb_path_ops);
+CREATE INDEX extracted_data_by_companies ON extracted_data USING GIN (companies jsonb_path_ops);
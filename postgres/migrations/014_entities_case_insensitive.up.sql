@@ -0,0 +1,8 @@
+-- The runs are searched by the job titles, company names, industries and technologies of their people and
+-- companies regardless of the case. The JSONB path queries match the lowercased entities, which the
+-- jsonb_path_ops indexes on the same expressions serve.
+DROP INDEX extracted_data_by_people;
+DROP INDEX extracted_data_by_companies;
+
+CREATE INDEX extracted_data_by_lower_people ON extracted_data USING GIN ((lower(people::text)::jsonb) jsonb_path_ops);
+CREATE INDEX extracted_data_by_lower_companies ON extracted_data USING GIN ((lower(companies::text)::jsonb) jsonb_path_ops);
@@ -0,0 +1,11 @@
+DROP POLICY webhook_deliveries_claim ON webhook_deliveries;
+
+DROP POLICY webhook_deliveries_workspace_isolation ON webhook_deliveries;
+
+ALTER TABLE webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY webhook_subscriptions_workspace_isolation ON webhook_subscriptions;
+
+ALTER TABLE webhook_subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_subscriptions DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,20 @@
+-- The webhooks are isolated by workspace as the runs, the repository setting app.workspace_id in each transaction.
+ALTER TABLE webhook_subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_subscriptions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY webhook_subscriptions_workspace_isolation ON webhook_subscriptions
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
+
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY webhook_deliveries_workspace_isolation ON webhook_deliveries
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
+
+-- The dispatcher leases the due deliveries of every workspace at once.
+CREATE POLICY webhook_deliveries_claim ON webhook_deliveries
+  FOR UPDATE
+  USING (current_setting('app.workspace_id', true) = '*')
+  WITH CHECK (current_setting('app.workspace_id', true) = '*');
@@ -0,0 +1,11 @@
+DROP POLICY watch_changes_workspace_isolation ON watch_changes;
+
+ALTER TABLE watch_changes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE watch_changes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY watches_claim ON watches;
+
+DROP POLICY watches_workspace_isolation ON watches;
+
+ALTER TABLE watches NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE watches DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,20 @@
+-- The watches are isolated by workspace as the runs, the repository setting app.workspace_id in each transaction.
+ALTER TABLE watches ENABLE ROW LEVEL SECURITY;
+ALTER TABLE watches FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY watches_workspace_isolation ON watches
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
+
+-- The scheduler leases the due watches of every workspace at once.
+CREATE POLICY watches_claim ON watches
+  FOR UPDATE
+  USING (current_setting('app.workspace_id', true) = '*')
+  WITH CHECK (current_setting('app.workspace_id', true) = '*');
+
+ALTER TABLE watch_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE watch_changes FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY watch_changes_workspace_isolation ON watch_changes
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
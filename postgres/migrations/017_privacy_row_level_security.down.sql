@@ -0,0 +1,9 @@
+DROP POLICY privacy_audit_log_workspace_isolation ON privacy_audit_log;
+
+ALTER TABLE privacy_audit_log NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE privacy_audit_log DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY privacy_suppressions_workspace_isolation ON privacy_suppressions;
+
+ALTER TABLE privacy_suppressions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE privacy_suppressions DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,15 @@
+-- The suppressions and the audit log are isolated by workspace as the runs, the repository setting
+-- app.workspace_id in each transaction.
+ALTER TABLE privacy_suppressions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE privacy_suppressions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY privacy_suppressions_workspace_isolation ON privacy_suppressions
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
+
+ALTER TABLE privacy_audit_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE privacy_audit_log FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY privacy_audit_log_workspace_isolation ON privacy_audit_log
+  USING (current_setting('app.workspace_id', true) IN (workspace_id, '*'))
+  WITH CHECK (current_setting('app.workspace_id', true) = workspace_id);
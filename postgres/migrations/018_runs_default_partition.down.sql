@@ -0,0 +1,38 @@
+-- The runs of the default partition are moved to monthly partitions first, so that none is lost.
+SELECT extracted_data_create_partitions(
+  min(created_at),
+  greatest(0, (
+    extract(YEAR FROM age(date_trunc('month', max(created_at)), date_trunc('month', now() AT TIME ZONE 'UTC'))) * 12
+    + extract(MONTH FROM age(date_trunc('month', max(created_at)), date_trunc('month', now() AT TIME ZONE 'UTC')))
+  )::INTEGER)
+)
+FROM extracted_data_default
+HAVING count(*) > 0;
+
+DROP TABLE extracted_data_default;
+
+CREATE OR REPLACE FUNCTION extracted_data_create_partitions(from_date TIMESTAMP, months_ahead INTEGER) RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+  partition_month TIMESTAMP := date_trunc('month', from_date);
+  last_month TIMESTAMP := date_trunc('month', now() AT TIME ZONE 'UTC') + make_interval(months => months_ahead);
+  partition_name TEXT;
+  created INTEGER := 0;
+BEGIN
+  -- Serializes the instances creating the same partitions.
+  PERFORM pg_advisory_xact_lock(hashtext('extracted_data_create_partitions'));
+
+  WHILE partition_month <= last_month LOOP
+    partition_name := 'extracted_data_' || to_char(partition_month, 'YYYY_MM');
+    IF to_regclass(quote_ident(partition_name)) IS NULL THEN
+      EXECUTE format(
+        'CREATE TABLE %I PARTITION OF extracted_data FOR VALUES FROM (%L) TO (%L)',
+        partition_name, partition_month, partition_month + INTERVAL '1 month'
+      );
+      created := created + 1;
+    END IF;
+    partition_month := partition_month + INTERVAL '1 month';
+  END LOOP;
+  RETURN created;
+END
+$$;
@@ -0,0 +1,50 @@
+-- The runs dated past the created partitions go to the default partition, rather than failing to be inserted
+-- while no instance creates the partitions (the API down, or only the CLI running). Creating a partition then
+-- moves the runs of its month out of the default partition, which stays empty otherwise.
+CREATE TABLE extracted_data_default PARTITION OF extracted_data DEFAULT;
+
+CREATE OR REPLACE FUNCTION extracted_data_create_partitions(from_date TIMESTAMP, months_ahead INTEGER) RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+  partition_month TIMESTAMP := date_trunc('month', from_date);
+  last_month TIMESTAMP := date_trunc('month', now() AT TIME ZONE 'UTC') + make_interval(months => months_ahead);
+  partition_name TEXT;
+  created INTEGER := 0;
+BEGIN
+  -- Serializes the instances creating the same partitions.
+  PERFORM pg_advisory_xact_lock(hashtext('extracted_data_create_partitions'));
+
+  WHILE partition_month <= last_month LOOP
+    partition_name := 'extracted_data_' || to_char(partition_month, 'YYYY_MM');
+    IF to_regclass(quote_ident(partition_name)) IS NULL THEN
+      IF EXISTS (
+        SELECT 1
+        FROM extracted_data_default
+        WHERE created_at >= partition_month
+         AND created_at < partition_month + INTERVAL '1 month'
+      ) THEN
+        -- A partition cannot be created over the runs of the default partition: they are moved to a new table,
+        -- attached afterwards. The partitions are not subject to the row level security policies of the runs.
+        EXECUTE format('CREATE TABLE %I (LIKE extracted_data INCLUDING DEFAULTS)', partition_name);
+        EXECUTE format(
+          'WITH moved AS (DELETE FROM extracted_data_default WHERE created_at >= %L AND created_at < %L RETURNING *) '
+          'INSERT INTO %I SELECT * FROM moved',
+          partition_month, partition_month + INTERVAL '1 month', partition_name
+        );
+        EXECUTE format(
+          'ALTER TABLE extracted_data ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+          partition_name, partition_month, partition_month + INTERVAL '1 month'
+        );
+      ELSE
+        EXECUTE format(
+          'CREATE TABLE %I PARTITION OF extracted_data FOR VALUES FROM (%L) TO (%L)',
+          partition_name, partition_month, partition_month + INTERVAL '1 month'
+        );
+      END IF;
+      created := created + 1;
+    END IF;
+    partition_month := partition_month + INTERVAL '1 month';
+  END LOOP;
+  RETURN created;
+END
+$$;
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// partitionLayout is the name of the monthly partitions of the runs, as created by extracted_data_create_partitions.
+const partitionLayout = "extracted_data_2006_01"
+
+// Partitions maintains the monthly partitions of the runs.
+type Partitions interface {
+	// Create creates the missing partitions, up to monthsAhead months after the current one, moving the runs
+	// of their months out of the default partition, and returns how many were created.
+	Create(ctx context.Context, monthsAhead int) (int, error)
+	// Expired lists the partitions whose month ended before a date, the oldest first.
+	Expired(ctx context.Context, before time.Time) ([]string, error)
+	// Detach detaches a partition without blocking the queries on the runs, and drops it.
+	Detach(ctx context.Context, name string) error
+}
+
+// NewPartitions returns the partitions of the runs stored in a database.
+func NewPartitions(db *pgxpool.Pool) Partitions {
+	return &partitions{
+		db: db,
+	}
+}
+
+type partitions struct {
+	db *pgxpool.Pool
+}
+
+func (p *partitions) Create(ctx context.Context, monthsAhead int) (created int, err error) {
+	err = p.db.QueryRow(ctx, "SELECT extracted_data_create_partitions(@from_date, @months_ahead)", pgx.NamedArgs{
+		"from_date":    time.Now().UTC(),
+		"months_ahead": monthsAhead,
+	}).Scan(&created)
+	return created, err
+}
+
+func (p *partitions) Expired(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := p.db.Query(ctx, `
+SELECT c.relname
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'extracted_data'::regclass
+ORDER BY c.relname`)
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []string{}
+	for _, name := range names {
+		month, err := time.Parse(partitionLayout, name)
+		if err != nil {
+			// Not a monthly partition.
+			continue
+		}
+		if !month.AddDate(0, 1, 0).After(before) {
+			expired = append(expired, name)
+		}
+	}
+	return expired, nil
+}
+
+func (p *partitions) Detach(ctx context.Context, name string) error {
+	table := pgx.Identifier{name}.Sanitize()
+
+	// Detaching concurrently cannot run in a transaction, and only takes a weak lock on the runs.
+	// A detach interrupted midway is left pending, and has to be finalized instead.
+	var pending bool
+	if err := p.db.QueryRow(ctx, "SELECT inhdetachpending FROM pg_inherits WHERE inhrelid = $1::regclass", table).Scan(&pending); err != nil {
+		return err
+	}
+	detach := "ALTER TABLE extracted_data DETACH PARTITION " + table + " CONCURRENTLY"
+	if pending {
+		detach = "ALTER TABLE extracted_data DETACH PARTITION " + table + " FINALIZE"
+	}
+	if _, err := p.db.Exec(ctx, detach); err != nil {
+		return err
+	}
+	_, err := p.db.Exec(ctx, "DROP TABLE "+table)
+	return err
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+const (
+	// keyPrefix starts every generated key, so that leaked keys can be recognized.
+	keyPrefix = "hk_"
+	// displayedPrefixLength is the number of characters of a key kept in clear for display.
+	displayedPrefixLength = 10
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid or revoked api key")
+	ErrNameRequired = errors.New("name is required")
+	ErrInvalidScope = errors.New("scopes must be extract, read-history, webhooks, watches or admin")
+	ErrMissingScope = errors.New("api key lacks the required scope")
+	ErrRateLimited  = errors.New("api key rate limit exceeded")
+
+	ErrInvalidWorkspace   = errors.New("workspace must be a lowercase slug of at most 63 characters")
+	ErrForbiddenWorkspace = errors.New("api key is bound to another workspace")
+)
+
+// CreatedAPIKey is an API key along with its clear value, only available at creation.
+type CreatedAPIKey struct {
+	apikeys.APIKey
+	Key string `json:"key" xml:"key"`
+}
+
+// Service represents the authentication service interface.
+type Service interface {
+	CreateKey(ctx context.Context, name string, scopes []string, workspaceID string, rateLimitPerMinute int) (*CreatedAPIKey, error)
+	ListKeys(ctx context.Context) ([]apikeys.APIKey, error)
+	RevokeKey(ctx context.Context, id uint64) (*apikeys.APIKey, error)
+	Authenticate(ctx context.Context, key string) (*apikeys.APIKey, error)
+}
+
+// NewService returns a new instance of the authentication service.
+// The bootstrap admin key, if not empty, is granted the admin scope without being stored,
+// so that the first keys can be created.
+func NewService(
+	l log.Logger,
+	apiKeysRepo apikeys.Repository,
+	bootstrapAdminKey string,
+) Service {
+	return &service{
+		l:                 l,
+		apiKeysRepo:       apiKeysRepo,
+		bootstrapAdminKey: bootstrapAdminKey,
+	}
+}
+
+type service struct {
+	l                 log.Logger
+	apiKeysRepo       apikeys.Repository
+	bootstrapAdminKey string
+}
+
+// CreateKey generates a new API key bound to a workspace, the default one if empty. Only its hash is stored.
+func (s *service) CreateKey(ctx context.Context, name string, scopes []string, workspaceID string, rateLimitPerMinute int) (*CreatedAPIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, ErrNameRequired
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(apikeys.Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+	if workspaceID == "" {
+		workspaceID = workspaces.DefaultID
+	}
+	if !workspaces.ValidID(workspaceID) {
+		return nil, ErrInvalidWorkspace
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := keyPrefix + hex.EncodeToString(b)
+
+	apiKey, err := s.apiKeysRepo.Insert(ctx, &apikeys.APIKey{
+		Name:               name,
+		Prefix:             key[:displayedPrefixLength],
+		Hash:               hashKey(key),
+		Scopes:             scopes,
+		WorkspaceID:        workspaceID,
+		RateLimitPerMinute: max(rateLimitPerMinute, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+// ListKeys returns every API key, revoked ones included.
+func (s *service) ListKeys(ctx context.Context) ([]apikeys.APIKey, error) {
+	return s.apiKeysRepo.Find(ctx)
+}
+
+// RevokeKey revokes an API key. Revoking a revoked key is a no-op.
+func (s *service) RevokeKey(ctx context.Context, id uint64) (*apikeys.APIKey, error) {
+	return s.apiKeysRepo.Revoke(ctx, id)
+}
+
+// Authenticate returns the valid API key matching a clear key.
+func (s *service) Authenticate(ctx context.Context, key string) (*apikeys.APIKey, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	if s.bootstrapAdminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.bootstrapAdminKey)) == 1 {
+		return &apikeys.APIKey{Name: "bootstrap", Scopes: []string{apikeys.ScopeAdmin}, WorkspaceID: workspaces.DefaultID}, nil
+	}
+
+	apiKey, err := s.apiKeysRepo.GetByHash(ctx, hashKey(key))
+	switch {
+	case err == apikeys.ErrNotFound:
+		return nil, ErrInvalidKey
+	case err != nil:
+		return nil, err
+	case apiKey.RevokedAt != nil:
+		return nil, ErrInvalidKey
+	}
+	return apiKey, nil
+}
+
+// hashKey hashes a key for storage. Keys are random enough for a fast hash to be safe.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestCreateAuthenticateRevoke(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(log.NewNopLogger(), apikeys.NewMemoryRepository(), "")
+
+	created, err := service.CreateKey(ctx, "crm sync", []string{apikeys.ScopeExtract}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.WorkspaceID != workspaces.DefaultID {
+		t.Errorf("workspace = %q, want %q", created.WorkspaceID, workspaces.DefaultID)
+	}
+	if !strings.HasPrefix(created.Key, keyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("key = %q, prefix = %q", created.Key, created.Prefix)
+	}
+	if created.Hash == created.Key || strings.Contains(created.Hash, created.Key) {
+		t.Error("expected the key to be stored hashed")
+	}
+
+	apiKey, err := service.Authenticate(ctx, created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey.ID != created.ID || !apiKey.HasScope(apikeys.ScopeExtract) || apiKey.HasScope(apikeys.ScopeAdmin) {
+		t.Errorf("authenticated %+v, want %+v", apiKey, created.APIKey)
+	}
+	if _, err := service.Authenticate(ctx, created.Key+"x"); err != ErrInvalidKey {
+		t.Errorf("err = %v, want %v", err, ErrInvalidKey)
+	}
+
+	if _, err := service.RevokeKey(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(ctx, created.Key); err != ErrInvalidKey {
+		t.Errorf("err = %v, want %v for a revoked key", err, ErrInvalidKey)
+	}
+}
+
+func TestCreateKeyValidation(t *testing.T) {
+	service := NewService(log.NewNopLogger(), apikeys.NewMemoryRepository(), "")
+
+	tests := []struct {
+		name        string
+		scopes      []string
+		workspaceID string
+		want        error
+	}{
+		{name: "", scopes: []string{apikeys.ScopeExtract}, want: ErrNameRequired},
+		{name: "no scope", scopes: nil, want: ErrInvalidScope},
+		{name: "unknown scope", scopes: []string{"superuser"}, want: ErrInvalidScope},
+		{name: "all workspaces", scopes: []string{apikeys.ScopeExtract}, workspaceID: workspaces.AllID, want: ErrInvalidWorkspace},
+		{name: "uppercase workspace", scopes: []string{apikeys.ScopeExtract}, workspaceID: "Acme", want: ErrInvalidWorkspace},
+	}
+	for _, tt := range tests {
+		if _, err := service.CreateKey(context.Background(), tt.name, tt.scopes, tt.workspaceID, 0); err != tt.want {
+			t.Errorf("%q %v: err = %v, want %v", tt.name, tt.scopes, err, tt.want)
+		}
+	}
+}
+
+func TestBootstrapAdminKey(t *testing.T) {
+	ctx := context.Background()
+
+	service := NewService(log.NewNopLogger(), apikeys.NewMemoryRepository(), "s3cr3t")
+	apiKey, err := service.Authenticate(ctx, "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !apiKey.HasScope(apikeys.ScopeAdmin) {
+		t.Errorf("scopes = %v, want admin", apiKey.Scopes)
+	}
+
+	// Without a bootstrap key, an empty key must not authenticate.
+	service = NewService(log.NewNopLogger(), apikeys.NewMemoryRepository(), "")
+	if _, err := service.Authenticate(ctx, ""); err != ErrInvalidKey {
+		t.Errorf("err = %v, want %v", err, ErrInvalidKey)
+	}
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/toolbox/api"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// APIKeyHeader is the request header carrying the API key, when not given as a bearer token.
+	APIKeyHeader = "X-API-Key"
+	// WorkspaceHeader is the request header selecting the workspace of an admin key.
+	WorkspaceHeader = "X-Workspace-ID"
+)
+
+var (
+	// httpRateLimited indicates that the API key exceeded its rate limit.
+	httpRateLimited = api.HTTPError{
+		Status:      http.StatusTooManyRequests,
+		Description: "Too many requests. Please retry later.",
+		ErrorCode:   "RATE_LIMITED",
+		Params:      make(map[string]interface{}),
+	}
+)
+
+// NewHTTPHandler returns a new HTTP handler for the API keys administration.
+func NewHTTPHandler(service Service, negotiator *render.Negotiator) http.Handler {
+	h := &httpHandler{
+		service: service,
+		render:  negotiator,
+	}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Post("/", h.CreateKey)
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Get("/", h.ListKeys)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Delete("/{id}", h.RevokeKey)
+
+	return router
+}
+
+type httpHandler struct {
+	service Service
+	render  render.Renderer
+}
+
+func (h *httpHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Name               string   `json:"name"`
+		Scopes             []string `json:"scopes"`
+		WorkspaceID        string   `json:"workspace_id"`
+		RateLimitPerMinute int      `json:"rate_limit_per_minute"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		return
+	}
+
+	result, err := h.service.CreateKey(ctx, req.Name, req.Scopes, req.WorkspaceID, req.RateLimitPerMinute)
+	if err != nil {
+		switch err {
+		case ErrNameRequired, ErrInvalidScope, ErrInvalidWorkspace:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusCreated, result)
+}
+
+func (h *httpHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := h.service.ListKeys(ctx)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	result, err := h.service.RevokeKey(ctx, id)
+	if err != nil {
+		switch err {
+		case apikeys.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+// NewAuthMiddleware returns a middleware authenticating requests with their API key,
+// and limiting the rate of requests of each key with a token bucket.
+// Keys without a rate limit of their own get ratePerMinute, unlimited if not positive.
+// Requests work in the workspace of their key. Admin keys can pick another one with the workspace header.
+// The errors are rendered in the media type the request prefers, as the routes have not negotiated it yet.
+func NewAuthMiddleware(service Service, renderer render.Renderer, ratePerMinute int, burst int) func(next http.Handler) http.Handler {
+	limiters := &rateLimiters{
+		ratePerMinute: ratePerMinute,
+		burst:         burst,
+		limiters:      map[uint64]*rate.Limiter{},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			errCtx := render.WithPreferredMediaType(ctx, r.Header.Get("Accept"))
+
+			key := r.Header.Get(APIKeyHeader)
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				key = bearer
+			}
+			apiKey, err := service.Authenticate(ctx, key)
+			if err != nil {
+				switch err {
+				case ErrInvalidKey:
+					renderer.RenderError(errCtx, w, api.HTTPUnauthorized, err)
+				default:
+					renderer.RenderError(errCtx, w, api.HTTPInternal, err)
+				}
+				return
+			}
+
+			if retryAfter, ok := limiters.allow(apiKey); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				renderer.RenderError(errCtx, w, httpRateLimited, ErrRateLimited)
+				return
+			}
+
+			workspaceID := apiKey.WorkspaceID
+			if header := r.Header.Get(WorkspaceHeader); header != "" && header != workspaceID {
+				switch {
+				case !workspaces.ValidID(header):
+					renderer.RenderError(errCtx, w, api.HTTPValidation, ErrInvalidWorkspace)
+					return
+				case !apiKey.HasScope(apikeys.ScopeAdmin):
+					renderer.RenderError(errCtx, w, api.HTTPForbidden, ErrForbiddenWorkspace)
+					return
+				}
+				workspaceID = header
+			}
+
+			ctx = apikeys.WithAPIKey(ctx, apiKey)
+			ctx = workspaces.WithID(ctx, workspaceID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScope returns a middleware refusing the requests whose API key lacks a scope.
+func RequireScope(renderer render.Renderer, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			apiKey := apikeys.FromContext(ctx)
+			switch {
+			case apiKey == nil:
+				renderer.RenderError(ctx, w, api.HTTPUnauthorized, ErrInvalidKey)
+				return
+			case !apiKey.HasScope(scope):
+				renderer.RenderError(ctx, w, api.HTTPForbidden, ErrMissingScope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimiters holds a token bucket per API key.
+type rateLimiters struct {
+	ratePerMinute int
+	burst         int
+
+	mu       sync.Mutex
+	limiters map[uint64]*rate.Limiter
+}
+
+// allow consumes a token of the key bucket, or tells how long to wait for one.
+func (l *rateLimiters) allow(apiKey *apikeys.APIKey) (retryAfter time.Duration, ok bool) {
+	ratePerMinute := l.ratePerMinute
+	if apiKey.RateLimitPerMinute > 0 {
+		ratePerMinute = apiKey.RateLimitPerMinute
+	}
+	if ratePerMinute <= 0 {
+		return 0, true
+	}
+	limit := rate.Limit(float64(ratePerMinute) / 60)
+
+	l.mu.Lock()
+	limiter, ok := l.limiters[apiKey.ID]
+	if !ok || limiter.Limit() != limit {
+		limiter = rate.NewLimiter(limit, max(l.burst, 1))
+		l.limiters[apiKey.ID] = limiter
+	}
+	l.mu.Unlock()
+
+	reservation := limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return delay, false
+	}
+	return 0, true
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/render"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(log.NewNopLogger(), apikeys.NewMemoryRepository(), "")
+	extractKey, err := service.CreateKey(ctx, "extractor", []string{apikeys.ScopeExtract}, "acme", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limitedKey, err := service.CreateKey(ctx, "limited", []string{apikeys.ScopeExtract}, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminKey, err := service.CreateKey(ctx, "admin", []string{apikeys.ScopeAdmin}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negotiator := render.NewNegotiator(nil, false)
+	var workspaceID string
+	handler := NewAuthMiddleware(service, negotiator, 0, 1)(
+		RequireScope(negotiator, apikeys.ScopeExtract)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apikeys.FromContext(r.Context()) == nil {
+				t.Error("expected the API key in the context")
+			}
+			workspaceID = workspaces.IDFromContext(r.Context())
+		})),
+	)
+	adminHandler := NewAuthMiddleware(service, negotiator, 0, 1)(NewHTTPHandler(service, negotiator))
+
+	tests := []struct {
+		name      string
+		handler   http.Handler
+		header    string
+		value     string
+		workspace string
+		want      int
+		// wantWorkspace is the workspace the request is expected to work in, if it succeeds.
+		wantWorkspace string
+	}{
+		{name: "no key", handler: handler, want: http.StatusUnauthorized},
+		{name: "invalid key", handler: handler, header: "Authorization", value: "Bearer hk_nope", want: http.StatusUnauthorized},
+		{name: "bearer", handler: handler, header: "Authorization", value: "Bearer " + extractKey.Key, want: http.StatusOK, wantWorkspace: "acme"},
+		{name: "header", handler: handler, header: APIKeyHeader, value: extractKey.Key, want: http.StatusOK, wantWorkspace: "acme"},
+		{name: "own workspace", handler: handler, header: APIKeyHeader, value: extractKey.Key, workspace: "acme", want: http.StatusOK, wantWorkspace: "acme"},
+		{name: "other workspace", handler: handler, header: APIKeyHeader, value: extractKey.Key, workspace: "globex", want: http.StatusForbidden},
+		{name: "admin workspace", handler: handler, header: APIKeyHeader, value: adminKey.Key, workspace: "globex", want: http.StatusOK, wantWorkspace: "globex"},
+		{name: "invalid workspace", handler: handler, header: APIKeyHeader, value: adminKey.Key, workspace: workspaces.AllID, want: http.StatusBadRequest},
+		{name: "missing scope", handler: adminHandler, header: APIKeyHeader, value: extractKey.Key, want: http.StatusForbidden},
+		{name: "within rate limit", handler: handler, header: APIKeyHeader, value: limitedKey.Key, want: http.StatusOK, wantWorkspace: workspaces.DefaultID},
+		{name: "rate limited", handler: handler, header: APIKeyHeader, value: limitedKey.Key, want: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		if tt.workspace != "" {
+			req.Header.Set(WorkspaceHeader, tt.workspace)
+		}
+		workspaceID = ""
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if workspaceID != tt.wantWorkspace {
+			t.Errorf("%s: workspace = %q, want %q", tt.name, workspaceID, tt.wantWorkspace)
+		}
+		if tt.want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: expected a Retry-After header", tt.name)
+		}
+	}
+
+	// The authentication errors are rendered in the media type the request prefers, before any negotiation.
+	for accept, want := range map[string]string{
+		"":                    render.MediaJSON,
+		render.MediaXML:       render.MediaXML,
+		render.MediaCSV:       render.MediaCSV,
+		"text/vcard":          render.MediaJSON,
+		"application/*;q=0.9": render.MediaJSON,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("accept %q: status = %d, want %d", accept, rec.Code, http.StatusUnauthorized)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, want) {
+			t.Errorf("accept %q: content type = %q, want %s", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package dataextraction
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/logutil"
+)
+
+const (
+	// charsPerToken is the rough number of characters per token used to estimate prompt sizes.
+	charsPerToken = 4
+	// completionTokensReserve is the number of completion tokens reserved for each extraction.
+	completionTokensReserve = 1024
+	// minPromptTokens is the smallest prompt worth sending when truncating a page to fit a budget.
+	minPromptTokens = 1000
+)
+
+var (
+	ErrBudgetExceeded = errors.New("openai budget exceeded")
+	ErrQuotaExceeded  = errors.New("client quota exceeded")
+)
+
+// Limits caps the OpenAI usage over a period. Zero values mean no limit.
+type Limits struct {
+	Tokens  int64
+	CostUSD float64
+}
+
+// Budget caps the OpenAI usage, globally and per client.
+type Budget struct {
+	Daily         Limits
+	Monthly       Limits
+	ClientDaily   Limits
+	ClientMonthly Limits
+	// Truncate makes pages exceeding the remaining budget truncated instead of refused.
+	Truncate bool
+}
+
+// NewBudgetExtractor returns an extractor refusing, or truncating, the extractions that would exceed the budget.
+// The usage is read from the extracted data history, so concurrent extractions may slightly overshoot it.
+func NewBudgetExtractor(
+	l log.Logger,
+	next Extractor,
+	extractedDataRepo extracteddata.Repository,
+	model string,
+	budget Budget,
+) Extractor {
+	return &budgetExtractor{
+		l:                 l,
+		next:              next,
+		extractedDataRepo: extractedDataRepo,
+		price:             priceOf(model),
+		budget:            budget,
+	}
+}
+
+type budgetExtractor struct {
+	l                 log.Logger
+	next              Extractor
+	extractedDataRepo extracteddata.Repository
+	price             modelPrice
+	budget            Budget
+}
+
+// ExtractDataFromString checks the estimated usage of the extraction against the budget before running it.
+func (e *budgetExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	maxPromptTokens, limitErr, err := e.maxPromptTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	promptTokens := estimateTokens(extractionPrompt + data)
+	if promptTokens > maxPromptTokens {
+		if !e.budget.Truncate || maxPromptTokens < minPromptTokens {
+			return nil, limitErr
+		}
+		maxChars := min(int(maxPromptTokens)*charsPerToken-len(extractionPrompt), len(data))
+		logutil.LoggerWithRequestID(ctx, e.l).Log("msg", "truncating page to fit the budget", "estimated_tokens", promptTokens, "max_tokens", maxPromptTokens)
+		data = strings.ToValidUTF8(data[:maxChars], "")
+	}
+
+	return e.next.ExtractDataFromString(ctx, data)
+}
+
+// budgetScope holds the limits applying to a client, or to everyone if clientID is empty.
+type budgetScope struct {
+	clientID         string
+	daily, monthly   Limits
+	errLimitExceeded error
+}
+
+// maxPromptTokens returns the largest prompt the remaining budget allows, along with the error to return
+// if the prompt is too large.
+func (e *budgetExtractor) maxPromptTokens(ctx context.Context) (int64, error, error) {
+	maxTokens, limitErr := int64(math.MaxInt64), ErrBudgetExceeded
+
+	scopes := []budgetScope{
+		{daily: e.budget.Daily, monthly: e.budget.Monthly, errLimitExceeded: ErrBudgetExceeded},
+	}
+	if clientID := ClientIDFromContext(ctx); clientID != "" {
+		scopes = append(scopes, budgetScope{
+			clientID:         clientID,
+			daily:            e.budget.ClientDaily,
+			monthly:          e.budget.ClientMonthly,
+			errLimitExceeded: ErrQuotaExceeded,
+		})
+	}
+
+	for _, scope := range scopes {
+		if scope.daily == (Limits{}) && scope.monthly == (Limits{}) {
+			continue
+		}
+		daily, monthly, err := e.usage(ctx, scope.clientID)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, tokens := range []int64{
+			e.remainingTokens(scope.daily, daily),
+			e.remainingTokens(scope.monthly, monthly),
+		} {
+			if tokens < maxTokens {
+				maxTokens, limitErr = tokens, scope.errLimitExceeded
+			}
+		}
+	}
+	return maxTokens, limitErr, nil
+}
+
+// remainingTokens converts what is left of the limits into a number of prompt tokens,
+// keeping enough for the completion.
+func (e *budgetExtractor) remainingTokens(limits Limits, used extracteddata.UsageAggregate) int64 {
+	remaining := int64(math.MaxInt64)
+	if limits.Tokens > 0 {
+		remaining = limits.Tokens - used.PromptTokens - used.CompletionTokens - completionTokensReserve
+	}
+	if limits.CostUSD > 0 && e.price.Prompt > 0 {
+		remainingCost := limits.CostUSD - used.CostUSD - completionTokensReserve*e.price.Completion/1_000_000
+		if tokens := int64(remainingCost / e.price.Prompt * 1_000_000); tokens < remaining {
+			remaining = tokens
+		}
+	}
+	return max(remaining, 0)
+}
+
+// usage returns the usage of the current UTC day and month, for a client or globally if clientID is empty.
+func (e *budgetExtractor) usage(ctx context.Context, clientID string) (daily, monthly extracteddata.UsageAggregate, err error) {
+	now := time.Now().UTC()
+	today := now.Format(time.DateOnly)
+	// The OpenAI account is shared by every workspace, so is its budget.
+	aggregates, err := e.extractedDataRepo.AggregateUsage(ctx, extracteddata.UsageSearch{
+		WorkspaceID:   workspaces.AllID,
+		GroupBy:       extracteddata.UsageByDay,
+		ClientID:      clientID,
+		CreatedAtFrom: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		return daily, monthly, err
+	}
+	for _, aggregate := range aggregates {
+		monthly.PromptTokens += aggregate.PromptTokens
+		monthly.CompletionTokens += aggregate.CompletionTokens
+		monthly.CostUSD += aggregate.CostUSD
+		if aggregate.Key == today {
+			daily = aggregate
+		}
+	}
+	return daily, monthly, nil
+}
+
+// estimateTokens roughly estimates the number of tokens of a text.
+func estimateTokens(text string) int64 {
+	return int64(len(text)+charsPerToken-1) / charsPerToken
+}
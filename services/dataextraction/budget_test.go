@@ -0,0 +1,75 @@
+package dataextraction
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+)
+
+// stubExtractor records the data it is asked to extract.
+type stubExtractor struct {
+	data string
+}
+
+func (e *stubExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	e.data = data
+	return &Extraction{}, nil
+}
+
+func TestBudgetExtractor(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	// The budget is shared by every workspace.
+	for workspaceID, clientID := range map[string]string{"default": "", "acme": "greedy"} {
+		if _, err := repo.Insert(ctx, &extracteddata.ExtractedData{
+			WorkspaceID:      workspaceID,
+			URL:              "https://acme-robotics.test/about",
+			PromptTokens:     4000,
+			CompletionTokens: 1000,
+			CostUSD:          0.02,
+			ClientID:         clientID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page := strings.Repeat("a", 4*charsPerToken*1000)
+
+	tests := []struct {
+		name      string
+		budget    Budget
+		clientID  string
+		want      error
+		truncated bool
+	}{
+		{name: "unlimited", budget: Budget{}},
+		{name: "within budget", budget: Budget{Daily: Limits{Tokens: 100_000}}},
+		{name: "daily tokens", budget: Budget{Daily: Limits{Tokens: 10_000}}, want: ErrBudgetExceeded},
+		{name: "monthly cost", budget: Budget{Monthly: Limits{CostUSD: 0.05}}, want: ErrBudgetExceeded},
+		{name: "client quota", budget: Budget{ClientDaily: Limits{Tokens: 8000}}, clientID: "greedy", want: ErrQuotaExceeded},
+		{name: "other client quota", budget: Budget{ClientDaily: Limits{Tokens: 8000}}, clientID: "frugal"},
+		{name: "anonymous quota", budget: Budget{ClientDaily: Limits{Tokens: 8000}}},
+		{name: "truncated", budget: Budget{Daily: Limits{Tokens: 14_000}, Truncate: true}, truncated: true},
+		{name: "too small to truncate", budget: Budget{Daily: Limits{Tokens: 12_000}, Truncate: true}, want: ErrBudgetExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &stubExtractor{}
+			extractor := NewBudgetExtractor(log.NewNopLogger(), next, repo, DefaultModel, tt.budget)
+
+			_, err := extractor.ExtractDataFromString(WithClientID(ctx, tt.clientID), page)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if truncated := len(next.data) < len(page); truncated != tt.truncated {
+				t.Errorf("truncated = %t, want %t", truncated, tt.truncated)
+			}
+		})
+	}
+}
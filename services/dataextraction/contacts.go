@@ -0,0 +1,265 @@
+package dataextraction
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/lib/render"
+)
+
+// mediaVCard is the media type of the vCards, negotiated on the extraction and history endpoints.
+const mediaVCard = "text/vcard"
+
+// Contact formats, rendering the people of the runs for address books and CRMs.
+const (
+	FormatJSON       = "json"
+	FormatVCard      = "vcard"
+	FormatHubSpot    = "hubspot"
+	FormatSalesforce = "salesforce"
+)
+
+var (
+	ErrInvalidContactFormat  = errors.New("format must be json, vcard, hubspot or salesforce")
+	ErrInvalidContactMapping = errors.New("mapping must be a comma separated list of header:field pairs, for the hubspot and salesforce formats")
+)
+
+// ContactColumn maps a field of the contacts to a column of the CRM import files.
+type ContactColumn struct {
+	Header string
+	Field  string
+}
+
+// ContactLayouts are the default columns of the CRM import files.
+var ContactLayouts = map[string][]ContactColumn{
+	FormatHubSpot: {
+		{"First Name", "first_name"},
+		{"Last Name", "last_name"},
+		{"Email", "email"},
+		{"Phone Number", "phone"},
+		{"Job Title", "job_title"},
+		{"Company Name", "company_name"},
+		{"Website URL", "website"},
+		{"LinkedIn URL", "linkedin_url"},
+	},
+	FormatSalesforce: {
+		{"First Name", "first_name"},
+		{"Last Name", "last_name"},
+		{"Title", "job_title"},
+		{"Company", "company_name"},
+		{"Email", "email"},
+		{"Phone", "phone"},
+		{"Website", "website"},
+		{"LinkedIn URL", "linkedin_url"},
+	},
+}
+
+// contact is a person, along with the run it was found by.
+type contact struct {
+	run    *extracteddata.ExtractedData
+	person *people.Person
+}
+
+// contactFields are the fields the CRM import columns can be mapped to.
+var contactFields = map[string]func(c contact) string{
+	"full_name":     func(c contact) string { return c.person.FullName },
+	"first_name":    func(c contact) string { first, _ := splitName(c.person.FullName); return first },
+	"last_name":     func(c contact) string { _, last := splitName(c.person.FullName); return last },
+	"job_title":     func(c contact) string { return c.person.JobTitle },
+	"email":         func(c contact) string { return c.person.Contact.Email },
+	"phone":         func(c contact) string { return c.person.Contact.Phone },
+	"linkedin_url":  func(c contact) string { return c.person.Contact.LinkedinURL },
+	"x_url":         func(c contact) string { return c.person.Contact.XURL },
+	"instagram_url": func(c contact) string { return c.person.Contact.InstagramURL },
+	"facebook_url":  func(c contact) string { return c.person.Contact.FacebookURL },
+	"company_name":  func(c contact) string { return companyName(c.run) },
+	"website":       func(c contact) string { return website(c.run.URL) },
+	"url":           func(c contact) string { return c.run.URL },
+}
+
+// ContactEncoder renders the people of runs as vCards or CRM import files.
+type ContactEncoder struct {
+	format  string
+	columns []ContactColumn
+}
+
+// NewContactEncoder returns an encoder for a contact format, or nil for JSON.
+// The mapping replaces the default columns of the CRM layouts, like "Email Address:email,Mobile:phone".
+func NewContactEncoder(format string, mapping string) (*ContactEncoder, error) {
+	switch format {
+	case "", FormatJSON:
+		if mapping != "" {
+			return nil, ErrInvalidContactMapping
+		}
+		return nil, nil
+	case FormatVCard:
+		if mapping != "" {
+			return nil, ErrInvalidContactMapping
+		}
+		return &ContactEncoder{format: format}, nil
+	case FormatHubSpot, FormatSalesforce:
+		columns := ContactLayouts[format]
+		if mapping != "" {
+			var err error
+			if columns, err = parseContactMapping(mapping); err != nil {
+				return nil, err
+			}
+		}
+		return &ContactEncoder{format: format, columns: columns}, nil
+	default:
+		return nil, ErrInvalidContactFormat
+	}
+}
+
+// parseContactMapping parses comma separated header:field pairs.
+func parseContactMapping(mapping string) ([]ContactColumn, error) {
+	columns := []ContactColumn{}
+	for _, pair := range strings.Split(mapping, ",") {
+		header, field, ok := strings.Cut(pair, ":")
+		header, field = strings.TrimSpace(header), strings.TrimSpace(field)
+		if _, known := contactFields[field]; !ok || !known || header == "" {
+			return nil, ErrInvalidContactMapping
+		}
+		columns = append(columns, ContactColumn{Header: header, Field: field})
+	}
+	return columns, nil
+}
+
+// ContentType returns the media type of the encoded contacts.
+func (e *ContactEncoder) ContentType() string {
+	if e.format == FormatVCard {
+		return mediaVCard + "; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Encode writes the people of the runs, each person once as found by the first run holding them.
+func (e *ContactEncoder) Encode(w io.Writer, runs []extracteddata.ExtractedData) error {
+	contacts := []contact{}
+	seen := map[string]bool{}
+	for i := range runs {
+		for j := range runs[i].People {
+			if !alreadySeen(seen, "person", personKeys(runs[i].People[j])) {
+				contacts = append(contacts, contact{run: &runs[i], person: &runs[i].People[j]})
+			}
+		}
+	}
+
+	if e.format == FormatVCard {
+		return writeVCards(w, contacts)
+	}
+	return e.writeCSV(w, contacts)
+}
+
+func (e *ContactEncoder) writeCSV(w io.Writer, contacts []contact) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		record[i] = column.Header
+	}
+	cw.Write(record)
+	for _, c := range contacts {
+		for i, column := range e.columns {
+			record[i] = render.EscapeFormula(contactFields[column.Field](c))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeVCards writes a vCard 4.0 (RFC 6350) per contact. The social profiles use the
+// SOCIALPROFILE property of RFC 9554.
+func writeVCards(w io.Writer, contacts []contact) error {
+	var b strings.Builder
+	for _, c := range contacts {
+		first, last := splitName(c.person.FullName)
+		props := [][2]string{
+			{"BEGIN", "VCARD"},
+			{"VERSION", "4.0"},
+			{"FN", vcardText(c.person.FullName)},
+			{"N", vcardText(last) + ";" + vcardText(first) + ";;;"},
+		}
+		for _, prop := range []struct{ name, value string }{
+			{"TITLE", c.person.JobTitle},
+			{"ORG", companyName(c.run)},
+			{"EMAIL", c.person.Contact.Email},
+			{"TEL;VALUE=text", c.person.Contact.Phone},
+			{"SOCIALPROFILE;SERVICE-TYPE=LinkedIn", c.person.Contact.LinkedinURL},
+			{"SOCIALPROFILE;SERVICE-TYPE=X", c.person.Contact.XURL},
+			{"SOCIALPROFILE;SERVICE-TYPE=Instagram", c.person.Contact.InstagramURL},
+			{"SOCIALPROFILE;SERVICE-TYPE=Facebook", c.person.Contact.FacebookURL},
+			{"URL", website(c.run.URL)},
+		} {
+			if prop.value != "" {
+				props = append(props, [2]string{prop.name, vcardText(prop.value)})
+			}
+		}
+		props = append(props,
+			[2]string{"REV", c.run.CreatedAt.UTC().Format("20060102T150405Z")},
+			[2]string{"END", "VCARD"},
+		)
+		for _, prop := range props {
+			b.WriteString(foldVCardLine(prop[0] + ":" + prop[1]))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// vcardText escapes a text value.
+func vcardText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldVCardLine terminates a content line, folding it every 75 octets without splitting characters.
+func foldVCardLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// splitName splits a full name into a first name and a last name, the last name
+// taking everything after the first word, or the single word of the name.
+func splitName(fullName string) (string, string) {
+	words := strings.Fields(fullName)
+	switch len(words) {
+	case 0:
+		return "", ""
+	case 1:
+		return "", words[0]
+	default:
+		return words[0], strings.Join(words[1:], " ")
+	}
+}
+
+// companyName returns the name of the first company found by a run, the one the people likely work for.
+func companyName(run *extracteddata.ExtractedData) string {
+	if len(run.Companies) == 0 {
+		return ""
+	}
+	return run.Companies[0].Name
+}
+
+// website returns the origin of the page a run was extracted from.
+func website(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,139 @@
+package dataextraction
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+func TestContactEncoder(t *testing.T) {
+	runs := []extracteddata.ExtractedData{
+		{
+			URL:       "https://acme.test/team",
+			CreatedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+			People: []people.Person{
+				{
+					FullName: "Jane van Doe",
+					JobTitle: "CTO, Platform; Data",
+					Contact: people.Contact{
+						Email:       "jane@acme.test",
+						Phone:       "+33 1 23 45 67 89",
+						LinkedinURL: "https://www.linkedin.com/in/jane-van-doe-0123456789abcdef0123456789abcdef",
+					},
+				},
+				{FullName: "Prince", JobTitle: "=1+1"},
+			},
+			Companies: []companies.Company{{Name: "Acme"}},
+		},
+		// Jane is rendered once, as found by the most recent run.
+		{URL: "https://acme.test/about", People: []people.Person{{FullName: "Jane van Doe", Contact: people.Contact{Email: "JANE@acme.test"}}}},
+	}
+
+	encode := func(t *testing.T, format, mapping string) string {
+		t.Helper()
+		encoder, err := NewContactEncoder(format, mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := encoder.Encode(&buf, runs); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	t.Run("VCard", func(t *testing.T) {
+		want := "BEGIN:VCARD\r\n" +
+			"VERSION:4.0\r\n" +
+			"FN:Jane van Doe\r\n" +
+			"N:van Doe;Jane;;;\r\n" +
+			"TITLE:CTO\\, Platform\\; Data\r\n" +
+			"ORG:Acme\r\n" +
+			"EMAIL:jane@acme.test\r\n" +
+			"TEL;VALUE=text:+33 1 23 45 67 89\r\n" +
+			"SOCIALPROFILE;SERVICE-TYPE=LinkedIn:https://www.linkedin.com/in/jane-van-do\r\n" +
+			" e-0123456789abcdef0123456789abcdef\r\n" +
+			"URL:https://acme.test\r\n" +
+			"REV:20250301T100000Z\r\n" +
+			"END:VCARD\r\n" +
+			"BEGIN:VCARD\r\n" +
+			"VERSION:4.0\r\n" +
+			"FN:Prince\r\n" +
+			"N:Prince;;;;\r\n" +
+			"TITLE:=1+1\r\n" +
+			"ORG:Acme\r\n" +
+			"URL:https://acme.test\r\n" +
+			"REV:20250301T100000Z\r\n" +
+			"END:VCARD\r\n"
+		if got := encode(t, FormatVCard, ""); got != want {
+			t.Errorf("vcards = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("CSVLayouts", func(t *testing.T) {
+		tests := []struct {
+			format  string
+			mapping string
+			want    [][]string
+		}{
+			{
+				format: FormatHubSpot,
+				want: [][]string{
+					{"First Name", "Last Name", "Email", "Phone Number", "Job Title", "Company Name", "Website URL", "LinkedIn URL"},
+					{"Jane", "van Doe", "jane@acme.test", "+33 1 23 45 67 89", "CTO, Platform; Data", "Acme", "https://acme.test", "https://www.linkedin.com/in/jane-van-doe-0123456789abcdef0123456789abcdef"},
+					{"", "Prince", "", "", "'=1+1", "Acme", "https://acme.test", ""},
+				},
+			},
+			{
+				format:  FormatSalesforce,
+				mapping: "Last Name:last_name, Company:company_name,Lead Source URL:url",
+				want: [][]string{
+					{"Last Name", "Company", "Lead Source URL"},
+					{"van Doe", "Acme", "https://acme.test/team"},
+					{"Prince", "Acme", "https://acme.test/team"},
+				},
+			},
+		}
+		for _, tt := range tests {
+			records, err := csv.NewReader(strings.NewReader(encode(t, tt.format, tt.mapping))).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprintf("%q", records) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("%s: records = %q, want %q", tt.format, records, tt.want)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		tests := []struct {
+			format  string
+			mapping string
+			err     error
+		}{
+			{format: "", mapping: ""},
+			{format: FormatJSON, mapping: ""},
+			{format: "ldif", err: ErrInvalidContactFormat},
+			{format: FormatVCard, mapping: "Email:email", err: ErrInvalidContactMapping},
+			{format: FormatHubSpot, mapping: "Email", err: ErrInvalidContactMapping},
+			{format: FormatHubSpot, mapping: "Revenue:revenue", err: ErrInvalidContactMapping},
+			{format: FormatHubSpot, mapping: ":email", err: ErrInvalidContactMapping},
+		}
+		for _, tt := range tests {
+			encoder, err := NewContactEncoder(tt.format, tt.mapping)
+			if err != tt.err {
+				t.Errorf("%q %q: err = %v, want %v", tt.format, tt.mapping, err, tt.err)
+			}
+			if encoder != nil {
+				t.Errorf("%q %q: encoder = %+v, want nil", tt.format, tt.mapping, encoder)
+			}
+		}
+	})
+}
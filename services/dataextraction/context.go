@@ -0,0 +1,45 @@
+package dataextraction
+
+import (
+	"context"
+
+	"github.com/solher/hunterio-test/entities/apikeys"
+)
+
+type contextKey string
+
+const (
+	clientIDContextKey contextKey = "dataextraction_client_id"
+	refreshContextKey  contextKey = "dataextraction_refresh"
+)
+
+// WithClientID returns a context identifying the caller of the service, for usage attribution and quotas.
+func WithClientID(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, clientIDContextKey, clientID)
+}
+
+// ClientIDFromContext returns the caller identifier set by WithClientID, if any.
+func ClientIDFromContext(ctx context.Context) string {
+	clientID, _ := ctx.Value(clientIDContextKey).(string)
+	return clientID
+}
+
+// WithRefresh returns a context bypassing the cache, so that the page is extracted again even if its last run
+// is fresh. The watches use it, as their schedules can be shorter than the cache freshness.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshContextKey, true)
+}
+
+// RefreshFromContext tells whether the context bypasses the cache, see WithRefresh.
+func RefreshFromContext(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshContextKey).(bool)
+	return refresh
+}
+
+// apiKeyID returns the ID of the API key the request was authenticated with, if any.
+func apiKeyID(ctx context.Context) uint64 {
+	if apiKey := apikeys.FromContext(ctx); apiKey != nil {
+		return apiKey.ID
+	}
+	return 0
+}
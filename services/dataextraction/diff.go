@@ -0,0 +1,169 @@
+package dataextraction
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+// Diff reports what changed between two runs of the same URL.
+type Diff struct {
+	URL           string                          `json:"url" xml:"url"`
+	FromID        uint64                          `json:"from_id" xml:"from_id"`
+	ToID          uint64                          `json:"to_id" xml:"to_id"`
+	FromCreatedAt time.Time                       `json:"from_created_at" xml:"from_created_at"`
+	ToCreatedAt   time.Time                       `json:"to_created_at" xml:"to_created_at"`
+	People        EntitiesDiff[people.Person]     `json:"people" xml:"people"`
+	Companies     EntitiesDiff[companies.Company] `json:"companies" xml:"companies"`
+}
+
+// EntitiesDiff lists the entities added, removed and changed between two runs.
+type EntitiesDiff[T any] struct {
+	Added   []T               `json:"added" xml:"added>item"`
+	Removed []T               `json:"removed" xml:"removed>item"`
+	Changed []EntityChange[T] `json:"changed" xml:"changed>item"`
+}
+
+// EntityChange is an entity found in both runs, with different fields.
+type EntityChange[T any] struct {
+	From   T             `json:"from" xml:"from"`
+	To     T             `json:"to" xml:"to"`
+	Fields []FieldChange `json:"fields" xml:"fields>field"`
+}
+
+// FieldChange is a field whose value changed. Nested fields are dotted, like contact.email.
+type FieldChange struct {
+	Field string `json:"field" xml:"field"`
+	From  any    `json:"from" xml:"from"`
+	To    any    `json:"to" xml:"to"`
+}
+
+// Empty tells whether the runs hold the same data.
+func (d *Diff) Empty() bool {
+	return d.People.empty() && d.Companies.empty()
+}
+
+func (d *EntitiesDiff[T]) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffRuns compares two runs of the same URL.
+func diffRuns(from, to *extracteddata.ExtractedData) *Diff {
+	return &Diff{
+		URL:           to.URL,
+		FromID:        from.ID,
+		ToID:          to.ID,
+		FromCreatedAt: from.CreatedAt,
+		ToCreatedAt:   to.CreatedAt,
+		People:        diffEntities(from.People, to.People, personKeys),
+		Companies:     diffEntities(from.Companies, to.Companies, companyKeys),
+	}
+}
+
+// personKeys returns the identifiers of a person, from the most to the least reliable.
+// People are matched on their first common identifier, so that a person whose email
+// was found by the second run only is still matched by name.
+func personKeys(person people.Person) []string {
+	return []string{
+		normalizeKey(person.Contact.Email),
+		normalizeKey(strings.TrimSuffix(person.Contact.LinkedinURL, "/")),
+		normalizeKey(person.FullName),
+	}
+}
+
+// companyKeys returns the identifiers of a company.
+func companyKeys(company companies.Company) []string {
+	return []string{normalizeKey(company.Name)}
+}
+
+// normalizeKey makes identifiers insensitive to the case and spacing variations of the extraction.
+func normalizeKey(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// diffEntities matches the entities of two runs by their keys, and compares the matched ones field by field.
+func diffEntities[T any](from, to []T, keys func(T) []string) EntitiesDiff[T] {
+	diff := EntitiesDiff[T]{Added: []T{}, Removed: []T{}, Changed: []EntityChange[T]{}}
+
+	matched := make([]bool, len(from))
+	for _, toEntity := range to {
+		i := matchEntity(from, matched, keys(toEntity), keys)
+		if i < 0 {
+			diff.Added = append(diff.Added, toEntity)
+			continue
+		}
+		matched[i] = true
+		if fields := diffFields("", reflect.ValueOf(from[i]), reflect.ValueOf(toEntity)); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, EntityChange[T]{From: from[i], To: toEntity, Fields: fields})
+		}
+	}
+	for i, fromEntity := range from {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, fromEntity)
+		}
+	}
+	return diff
+}
+
+// matchEntity returns the index of the first unmatched entity sharing an identifier with toKeys,
+// trying the identifiers by order of reliability, or -1.
+func matchEntity[T any](from []T, matched []bool, toKeys []string, keys func(T) []string) int {
+	for k, toKey := range toKeys {
+		if toKey == "" {
+			continue
+		}
+		for i, fromEntity := range from {
+			if !matched[i] && keys(fromEntity)[k] == toKey {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// diffFields compares two structs field by field, recursing into the nested structs.
+// Lists are compared regardless of their order.
+func diffFields(prefix string, from, to reflect.Value) []FieldChange {
+	changes := []FieldChange{}
+	for i := range from.NumField() {
+		field := from.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		fromField, toField := from.Field(i), to.Field(i)
+		switch fromField.Kind() {
+		case reflect.Struct:
+			changes = append(changes, diffFields(name+".", fromField, toField)...)
+		case reflect.Slice:
+			if !sameElements(fromField, toField) {
+				changes = append(changes, FieldChange{Field: name, From: fromField.Interface(), To: toField.Interface()})
+			}
+		default:
+			if !fromField.Equal(toField) {
+				changes = append(changes, FieldChange{Field: name, From: fromField.Interface(), To: toField.Interface()})
+			}
+		}
+	}
+	return changes
+}
+
+// sameElements tells whether two string lists hold the same elements, whatever their order.
+func sameElements(from, to reflect.Value) bool {
+	fromStrings, ok := from.Interface().([]string)
+	toStrings, _ := to.Interface().([]string)
+	if !ok {
+		return reflect.DeepEqual(from.Interface(), to.Interface())
+	}
+	fromStrings, toStrings = slices.Clone(fromStrings), slices.Clone(toStrings)
+	slices.Sort(fromStrings)
+	slices.Sort(toStrings)
+	return slices.Equal(fromStrings, toStrings)
+}
@@ -0,0 +1,140 @@
+package dataextraction
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestDiffRuns(t *testing.T) {
+	from := &extracteddata.ExtractedData{
+		ID: 1,
+		People: []people.Person{
+			{FullName: "Jane Doe", JobTitle: "CTO", Contact: people.Contact{Email: "jane@acme.test"}},
+			{FullName: "John Smith", JobTitle: "CEO"},
+			{FullName: "Ada Lovelace", JobTitle: "Engineer"},
+		},
+		Companies: []companies.Company{
+			{Name: "Acme", Locations: []string{"Paris", "Berlin"}, TechStack: []string{"Go"}},
+		},
+	}
+	to := &extracteddata.ExtractedData{
+		ID: 2,
+		People: []people.Person{
+			// Same email, new name spelling and title.
+			{FullName: "Jane Doe-Martin", JobTitle: "CEO", Contact: people.Contact{Email: "JANE@acme.test"}},
+			// Matched by name, with an email found this time.
+			{FullName: "john  smith", JobTitle: "CEO", Contact: people.Contact{Email: "john@acme.test"}},
+			{FullName: "Grace Hopper", JobTitle: "Engineer"},
+		},
+		Companies: []companies.Company{
+			{Name: "ACME", Locations: []string{"Berlin", "Paris"}, TechStack: []string{"Go"}, Employees: 50},
+			{Name: "Globex"},
+		},
+	}
+
+	diff := diffRuns(from, to)
+
+	if len(diff.People.Added) != 1 || diff.People.Added[0].FullName != "Grace Hopper" {
+		t.Errorf("added people = %+v", diff.People.Added)
+	}
+	if len(diff.People.Removed) != 1 || diff.People.Removed[0].FullName != "Ada Lovelace" {
+		t.Errorf("removed people = %+v", diff.People.Removed)
+	}
+	changedFields := map[string][]string{}
+	for _, change := range diff.People.Changed {
+		for _, field := range change.Fields {
+			changedFields[change.From.FullName] = append(changedFields[change.From.FullName], field.Field)
+		}
+	}
+	want := map[string][]string{
+		"Jane Doe":   {"full_name", "job_title", "contact.email"},
+		"John Smith": {"full_name", "contact.email"},
+	}
+	if fmt.Sprint(changedFields) != fmt.Sprint(want) {
+		t.Errorf("changed people fields = %v, want %v", changedFields, want)
+	}
+
+	if len(diff.Companies.Added) != 1 || diff.Companies.Added[0].Name != "Globex" || len(diff.Companies.Removed) != 0 {
+		t.Errorf("companies = %+v", diff.Companies)
+	}
+	// The locations are the same, in another order.
+	if len(diff.Companies.Changed) != 1 || fmt.Sprint(diff.Companies.Changed[0].Fields) != fmt.Sprint([]FieldChange{
+		{Field: "name", From: "Acme", To: "ACME"},
+		{Field: "employees", From: 0, To: 50},
+	}) {
+		t.Errorf("changed companies = %+v", diff.Companies.Changed)
+	}
+
+	if diff := diffRuns(from, from); !diff.Empty() {
+		t.Errorf("diff of a run with itself = %+v, want empty", diff)
+	}
+}
+
+func TestDiffExtractedData(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), nil, nil, repo, nil)
+	url := "https://acme-robotics.test/about"
+
+	if _, err := service.DiffExtractedData(ctx, "", 0, 0); err != ErrURLRequired {
+		t.Errorf("err = %v, want %v", err, ErrURLRequired)
+	}
+	if _, err := service.DiffExtractedData(ctx, url, 0, 0); err != ErrNotEnoughRuns {
+		t.Errorf("err = %v, want %v", err, ErrNotEnoughRuns)
+	}
+
+	runs := []*extracteddata.ExtractedData{}
+	for _, title := range []string{"Engineer", "CTO", "CEO"} {
+		run, err := repo.Insert(ctx, &extracteddata.ExtractedData{
+			WorkspaceID: workspaces.DefaultID,
+			URL:         url,
+			People:      []people.Person{{FullName: "Jane Doe", JobTitle: title}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, run)
+		time.Sleep(5 * time.Millisecond)
+	}
+	other, err := repo.Insert(ctx, &extracteddata.ExtractedData{WorkspaceID: workspaces.DefaultID, URL: "https://globex.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		fromID, toID   uint64
+		wantFrom, want uint64
+		err            error
+	}{
+		{name: "latest", wantFrom: runs[1].ID, want: runs[2].ID},
+		{name: "preceding", toID: runs[1].ID, wantFrom: runs[0].ID, want: runs[1].ID},
+		{name: "explicit", fromID: runs[0].ID, toID: runs[2].ID, wantFrom: runs[0].ID, want: runs[2].ID},
+		{name: "first", toID: runs[0].ID, err: ErrNotEnoughRuns},
+		{name: "other url", fromID: other.ID, err: ErrRunNotFound},
+	}
+	for _, tt := range tests {
+		diff, err := service.DiffExtractedData(ctx, url, tt.fromID, tt.toID)
+		if err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if diff.FromID != tt.wantFrom || diff.ToID != tt.want {
+			t.Errorf("%s: diff of %d -> %d, want %d -> %d", tt.name, diff.FromID, diff.ToID, tt.wantFrom, tt.want)
+		}
+		if len(diff.People.Changed) != 1 {
+			t.Errorf("%s: changed people = %+v", tt.name, diff.People.Changed)
+		}
+	}
+}
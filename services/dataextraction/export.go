@@ -0,0 +1,307 @@
+package dataextraction
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/lib/xlsx"
+)
+
+// Export kinds.
+const (
+	// ExportHistory exports every person and company of every run, one per row.
+	ExportHistory = "history"
+	// ExportPeople exports each person once, as found by their most recent run.
+	ExportPeople = "people"
+	// ExportCompanies exports each company once, as found by its most recent run.
+	ExportCompanies = "companies"
+)
+
+// Export formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// ExportContentTypes maps the export formats to their media type.
+var ExportContentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+var (
+	ErrInvalidExportKind   = errors.New("kind must be history, people or companies")
+	ErrInvalidExportFormat = errors.New("format must be csv, ndjson or xlsx")
+	ErrInvalidExportColumn = errors.New("unknown export column")
+)
+
+// ExportRequest selects the runs to export, and how. Every column of the kind is exported if none is given.
+type ExportRequest struct {
+	Kind          string
+	Format        string
+	Columns       []string
+	URL           string
+	CreatedAtFrom time.Time
+	CreatedAtTo   time.Time
+}
+
+// exportRow is a person or a company, along with the run it was found by.
+type exportRow struct {
+	run     *extracteddata.ExtractedData
+	person  *people.Person
+	company *companies.Company
+}
+
+// exportColumn is a named value of the rows.
+type exportColumn struct {
+	name  string
+	value func(row exportRow) any
+}
+
+var (
+	runColumns = []exportColumn{
+		{"run_id", func(row exportRow) any { return row.run.ID }},
+		{"url", func(row exportRow) any { return row.run.URL }},
+		{"created_at", func(row exportRow) any { return row.run.CreatedAt.Format(time.RFC3339) }},
+	}
+	entityColumn = exportColumn{"entity", func(row exportRow) any {
+		if row.person != nil {
+			return "person"
+		}
+		return "company"
+	}}
+	personColumns = []exportColumn{
+		personColumn("full_name", func(p *people.Person) any { return p.FullName }),
+		personColumn("job_title", func(p *people.Person) any { return p.JobTitle }),
+		personColumn("email", func(p *people.Person) any { return p.Contact.Email }),
+		personColumn("phone", func(p *people.Person) any { return p.Contact.Phone }),
+		personColumn("linkedin_url", func(p *people.Person) any { return p.Contact.LinkedinURL }),
+		personColumn("x_url", func(p *people.Person) any { return p.Contact.XURL }),
+		personColumn("instagram_url", func(p *people.Person) any { return p.Contact.InstagramURL }),
+		personColumn("facebook_url", func(p *people.Person) any { return p.Contact.FacebookURL }),
+	}
+	companyColumns = []exportColumn{
+		companyColumn("company_name", func(c *companies.Company) any { return c.Name }),
+		companyColumn("founded_year", func(c *companies.Company) any { return c.FoundedYear }),
+		companyColumn("industry", func(c *companies.Company) any { return c.Industry }),
+		companyColumn("revenue", func(c *companies.Company) any { return c.Revenue }),
+		companyColumn("employees", func(c *companies.Company) any { return c.Employees }),
+		companyColumn("locations", func(c *companies.Company) any { return c.Locations }),
+		companyColumn("tech_stack", func(c *companies.Company) any { return c.TechStack }),
+	}
+
+	// exportColumns lists the columns of each kind, in their default order.
+	exportColumns = map[string][]exportColumn{
+		ExportHistory:   slices.Concat(runColumns, []exportColumn{entityColumn}, personColumns, companyColumns),
+		ExportPeople:    slices.Concat(runColumns, personColumns),
+		ExportCompanies: slices.Concat(runColumns, companyColumns),
+	}
+)
+
+// personColumn returns a column empty on the company rows.
+func personColumn(name string, value func(p *people.Person) any) exportColumn {
+	return exportColumn{name, func(row exportRow) any {
+		if row.person == nil {
+			return nil
+		}
+		return value(row.person)
+	}}
+}
+
+// companyColumn returns a column empty on the person rows.
+func companyColumn(name string, value func(c *companies.Company) any) exportColumn {
+	return exportColumn{name, func(row exportRow) any {
+		if row.company == nil {
+			return nil
+		}
+		return value(row.company)
+	}}
+}
+
+// selectExportColumns returns the columns of a kind with the given names, in the given order.
+func selectExportColumns(kind string, names []string) ([]exportColumn, error) {
+	columns, ok := exportColumns[kind]
+	if !ok {
+		return nil, ErrInvalidExportKind
+	}
+	if len(names) == 0 {
+		return columns, nil
+	}
+
+	selected := []exportColumn{}
+	for _, name := range names {
+		i := slices.IndexFunc(columns, func(column exportColumn) bool { return column.name == name })
+		if i < 0 {
+			return nil, ErrInvalidExportColumn
+		}
+		selected = append(selected, columns[i])
+	}
+	return selected, nil
+}
+
+// exportRows returns the rows of a run for a kind. The people and companies already exported,
+// by a more recent run, are skipped unless the whole history is exported.
+func exportRows(kind string, run *extracteddata.ExtractedData, seen map[string]bool) []exportRow {
+	rows := []exportRow{}
+	if kind != ExportCompanies {
+		for i := range run.People {
+			if kind == ExportHistory || !alreadySeen(seen, "person", personKeys(run.People[i])) {
+				rows = append(rows, exportRow{run: run, person: &run.People[i]})
+			}
+		}
+	}
+	if kind != ExportPeople {
+		for i := range run.Companies {
+			if kind == ExportHistory || !alreadySeen(seen, "company", companyKeys(run.Companies[i])) {
+				rows = append(rows, exportRow{run: run, company: &run.Companies[i]})
+			}
+		}
+	}
+	return rows
+}
+
+// alreadySeen tells whether an entity shares an identifier with one seen before, and records its identifiers.
+// The identifiers are the ones matching the entities of two runs in the diffs.
+func alreadySeen(seen map[string]bool, entity string, keys []string) bool {
+	found := false
+	for k, key := range keys {
+		if key == "" {
+			continue
+		}
+		key = entity + ":" + strconv.Itoa(k) + ":" + key
+		found = found || seen[key]
+		seen[key] = true
+	}
+	return found
+}
+
+// rowWriter writes the rows of an export in a format.
+type rowWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+// newRowWriter returns a writer for the format, having written the header if the format has one.
+func newRowWriter(w io.Writer, format string, columns []exportColumn) (rowWriter, error) {
+	names := make([]string, len(columns))
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		names[i], header[i] = column.name, column.name
+	}
+
+	var rw rowWriter
+	switch format {
+	case FormatCSV:
+		rw = &csvRowWriter{w: csv.NewWriter(w)}
+	case FormatNDJSON:
+		return &ndjsonRowWriter{w: w, names: names}, nil
+	case FormatXLSX:
+		xw, err := xlsx.NewWriter(w, "export")
+		if err != nil {
+			return nil, err
+		}
+		rw = &xlsxRowWriter{w: xw}
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+	if err := rw.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvRowWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			record[i] = render.EscapeFormula(v)
+		case []string:
+			record[i] = render.EscapeFormula(strings.Join(v, "; "))
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case int:
+			record[i] = strconv.Itoa(v)
+		case uint64:
+			record[i] = strconv.FormatUint(v, 10)
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvRowWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonRowWriter struct {
+	w     io.Writer
+	names []string
+}
+
+// WriteRow writes the row as an object whose keys follow the order of the columns.
+func (w *ndjsonRowWriter) WriteRow(values []any) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(w.names[i])
+		buf.Write(key)
+		buf.WriteByte(':')
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	buf.WriteString("}\n")
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *ndjsonRowWriter) Close() error {
+	return nil
+}
+
+type xlsxRowWriter struct {
+	w *xlsx.Writer
+}
+
+func (w *xlsxRowWriter) WriteRow(values []any) error {
+	cells := make([]any, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			cells[i] = ""
+		case []string:
+			cells[i] = strings.Join(v, "; ")
+		default:
+			cells[i] = v
+		}
+	}
+	return w.w.WriteRow(cells)
+}
+
+func (w *xlsxRowWriter) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,144 @@
+package dataextraction
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestExportExtractedData(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), nil, nil, repo, nil)
+
+	for _, run := range []*extracteddata.ExtractedData{
+		{
+			URL:       "https://acme.test/about",
+			People:    []people.Person{{FullName: "Jane Doe", JobTitle: "CTO", Contact: people.Contact{Email: "jane@acme.test"}}},
+			Companies: []companies.Company{{Name: "Acme", Employees: 40, Locations: []string{"Paris", "Berlin"}}},
+		},
+		{
+			URL: "https://acme.test/team",
+			People: []people.Person{
+				{FullName: "Jane Doe", JobTitle: "CEO", Contact: people.Contact{Email: "JANE@acme.test"}},
+				{FullName: "=HYPERLINK(\"http://evil.test\")", JobTitle: "Engineer"},
+			},
+			Companies: []companies.Company{{Name: "Acme", Employees: 50}},
+		},
+		{WorkspaceID: "globex", URL: "https://globex.test", People: []people.Person{{FullName: "Hank Scorpio"}}},
+	} {
+		if run.WorkspaceID == "" {
+			run.WorkspaceID = workspaces.DefaultID
+		}
+		if _, err := repo.Insert(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	export := func(t *testing.T, req ExportRequest) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := service.ExportExtractedData(ctx, &buf, req); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	t.Run("CSVHistory", func(t *testing.T) {
+		records, err := csv.NewReader(strings.NewReader(export(t, ExportRequest{
+			Kind:    ExportHistory,
+			Format:  FormatCSV,
+			Columns: []string{"entity", "full_name", "company_name", "employees", "locations"},
+		}))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{
+			{"entity", "full_name", "company_name", "employees", "locations"},
+			// Most recent run first, and formulas escaped.
+			{"person", "Jane Doe", "", "", ""},
+			{"person", "'=HYPERLINK(\"http://evil.test\")", "", "", ""},
+			{"company", "", "Acme", "50", ""},
+			{"person", "Jane Doe", "", "", ""},
+			{"company", "", "Acme", "40", "Paris; Berlin"},
+		}
+		if fmt.Sprintf("%q", records) != fmt.Sprintf("%q", want) {
+			t.Errorf("records = %q, want %q", records, want)
+		}
+	})
+
+	t.Run("NDJSONPeople", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(export(t, ExportRequest{
+			Kind:    ExportPeople,
+			Format:  FormatNDJSON,
+			Columns: []string{"job_title", "email", "url"},
+		})), "\n")
+		// Jane is exported once, as found by the most recent run.
+		want := []string{
+			`{"job_title":"CEO","email":"JANE@acme.test","url":"https://acme.test/team"}`,
+			`{"job_title":"Engineer","email":"","url":"https://acme.test/team"}`,
+		}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("lines = %s, want %s", lines, want)
+		}
+		for _, line := range lines {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("invalid JSON line %s", line)
+			}
+		}
+	})
+
+	t.Run("XLSXCompanies", func(t *testing.T) {
+		out := export(t, ExportRequest{Kind: ExportCompanies, Format: FormatXLSX, URL: "https://acme.test/about"})
+		zr, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sheet string
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, _ := f.Open()
+				b, _ := io.ReadAll(rc)
+				rc.Close()
+				sheet = string(b)
+			}
+		}
+		if strings.Count(sheet, "<row>") != 2 || !strings.Contains(sheet, "Paris; Berlin") || !strings.Contains(sheet, "<c><v>40</v></c>") {
+			t.Errorf("sheet = %s", sheet)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		tests := []struct {
+			req ExportRequest
+			err error
+		}{
+			{req: ExportRequest{Kind: "everything", Format: FormatCSV}, err: ErrInvalidExportKind},
+			{req: ExportRequest{Kind: ExportPeople, Format: "pdf"}, err: ErrInvalidExportFormat},
+			{req: ExportRequest{Kind: ExportPeople, Format: FormatCSV, Columns: []string{"company_name"}}, err: ErrInvalidExportColumn},
+		}
+		for _, tt := range tests {
+			var buf bytes.Buffer
+			if err := service.ExportExtractedData(ctx, &buf, tt.req); err != tt.err {
+				t.Errorf("%+v: err = %v, want %v", tt.req, err, tt.err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("%+v: wrote %q before failing", tt.req, buf.String())
+			}
+		}
+	})
+}
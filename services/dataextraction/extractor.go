@@ -0,0 +1,136 @@
+package dataextraction
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+// Extraction holds the companies and people extracted from a webpage.
+type Extraction struct {
+	Companies []companies.Company `json:"companies"`
+	People    []people.Person     `json:"people"`
+	// Usage is not part of the model output, and so not of the JSON schema.
+	Usage Usage `json:"-"`
+}
+
+// Usage holds the resources consumed by an extraction.
+type Usage struct {
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+	Latency          time.Duration
+	CostUSD          float64
+}
+
+// Extractor extracts companies and people from a webpage content.
+type Extractor interface {
+	ExtractDataFromString(ctx context.Context, data string) (*Extraction, error)
+}
+
+// generateSchema generates a JSON schema for a given struct.
+func generateSchema[T any]() interface{} {
+	// Structured Outputs uses a subset of JSON schema
+	// These flags are necessary to comply with the subset
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties: false,
+		DoNotReference:            true,
+	}
+	var v T
+	schema := reflector.Reflect(v)
+	return schema
+}
+
+// Generate the JSON schema at initialization time
+var ExtractedDataSchema = generateSchema[Extraction]()
+
+const extractionPrompt = `
+You're looking for B2B data to help with lead generation for a CRM tool. Extract companies and people from the following webpage content.
+Be extra careful when extracting data and prefer to discard info if you have any doubt that it's matching the expected format.
+
+Webpage:
+`
+
+// PromptVersion identifies the extraction prompt, so that evaluation runs of different prompts can be told apart.
+var PromptVersion = func() string {
+	sum := sha256.Sum256([]byte(extractionPrompt))
+	return hex.EncodeToString(sum[:])[:12]
+}()
+
+// DefaultModel is the OpenAI model used when none is configured.
+const DefaultModel = openai.ChatModelGPT4o2024_08_06
+
+// NewOpenAIExtractor returns an extractor backed by the OpenAI chat completion API.
+func NewOpenAIExtractor(openAICli *openai.Client, model string) Extractor {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &openAIExtractor{
+		openAICli: openAICli,
+		model:     model,
+	}
+}
+
+type openAIExtractor struct {
+	openAICli *openai.Client
+	model     string
+}
+
+// ExtractDataFromString extracts data from a string using the OpenAI API.
+func (e *openAIExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	// Define the prompt
+	prompt := extractionPrompt + data
+
+	// Define the response format
+	resFormat := openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:        "extracted_companies_people",
+				Description: openai.String("Extracted companies and people from a webpage"),
+				Schema:      ExtractedDataSchema,
+				Strict:      openai.Bool(true),
+			},
+		},
+	}
+
+	start := time.Now()
+	chat, err := e.openAICli.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		},
+		ResponseFormat: resFormat,
+		Model:          e.model,
+		Temperature:    param.NewOpt(0.0), // We want the output to be the most deterministic possible.
+	})
+	latency := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned")
+	}
+
+	// extract into a well-typed struct
+	extractedData := &Extraction{}
+	err = json.Unmarshal([]byte(chat.Choices[0].Message.Content), extractedData)
+	if err != nil {
+		return nil, err
+	}
+	extractedData.Usage = Usage{
+		Model:            chat.Model,
+		PromptTokens:     chat.Usage.PromptTokens,
+		CompletionTokens: chat.Usage.CompletionTokens,
+		Latency:          latency,
+		CostUSD:          computeCost(chat.Model, chat.Usage.PromptTokens, chat.Usage.CompletionTokens),
+	}
+	return extractedData, nil
+}
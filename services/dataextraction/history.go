@@ -0,0 +1,69 @@
+package dataextraction
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/extracteddata"
+)
+
+var (
+	ErrInvalidLimit        = errors.New("limit must be positive")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidDomainPrefix = errors.New("domain prefix cannot hold a slash")
+)
+
+// HistoryRequest selects a page of runs, most recent first. The cursor is the next cursor of the
+// previous page, empty for the first one. The entity filters select the runs holding a person or a company
+// matching them, see extracteddata.Search.
+type HistoryRequest struct {
+	URL           string
+	DomainPrefix  string
+	CreatedAtFrom time.Time
+	CreatedAtTo   time.Time
+	PersonName    string
+	JobTitle      string
+	EmailDomain   string
+	CompanyName   string
+	Industry      string
+	TechStack     string
+	Limit         int
+	Cursor        string
+}
+
+// HistoryPage is a page of runs. The next cursor is empty on the last page.
+type HistoryPage struct {
+	Runs       []extracteddata.ExtractedData `json:"runs" xml:"runs>run"`
+	NextCursor string                        `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+}
+
+// encodeCursor returns the cursor of the runs following a run. The cursors are opaque to the clients,
+// so that the keyset they hold can change.
+func encodeCursor(run *extracteddata.ExtractedData) string {
+	key := run.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatUint(run.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor returns the creation date and ID of the run preceding the cursor.
+func decodeCursor(cursor string) (time.Time, uint64, error) {
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(key), ",")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return t, n, nil
+}
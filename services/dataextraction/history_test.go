@@ -0,0 +1,149 @@
+package dataextraction
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestListExtractedData(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), nil, nil, repo, nil)
+
+	insert := func(t *testing.T, url string) uint64 {
+		t.Helper()
+		run, err := repo.Insert(ctx, &extracteddata.ExtractedData{URL: url, WorkspaceID: workspaces.DefaultID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		return run.ID
+	}
+	ids := []uint64{}
+	for _, url := range []string{"https://acme.test/a", "https://globex.test/a", "https://acme.test/b", "https://acme.test/c", "https://acme.test/d"} {
+		ids = append(ids, insert(t, url))
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		req := HistoryRequest{DomainPrefix: "acme.", Limit: 2}
+		pages := []string{}
+		for i := 0; ; i++ {
+			page, err := service.ListExtractedData(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pageIDs := []uint64{}
+			for _, run := range page.Runs {
+				pageIDs = append(pageIDs, run.ID)
+			}
+			pages = append(pages, fmt.Sprint(pageIDs))
+			if page.NextCursor == "" {
+				break
+			}
+			// The runs inserted meanwhile do not shift the next pages.
+			if i == 0 {
+				insert(t, "https://acme.test/e")
+			}
+			req.Cursor = page.NextCursor
+		}
+
+		want := []string{
+			fmt.Sprint([]uint64{ids[4], ids[3]}),
+			fmt.Sprint([]uint64{ids[2], ids[0]}),
+		}
+		if fmt.Sprint(pages) != fmt.Sprint(want) {
+			t.Errorf("pages = %v, want %v", pages, want)
+		}
+	})
+
+	t.Run("EntityFilters", func(t *testing.T) {
+		run, err := repo.Insert(ctx, &extracteddata.ExtractedData{
+			URL:         "https://initech.test/team",
+			People:      []people.Person{{FullName: "Peter Gibbons", JobTitle: "CTO", Contact: people.Contact{Email: "peter@initech.test"}}},
+			Companies:   []companies.Company{{Name: "Initech", Industry: "Software", TechStack: []string{"COBOL"}}},
+			WorkspaceID: workspaces.DefaultID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, req := range []HistoryRequest{
+			{PersonName: "peter gibbons"},
+			{JobTitle: "CTO"},
+			{EmailDomain: "@initech.test"},
+			{CompanyName: "Initech", Industry: "Software", TechStack: "COBOL"},
+		} {
+			req.Limit = 10
+			page, err := service.ListExtractedData(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Runs) != 1 || page.Runs[0].ID != run.ID {
+				t.Errorf("%+v: runs = %+v, want the run of Initech", req, page.Runs)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		tests := []struct {
+			req HistoryRequest
+			err error
+		}{
+			{req: HistoryRequest{}, err: ErrInvalidLimit},
+			{req: HistoryRequest{Limit: 1, Cursor: "!"}, err: ErrInvalidCursor},
+			{req: HistoryRequest{Limit: 1, Cursor: "bm9wZQ"}, err: ErrInvalidCursor},
+			{req: HistoryRequest{Limit: 1, DomainPrefix: "acme.test/a"}, err: ErrInvalidDomainPrefix},
+		}
+		for _, tt := range tests {
+			if _, err := service.ListExtractedData(ctx, tt.req); err != tt.err {
+				t.Errorf("%+v: err = %v, want %v", tt.req, err, tt.err)
+			}
+		}
+	})
+}
+
+func TestGetAndDeleteExtractedData(t *testing.T) {
+	ctx := workspaces.WithID(context.Background(), workspaces.DefaultID)
+	repo := extracteddata.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), nil, nil, repo, nil)
+
+	run, err := repo.Insert(ctx, &extracteddata.ExtractedData{URL: "https://acme.test", WorkspaceID: workspaces.DefaultID, PromptTokens: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := service.GetExtractedData(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != run.ID {
+		t.Errorf("id = %d, want %d", found.ID, run.ID)
+	}
+
+	if err := service.DeleteExtractedData(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetExtractedData(ctx, run.ID); err != extracteddata.ErrNotFound {
+		t.Errorf("err = %v, want %v", err, extracteddata.ErrNotFound)
+	}
+	if err := service.DeleteExtractedData(ctx, run.ID); err != extracteddata.ErrNotFound {
+		t.Errorf("err = %v, want %v", err, extracteddata.ErrNotFound)
+	}
+
+	// Deleting a run does not give its usage back.
+	usage, err := service.GetUsage(ctx, time.Time{}, time.Now(), extracteddata.UsageByURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].PromptTokens != 1000 {
+		t.Errorf("usage = %+v, want the deleted run", usage)
+	}
+}
@@ -0,0 +1,125 @@
+package dataextraction
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cache lookup results.
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheStale  = "stale"
+	cacheBypass = "bypass"
+)
+
+// Metrics holds the Prometheus metrics of the service. A nil *Metrics records nothing.
+type Metrics struct {
+	cacheLookups   *prometheus.CounterVec
+	fetchDuration  *prometheus.HistogramVec
+	openAIDuration *prometheus.HistogramVec
+	openAITokens   *prometheus.CounterVec
+	openAIErrors   *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics of the service and registers them.
+func NewMetrics(reg prometheus.Registerer, namespace string) *Metrics {
+	m := &Metrics{
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "extraction",
+			Name:      "cache_lookups_total",
+			Help:      "Number of extraction cache lookups, by result: hit, miss or stale.",
+		}, []string{"result"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "extraction",
+			Name:      "fetch_duration_seconds",
+			Help:      "Latency of page fetches, by result: ok, not_found, unavailable or error.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		openAIDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "openai",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of OpenAI extractions.",
+			Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+		}, []string{"model"}),
+		openAITokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "openai",
+			Name:      "tokens_total",
+			Help:      "Number of OpenAI tokens consumed, by type: prompt or completion.",
+		}, []string{"model", "type"}),
+		openAIErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "openai",
+			Name:      "errors_total",
+			Help:      "Number of failed OpenAI extractions.",
+		}, []string{"model"}),
+	}
+	reg.MustRegister(m.cacheLookups, m.fetchDuration, m.openAIDuration, m.openAITokens, m.openAIErrors)
+	return m
+}
+
+// observeCacheLookup records the result of a cache lookup.
+func (m *Metrics) observeCacheLookup(result string) {
+	if m == nil {
+		return
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// observeFetch records the latency of a page fetch, classified by its error.
+func (m *Metrics) observeFetch(latency time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrPageNotFound):
+		result = "not_found"
+	case errors.Is(err, ErrServiceUnavailable):
+		result = "unavailable"
+	default:
+		result = "error"
+	}
+	m.fetchDuration.WithLabelValues(result).Observe(latency.Seconds())
+}
+
+// NewInstrumentingExtractor returns an extractor recording the latency, token usage and errors of next.
+func NewInstrumentingExtractor(next Extractor, model string, metrics *Metrics) Extractor {
+	return &instrumentingExtractor{
+		next:    next,
+		model:   model,
+		metrics: metrics,
+	}
+}
+
+type instrumentingExtractor struct {
+	next    Extractor
+	model   string
+	metrics *Metrics
+}
+
+// ExtractDataFromString records the metrics of the underlying extraction.
+func (e *instrumentingExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	if e.metrics == nil {
+		return e.next.ExtractDataFromString(ctx, data)
+	}
+
+	start := time.Now()
+	extraction, err := e.next.ExtractDataFromString(ctx, data)
+	e.metrics.openAIDuration.WithLabelValues(e.model).Observe(time.Since(start).Seconds())
+	if err != nil {
+		e.metrics.openAIErrors.WithLabelValues(e.model).Inc()
+		return nil, err
+	}
+	e.metrics.openAITokens.WithLabelValues(e.model, "prompt").Add(float64(extraction.Usage.PromptTokens))
+	e.metrics.openAITokens.WithLabelValues(e.model, "completion").Add(float64(extraction.Usage.CompletionTokens))
+	return extraction, nil
+}
@@ -0,0 +1,60 @@
+package dataextraction
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/lib/logutil"
+)
+
+// Publisher notifies the outcome of the extractions, to the webhook subscriptions for instance.
+type Publisher interface {
+	Publish(ctx context.Context, event string, data any) error
+}
+
+// ExtractionFailure is the data of the failed extraction events.
+type ExtractionFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// NewNotifyingService returns a service publishing an event when an extraction completes or fails.
+// The runs served from the cache are not extractions, and publish nothing.
+// Publishing errors are logged, they never fail the extraction.
+func NewNotifyingService(l log.Logger, next Service, publisher Publisher) Service {
+	return &notifyingService{
+		Service:   next,
+		l:         l,
+		publisher: publisher,
+	}
+}
+
+type notifyingService struct {
+	Service
+	l         log.Logger
+	publisher Publisher
+}
+
+func (s *notifyingService) ExtractAndPersistFromURL(ctx context.Context, url string) (*extracteddata.ExtractedData, error) {
+	start := time.Now()
+	extractedData, err := s.Service.ExtractAndPersistFromURL(ctx, url)
+
+	// The event outlives the request, but keeps its workspace.
+	publishCtx := context.WithoutCancel(ctx)
+	switch {
+	case err != nil:
+		s.publish(publishCtx, webhooks.EventExtractionFailed, ExtractionFailure{URL: url, Error: err.Error()})
+	case !extractedData.CreatedAt.Before(start):
+		s.publish(publishCtx, webhooks.EventExtractionCompleted, extractedData)
+	}
+	return extractedData, err
+}
+
+func (s *notifyingService) publish(ctx context.Context, event string, data any) {
+	if err := s.publisher.Publish(ctx, event, data); err != nil {
+		logutil.LoggerWithRequestID(ctx, s.l).Log("msg", "could not publish extraction event", "event", event, "err", err)
+	}
+}
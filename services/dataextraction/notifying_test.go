@@ -0,0 +1,58 @@
+package dataextraction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/webhooks"
+)
+
+// stubService returns the run or the error it is given.
+type stubService struct {
+	Service
+	extractedData *extracteddata.ExtractedData
+	err           error
+}
+
+func (s *stubService) ExtractAndPersistFromURL(ctx context.Context, url string) (*extracteddata.ExtractedData, error) {
+	return s.extractedData, s.err
+}
+
+// stubPublisher records the events it publishes.
+type stubPublisher struct {
+	events []string
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event string, data any) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestNotifyingService(t *testing.T) {
+	tests := []struct {
+		name          string
+		extractedData *extracteddata.ExtractedData
+		err           error
+		want          []string
+	}{
+		{name: "extracted", extractedData: &extracteddata.ExtractedData{CreatedAt: time.Now().Add(time.Second)}, want: []string{webhooks.EventExtractionCompleted}},
+		{name: "cached", extractedData: &extracteddata.ExtractedData{CreatedAt: time.Now().Add(-time.Minute)}},
+		{name: "failed", err: ErrPageNotFound, want: []string{webhooks.EventExtractionFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &stubPublisher{}
+			service := NewNotifyingService(log.NewNopLogger(), &stubService{extractedData: tt.extractedData, err: tt.err}, publisher)
+
+			if _, err := service.ExtractAndPersistFromURL(context.Background(), "https://acme-robotics.test/about"); err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if len(publisher.events) != len(tt.want) || (len(tt.want) > 0 && publisher.events[0] != tt.want[0]) {
+				t.Errorf("events = %v, want %v", publisher.events, tt.want)
+			}
+		})
+	}
+}
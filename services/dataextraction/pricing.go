@@ -0,0 +1,40 @@
+package dataextraction
+
+import "strings"
+
+// modelPrice is the price of a model, in USD per million tokens.
+type modelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// modelPrices lists the OpenAI prices of the models we use. Dated snapshots fall back to the price of their
+// model family, so the longest matching prefix wins.
+var modelPrices = map[string]modelPrice{
+	"gpt-4o":            {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-2024-05-13": {Prompt: 5.00, Completion: 15.00},
+	"gpt-4o-mini":       {Prompt: 0.15, Completion: 0.60},
+	"gpt-4.1":           {Prompt: 2.00, Completion: 8.00},
+	"gpt-4.1-mini":      {Prompt: 0.40, Completion: 1.60},
+	"gpt-4.1-nano":      {Prompt: 0.10, Completion: 0.40},
+}
+
+// priceOf returns the price of a model, or a zero price if it is unknown.
+func priceOf(model string) modelPrice {
+	var (
+		price  modelPrice
+		prefix string
+	)
+	for name, p := range modelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(prefix) {
+			price, prefix = p, name
+		}
+	}
+	return price
+}
+
+// computeCost returns the cost of a completion in USD, or zero if the model price is unknown.
+func computeCost(model string, promptTokens, completionTokens int64) float64 {
+	price := priceOf(model)
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1_000_000
+}
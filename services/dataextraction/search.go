@@ -0,0 +1,45 @@
+package dataextraction
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+var (
+	ErrQueryRequired     = errors.New("query is required")
+	ErrInvalidEntityKind = errors.New("kind must be person or company")
+)
+
+// SearchRequest searches the people and companies of the runs by name, job title or industry, tolerating
+// misspelled names. Kind restricts the search to people or companies, both are searched when empty.
+type SearchRequest struct {
+	Query string
+	Kind  string
+	Limit int
+}
+
+// SearchEntities returns the people and companies matching the request, the best ranked first. Each entity
+// is returned once, as found by its most recent run.
+func (s *service) SearchEntities(ctx context.Context, req SearchRequest) ([]extracteddata.EntityMatch, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return nil, ErrQueryRequired
+	}
+	if req.Kind != "" && req.Kind != extracteddata.EntityPerson && req.Kind != extracteddata.EntityCompany {
+		return nil, ErrInvalidEntityKind
+	}
+	if req.Limit <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	return s.extractedDataRepo.SearchEntities(ctx, extracteddata.EntitySearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		Query:       req.Query,
+		Kind:        req.Kind,
+		Limit:       req.Limit,
+	})
+}
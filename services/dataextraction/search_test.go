@@ -0,0 +1,62 @@
+package dataextraction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/companies"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestSearchEntities(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), nil, nil, repo, nil)
+
+	for _, workspaceID := range []string{workspaces.DefaultID, "globex"} {
+		if _, err := repo.Insert(ctx, &extracteddata.ExtractedData{
+			URL:         "https://acme.test/team",
+			People:      []people.Person{{FullName: "John Smith", JobTitle: "CTO"}},
+			Companies:   []companies.Company{{Name: "Acme Robotics", Industry: "Robotics"}},
+			WorkspaceID: workspaceID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Workspace", func(t *testing.T) {
+		matches, err := service.SearchEntities(ctx, SearchRequest{Query: " smith ", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 || matches[0].Name != "John Smith" {
+			t.Fatalf("matches = %+v, want John Smith once", matches)
+		}
+
+		matches, err = service.SearchEntities(workspaces.WithID(ctx, "initech"), SearchRequest{Query: "smith", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 0 {
+			t.Errorf("found %d matches in another workspace, want 0", len(matches))
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, tc := range []struct {
+			req  SearchRequest
+			want error
+		}{
+			{SearchRequest{Query: "  ", Limit: 10}, ErrQueryRequired},
+			{SearchRequest{Query: "smith", Kind: "robot", Limit: 10}, ErrInvalidEntityKind},
+			{SearchRequest{Query: "smith"}, ErrInvalidLimit},
+		} {
+			if _, err := service.SearchEntities(ctx, tc.req); err != tc.want {
+				t.Errorf("%+v: err = %v, want %v", tc.req, err, tc.want)
+			}
+		}
+	})
+}
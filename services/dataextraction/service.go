@@ -2,47 +2,59 @@ package dataextraction
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
-	"github.com/invopop/jsonschema"
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/packages/param"
-	"github.com/solher/hunterio-test/entities/companies"
 	"github.com/solher/hunterio-test/entities/extracteddata"
-	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/otelutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+var tracer = otel.Tracer("github.com/solher/hunterio-test/services/dataextraction")
+
 // Service represents the data extraction service interface.
 type Service interface {
 	ExtractAndPersistFromURL(ctx context.Context, url string) (*extracteddata.ExtractedData, error)
 	GetExtractedDataHistory(ctx context.Context, url string, from time.Time, to time.Time, limit int, offset int) ([]extracteddata.ExtractedData, error)
+	ListExtractedData(ctx context.Context, req HistoryRequest) (*HistoryPage, error)
+	GetExtractedData(ctx context.Context, id uint64) (*extracteddata.ExtractedData, error)
+	DeleteExtractedData(ctx context.Context, id uint64) error
+	GetUsage(ctx context.Context, from time.Time, to time.Time, groupBy string) ([]extracteddata.UsageAggregate, error)
+	DiffExtractedData(ctx context.Context, url string, fromID uint64, toID uint64) (*Diff, error)
+	ExportExtractedData(ctx context.Context, w io.Writer, req ExportRequest) error
+	SearchEntities(ctx context.Context, req SearchRequest) ([]extracteddata.EntityMatch, error)
 }
 
 // NewService returns a new instance of the data extraction service.
 func NewService(
 	l log.Logger,
-	openAICli *openai.Client,
+	httpCli *http.Client,
+	extractor Extractor,
 	extractedDataRepo extracteddata.Repository,
+	metrics *Metrics,
 ) Service {
 	return &service{
 		l:                 l,
-		httpCli:           &http.Client{},
-		openAICli:         openAICli,
+		httpCli:           httpCli,
+		extractor:         extractor,
 		extractedDataRepo: extractedDataRepo,
+		metrics:           metrics,
 	}
 }
 
 type service struct {
 	l                 log.Logger
 	httpCli           *http.Client
-	openAICli         *openai.Client
+	extractor         Extractor
 	extractedDataRepo extracteddata.Repository
+	metrics           *Metrics
 }
 
 const (
@@ -52,23 +64,45 @@ const (
 var (
 	ErrServiceUnavailable = errors.New("service unavailable, try again later")
 	ErrPageNotFound       = errors.New("page not found")
+	ErrInvalidGroupBy     = errors.New("group by must be one of day, url or domain")
+	ErrURLRequired        = errors.New("url is required")
+	ErrRunNotFound        = errors.New("extraction run not found for this url")
+	ErrNotEnoughRuns      = errors.New("at least two extraction runs are needed to diff")
 )
 
 // ExtractAndPersistFromURL fetches a page from a URL, extracts data from it, and persists it to the database.
-func (s *service) ExtractAndPersistFromURL(ctx context.Context, url string) (*extracteddata.ExtractedData, error) {
-	// First, we check if the data is already in the database for this URL.
-	extractedData, err := s.extractedDataRepo.GetLastByURL(ctx, url)
+func (s *service) ExtractAndPersistFromURL(ctx context.Context, url string) (_ *extracteddata.ExtractedData, err error) {
+	ctx, span := tracer.Start(ctx, "ExtractAndPersistFromURL")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+	span.SetAttributes(semconv.URLFull(url))
+
+	// First, we check if the data is already in the workspace for this URL.
+	extractedData, err := s.extractedDataRepo.GetLastByURL(ctx, workspaces.IDFromContext(ctx), url)
 	if err != nil && err != extracteddata.ErrNotFound {
 		return nil, err
 	}
 
-	// If the data is fresh, we return it. Otherwise, we refetch.
-	if extractedData != nil && extractedData.CreatedAt.After(time.Now().Add(-cacheFreshness)) {
+	// If the data is fresh, we return it, unless a refresh is requested. Otherwise, we refetch.
+	switch {
+	case extractedData == nil:
+		s.metrics.observeCacheLookup(cacheMiss)
+		span.SetAttributes(attribute.String("cache", cacheMiss))
+	case RefreshFromContext(ctx):
+		s.metrics.observeCacheLookup(cacheBypass)
+		span.SetAttributes(attribute.String("cache", cacheBypass))
+	case extractedData.CreatedAt.After(time.Now().Add(-cacheFreshness)):
+		s.metrics.observeCacheLookup(cacheHit)
+		span.SetAttributes(attribute.String("cache", cacheHit))
 		return extractedData, nil
+	default:
+		s.metrics.observeCacheLookup(cacheStale)
+		span.SetAttributes(attribute.String("cache", cacheStale))
 	}
 
 	// If the data is not in the database, we fetch it from the URL and extract the data using the OpenAI API.
+	start := time.Now()
 	strData, err := s.fetchStringDataFromURL(ctx, url)
+	s.metrics.observeFetch(time.Since(start), err)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +120,10 @@ func (s *service) ExtractAndPersistFromURL(ctx context.Context, url string) (*ex
 }
 
 // fetchStringDataFromURL fetches a page from a URL and returns the content as a string.
-func (s *service) fetchStringDataFromURL(ctx context.Context, url string) (string, error) {
+func (s *service) fetchStringDataFromURL(ctx context.Context, url string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "fetchStringDataFromURL")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
@@ -97,6 +134,7 @@ func (s *service) fetchStringDataFromURL(ctx context.Context, url string) (strin
 	}
 	defer resp.Body.Close()
 
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 	switch resp.StatusCode {
 	case http.StatusOK:
 		// All good, continue
@@ -110,82 +148,44 @@ func (s *service) fetchStringDataFromURL(ctx context.Context, url string) (strin
 	if err != nil {
 		return "", err
 	}
+	span.SetAttributes(attribute.Int("page.size", len(body)))
 	return string(body), nil
 }
 
-// generateSchema generates a JSON schema for a given struct.
-func generateSchema[T any]() interface{} {
-	// Structured Outputs uses a subset of JSON schema
-	// These flags are necessary to comply with the subset
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
-	var v T
-	schema := reflector.Reflect(v)
-	return schema
-}
+// extractDataFromString extracts the companies and people of a page content.
+func (s *service) extractDataFromString(ctx context.Context, data string) (_ *Extraction, err error) {
+	ctx, span := tracer.Start(ctx, "extractDataFromString")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
 
-type openAIExtractedData struct {
-	Companies []companies.Company `json:"companies"`
-	People    []people.Person     `json:"people"`
-}
-
-// Generate the JSON schema at initialization time
-var ExtractedDataSchema = generateSchema[openAIExtractedData]()
-
-// extractDataFromString extracts data from a string using the OpenAI API.
-func (s *service) extractDataFromString(ctx context.Context, data string) (*openAIExtractedData, error) {
-	// Define the prompt
-	prompt := `
-You're looking for B2B data to help with lead generation for a CRM tool. Extract companies and people from the following webpage content.
-Be extra careful when extracting data and prefer to discard info if you have any doubt that it's matching the expected format.
-
-Webpage:
-` + data
-
-	// Define the response format
-	resFormat := openai.ChatCompletionNewParamsResponseFormatUnion{
-		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
-				Name:        "extracted_companies_people",
-				Description: openai.String("Extracted companies and people from a webpage"),
-				Schema:      ExtractedDataSchema,
-				Strict:      openai.Bool(true),
-			},
-		},
-	}
-
-	chat, err := s.openAICli.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
-		},
-		ResponseFormat: resFormat,
-		Model:          openai.ChatModelGPT4o2024_08_06,
-		Temperature:    param.NewOpt(0.0), // We want the output to be the most deterministic possible.
-	})
+	extraction, err := s.extractor.ExtractDataFromString(ctx, data)
 	if err != nil {
 		return nil, err
 	}
-	if len(chat.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned")
-	}
-
-	// extract into a well-typed struct
-	extractedData := &openAIExtractedData{}
-	err = json.Unmarshal([]byte(chat.Choices[0].Message.Content), extractedData)
-	if err != nil {
-		return nil, err
-	}
-	return extractedData, nil
+	span.SetAttributes(
+		attribute.String("openai.model", extraction.Usage.Model),
+		attribute.Int64("openai.prompt_tokens", extraction.Usage.PromptTokens),
+		attribute.Int64("openai.completion_tokens", extraction.Usage.CompletionTokens),
+	)
+	return extraction, nil
 }
 
 // persistExtractedData persists the extracted data to the database.
-func (s *service) persistExtractedData(ctx context.Context, url string, data *openAIExtractedData) (*extracteddata.ExtractedData, error) {
+func (s *service) persistExtractedData(ctx context.Context, url string, data *Extraction) (_ *extracteddata.ExtractedData, err error) {
+	ctx, span := tracer.Start(ctx, "persistExtractedData")
+	defer func() { otelutil.RecordError(span, err); span.End() }()
+
 	newData, err := s.extractedDataRepo.Insert(ctx, &extracteddata.ExtractedData{
-		URL:       url,
-		Companies: data.Companies,
-		People:    data.People,
+		URL:              url,
+		Companies:        data.Companies,
+		People:           data.People,
+		Model:            data.Usage.Model,
+		PromptTokens:     data.Usage.PromptTokens,
+		CompletionTokens: data.Usage.CompletionTokens,
+		LatencyMs:        data.Usage.Latency.Milliseconds(),
+		CostUSD:          data.Usage.CostUSD,
+		ClientID:         ClientIDFromContext(ctx),
+		APIKeyID:         apiKeyID(ctx),
+		WorkspaceID:      workspaces.IDFromContext(ctx),
 	})
 	if err != nil {
 		return nil, err
@@ -194,6 +194,8 @@ func (s *service) persistExtractedData(ctx context.Context, url string, data *op
 }
 
 // GetExtractedDataHistory returns the extracted data history for a given URL.
+//
+// Deprecated: its offset pagination drifts as runs are inserted, ListExtractedData paginates by keyset.
 func (s *service) GetExtractedDataHistory(ctx context.Context, url string, from time.Time, to time.Time, limit int, offset int) ([]extracteddata.ExtractedData, error) {
 	if from.IsZero() {
 		return nil, errors.New("from cannot be zero")
@@ -206,6 +208,7 @@ func (s *service) GetExtractedDataHistory(ctx context.Context, url string, from
 	}
 
 	extractedDataList, err := s.extractedDataRepo.Find(ctx, extracteddata.Search{
+		WorkspaceID:   workspaces.IDFromContext(ctx),
 		URL:           url,
 		CreatedAtFrom: from,
 		CreatedAtTo:   to,
@@ -217,3 +220,192 @@ func (s *service) GetExtractedDataHistory(ctx context.Context, url string, from
 	}
 	return extractedDataList, nil
 }
+
+// ListExtractedData returns a page of the runs matching the request, most recent first. The pages are
+// paginated by keyset, so that the runs inserted meanwhile do not shift them.
+func (s *service) ListExtractedData(ctx context.Context, req HistoryRequest) (*HistoryPage, error) {
+	if req.Limit <= 0 {
+		return nil, ErrInvalidLimit
+	}
+	if strings.Contains(req.DomainPrefix, "/") {
+		return nil, ErrInvalidDomainPrefix
+	}
+
+	search := extracteddata.Search{
+		WorkspaceID:   workspaces.IDFromContext(ctx),
+		URL:           req.URL,
+		DomainPrefix:  req.DomainPrefix,
+		CreatedAtFrom: req.CreatedAtFrom,
+		CreatedAtTo:   req.CreatedAtTo,
+		PersonName:    req.PersonName,
+		JobTitle:      req.JobTitle,
+		EmailDomain:   strings.TrimPrefix(req.EmailDomain, "@"),
+		CompanyName:   req.CompanyName,
+		Industry:      req.Industry,
+		TechStack:     req.TechStack,
+		// One more run tells whether there is a next page.
+		Limit: req.Limit + 1,
+	}
+	if req.Cursor != "" {
+		var err error
+		if search.BeforeCreatedAt, search.BeforeID, err = decodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	runs, err := s.extractedDataRepo.Find(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	page := &HistoryPage{Runs: runs}
+	if len(runs) > req.Limit {
+		page.Runs = runs[:req.Limit]
+		page.NextCursor = encodeCursor(&page.Runs[req.Limit-1])
+	}
+	return page, nil
+}
+
+// GetExtractedData returns a run by ID, or extracteddata.ErrNotFound if it is not in the workspace.
+func (s *service) GetExtractedData(ctx context.Context, id uint64) (*extracteddata.ExtractedData, error) {
+	return s.extractedDataRepo.GetByID(ctx, workspaces.IDFromContext(ctx), id)
+}
+
+// DeleteExtractedData erases the people and companies of a run. Its usage still counts against the
+// budgets and quotas, so that deleting runs cannot reset them.
+func (s *service) DeleteExtractedData(ctx context.Context, id uint64) error {
+	return s.extractedDataRepo.Delete(ctx, workspaces.IDFromContext(ctx), id)
+}
+
+// ExportExtractedData streams the people and companies of the matching runs to w, in the requested format.
+// The request is validated before anything is written, so that its errors can still be reported to the caller.
+func (s *service) ExportExtractedData(ctx context.Context, w io.Writer, req ExportRequest) error {
+	columns, err := selectExportColumns(req.Kind, req.Columns)
+	if err != nil {
+		return err
+	}
+	if _, ok := ExportContentTypes[req.Format]; !ok {
+		return ErrInvalidExportFormat
+	}
+
+	rw, err := newRowWriter(w, req.Format, columns)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	values := make([]any, len(columns))
+	if err := s.extractedDataRepo.Stream(ctx, extracteddata.Search{
+		WorkspaceID:   workspaces.IDFromContext(ctx),
+		URL:           req.URL,
+		CreatedAtFrom: req.CreatedAtFrom,
+		CreatedAtTo:   req.CreatedAtTo,
+	}, func(run *extracteddata.ExtractedData) error {
+		for _, row := range exportRows(req.Kind, run, seen) {
+			for i, column := range columns {
+				values[i] = column.value(row)
+			}
+			if err := rw.WriteRow(values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// GetUsage returns the OpenAI usage of the extractions made in a time range, grouped by day, URL or domain.
+func (s *service) GetUsage(ctx context.Context, from time.Time, to time.Time, groupBy string) ([]extracteddata.UsageAggregate, error) {
+	switch groupBy {
+	case "":
+		groupBy = extracteddata.UsageByDay
+	case extracteddata.UsageByDay, extracteddata.UsageByURL, extracteddata.UsageByDomain:
+	default:
+		return nil, ErrInvalidGroupBy
+	}
+
+	aggregates, err := s.extractedDataRepo.AggregateUsage(ctx, extracteddata.UsageSearch{
+		WorkspaceID:   workspaces.IDFromContext(ctx),
+		GroupBy:       groupBy,
+		CreatedAtFrom: from,
+		CreatedAtTo:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aggregates, nil
+}
+
+// DiffExtractedData compares two runs of a URL. The target run defaults to the most recent one,
+// and the base run to the one preceding the target.
+func (s *service) DiffExtractedData(ctx context.Context, url string, fromID uint64, toID uint64) (*Diff, error) {
+	if url == "" {
+		return nil, ErrURLRequired
+	}
+	workspaceID := workspaces.IDFromContext(ctx)
+
+	var to *extracteddata.ExtractedData
+	if toID != 0 {
+		run, err := s.getRun(ctx, url, toID)
+		if err != nil {
+			return nil, err
+		}
+		to = run
+	} else {
+		run, err := s.extractedDataRepo.GetLastByURL(ctx, workspaceID, url)
+		switch {
+		case err == extracteddata.ErrNotFound:
+			return nil, ErrNotEnoughRuns
+		case err != nil:
+			return nil, err
+		}
+		to = run
+	}
+
+	var from *extracteddata.ExtractedData
+	if fromID != 0 {
+		run, err := s.getRun(ctx, url, fromID)
+		if err != nil {
+			return nil, err
+		}
+		from = run
+	} else {
+		// The preceding run is the most recent one created until the target, the target aside.
+		runs, err := s.extractedDataRepo.Find(ctx, extracteddata.Search{
+			WorkspaceID: workspaceID,
+			URL:         url,
+			CreatedAtTo: to.CreatedAt,
+			Limit:       2,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range runs {
+			if runs[i].ID != to.ID {
+				from = &runs[i]
+				break
+			}
+		}
+		if from == nil {
+			return nil, ErrNotEnoughRuns
+		}
+	}
+
+	return diffRuns(from, to), nil
+}
+
+// getRun returns a run of a URL by ID.
+func (s *service) getRun(ctx context.Context, url string, id uint64) (*extracteddata.ExtractedData, error) {
+	runs, err := s.extractedDataRepo.Find(ctx, extracteddata.Search{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		ID:          id,
+		URL:         url,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrRunNotFound
+	}
+	return &runs[0], nil
+}
@@ -0,0 +1,185 @@
+package dataextraction
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/lib/cassette"
+)
+
+// newTestService returns a service replaying the HTTP interactions of the given cassette.
+func newTestService(t *testing.T, cassettePath string, repo extracteddata.Repository, metrics *Metrics) Service {
+	t.Helper()
+
+	transport, err := cassette.New(cassettePath, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpCli := &http.Client{Transport: transport}
+	openAICli := openai.NewClient(
+		option.WithAPIKey("test"),
+		option.WithHTTPClient(httpCli),
+		option.WithMaxRetries(0),
+	)
+	extractor := NewInstrumentingExtractor(NewOpenAIExtractor(&openAICli, DefaultModel), DefaultModel, metrics)
+	return NewService(log.NewNopLogger(), httpCli, extractor, repo, metrics)
+}
+
+func TestExtractAndPersistFromURL(t *testing.T) {
+	ctx := apikeys.WithAPIKey(context.Background(), &apikeys.APIKey{ID: 7})
+	ctx = workspaces.WithID(ctx, "acme")
+	repo := extracteddata.NewMemoryRepository()
+	metrics := NewMetrics(prometheus.NewRegistry(), "test")
+	service := newTestService(t, "testdata/extract_about.json", repo, metrics)
+
+	extractedData, err := service.ExtractAndPersistFromURL(ctx, "https://acme-robotics.test/about")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractedData.URL != "https://acme-robotics.test/about" {
+		t.Errorf("url = %q, want %q", extractedData.URL, "https://acme-robotics.test/about")
+	}
+	if len(extractedData.Companies) != 1 || extractedData.Companies[0].Name != "Acme Robotics" {
+		t.Errorf("companies = %+v, want Acme Robotics", extractedData.Companies)
+	}
+	if len(extractedData.People) != 1 || extractedData.People[0].Contact.Email != "jane@acme-robotics.com" {
+		t.Errorf("people = %+v, want Jane Doe", extractedData.People)
+	}
+	if extractedData.Model != "gpt-4o-2024-08-06" || extractedData.PromptTokens != 180 || extractedData.CompletionTokens != 95 {
+		t.Errorf("usage = %s %d/%d, want gpt-4o-2024-08-06 180/95", extractedData.Model, extractedData.PromptTokens, extractedData.CompletionTokens)
+	}
+	if want := computeCost("gpt-4o-2024-08-06", 180, 95); extractedData.CostUSD != want {
+		t.Errorf("cost = %f, want %f", extractedData.CostUSD, want)
+	}
+	if extractedData.APIKeyID != 7 || extractedData.WorkspaceID != "acme" {
+		t.Errorf("attribution = %d/%q, want 7/acme", extractedData.APIKeyID, extractedData.WorkspaceID)
+	}
+	if n := countRuns(t, repo, extractedData.URL); n != 1 {
+		t.Fatalf("runs = %d, want 1", n)
+	}
+
+	// The second call is served from the database: the cassette has no interaction left,
+	// so any network access would fail.
+	cached, err := service.ExtractAndPersistFromURL(ctx, "https://acme-robotics.test/about")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.ID != extractedData.ID {
+		t.Errorf("cached id = %d, want %d", cached.ID, extractedData.ID)
+	}
+	if n := countRuns(t, repo, extractedData.URL); n != 1 {
+		t.Errorf("runs = %d, want 1", n)
+	}
+
+	for result, want := range map[string]float64{cacheMiss: 1, cacheHit: 1, cacheStale: 0} {
+		if got := testutil.ToFloat64(metrics.cacheLookups.WithLabelValues(result)); got != want {
+			t.Errorf("cache %s = %v, want %v", result, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.openAITokens.WithLabelValues(DefaultModel, "prompt")); got != 180 {
+		t.Errorf("prompt tokens = %v, want 180", got)
+	}
+}
+
+func TestExtractAndPersistFromURLStaleCache(t *testing.T) {
+	ctx := context.Background()
+	repo := &agedRepository{Repository: extracteddata.NewMemoryRepository(), age: 2 * cacheFreshness}
+	stale, err := repo.Insert(ctx, &extracteddata.ExtractedData{URL: "https://acme-robotics.test/about", WorkspaceID: workspaces.DefaultID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := newTestService(t, "testdata/extract_about.json", repo, nil)
+
+	extractedData, err := service.ExtractAndPersistFromURL(ctx, "https://acme-robotics.test/about")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractedData.ID == stale.ID {
+		t.Error("expected the stale run to be refreshed")
+	}
+	if n := countRuns(t, repo, extractedData.URL); n != 2 {
+		t.Errorf("runs = %d, want 2", n)
+	}
+}
+
+func TestExtractAndPersistFromURLRefresh(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	fresh, err := repo.Insert(ctx, &extracteddata.ExtractedData{URL: "https://acme-robotics.test/about", WorkspaceID: workspaces.DefaultID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewMetrics(prometheus.NewRegistry(), "test")
+	service := newTestService(t, "testdata/extract_about.json", repo, metrics)
+
+	extractedData, err := service.ExtractAndPersistFromURL(WithRefresh(ctx), "https://acme-robotics.test/about")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractedData.ID == fresh.ID {
+		t.Error("expected the fresh run to be bypassed")
+	}
+	if got := testutil.ToFloat64(metrics.cacheLookups.WithLabelValues(cacheBypass)); got != 1 {
+		t.Errorf("cache bypass = %v, want 1", got)
+	}
+}
+
+func TestExtractAndPersistFromURLFetchErrors(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{url: "https://acme-robotics.test/missing", want: ErrPageNotFound},
+		{url: "https://acme-robotics.test/broken", want: ErrServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			repo := extracteddata.NewMemoryRepository()
+			service := newTestService(t, "testdata/fetch_errors.json", repo, nil)
+
+			_, err := service.ExtractAndPersistFromURL(context.Background(), tt.url)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if n := countRuns(t, repo, tt.url); n != 0 {
+				t.Errorf("runs = %d, want 0", n)
+			}
+		})
+	}
+}
+
+// agedRepository makes the runs it returns look older than they are, to test the cache expiration.
+type agedRepository struct {
+	extracteddata.Repository
+	age time.Duration
+}
+
+func (r *agedRepository) GetLastByURL(ctx context.Context, workspaceID string, url string) (*extracteddata.ExtractedData, error) {
+	extractedData, err := r.Repository.GetLastByURL(ctx, workspaceID, url)
+	if err != nil {
+		return nil, err
+	}
+	extractedData.CreatedAt = extractedData.CreatedAt.Add(-r.age)
+	return extractedData, nil
+}
+
+// countRuns returns the number of runs stored for a URL, in every workspace.
+func countRuns(t *testing.T, repo extracteddata.Repository, url string) int {
+	t.Helper()
+	extractedDataList, err := repo.Find(context.Background(), extracteddata.Search{WorkspaceID: workspaces.AllID, URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(extractedDataList)
+}
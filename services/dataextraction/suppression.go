@@ -0,0 +1,47 @@
+package dataextraction
+
+import (
+	"context"
+
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+// Suppressions tells which people asked not to be ingested, like the data protection service.
+type Suppressions interface {
+	Suppressed(ctx context.Context, person people.Person) (bool, error)
+}
+
+// NewSuppressingExtractor returns an extractor dropping the suppressed people from the extractions of next,
+// before they are persisted.
+func NewSuppressingExtractor(next Extractor, suppressions Suppressions) Extractor {
+	return &suppressingExtractor{
+		next:         next,
+		suppressions: suppressions,
+	}
+}
+
+type suppressingExtractor struct {
+	next         Extractor
+	suppressions Suppressions
+}
+
+// ExtractDataFromString drops the suppressed people of the underlying extraction.
+func (e *suppressingExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	extraction, err := e.next.ExtractDataFromString(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []people.Person{}
+	for _, person := range extraction.People {
+		suppressed, err := e.suppressions.Suppressed(ctx, person)
+		if err != nil {
+			return nil, err
+		}
+		if !suppressed {
+			kept = append(kept, person)
+		}
+	}
+	extraction.People = kept
+	return extraction, nil
+}
@@ -0,0 +1,35 @@
+package dataextraction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/solher/hunterio-test/entities/people"
+)
+
+// stubSuppressions suppresses the people by name.
+type stubSuppressions map[string]bool
+
+func (s stubSuppressions) Suppressed(ctx context.Context, person people.Person) (bool, error) {
+	return s[person.FullName], nil
+}
+
+// peopleExtractor extracts the same people from every page.
+type peopleExtractor []people.Person
+
+func (e peopleExtractor) ExtractDataFromString(ctx context.Context, data string) (*Extraction, error) {
+	return &Extraction{People: e}, nil
+}
+
+func TestSuppressingExtractor(t *testing.T) {
+	next := peopleExtractor{{FullName: "Jane Doe"}, {FullName: "John Smith"}}
+	extractor := NewSuppressingExtractor(next, stubSuppressions{"Jane Doe": true})
+
+	extraction, err := extractor.ExtractDataFromString(context.Background(), "page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extraction.People) != 1 || extraction.People[0].FullName != "John Smith" {
+		t.Errorf("people = %+v, want John Smith only", extraction.People)
+	}
+}
@@ -1,53 +1,185 @@
 package dataextraction
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/lib/otelutil"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/services/auth"
 	"github.com/solher/toolbox/api"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// NewHTTPHandler returns a new HTTP handler for the service.
-func NewHTTPHandler(service Service, json *api.JSON) http.Handler {
+// ClientIDHeader is the request header identifying the caller, for usage attribution and quotas.
+const ClientIDHeader = "X-Client-ID"
+
+var (
+	// httpBudgetExceeded indicates that the OpenAI budget of the service is exhausted.
+	httpBudgetExceeded = api.HTTPError{
+		Status:      http.StatusPaymentRequired,
+		Description: "The extraction budget is exhausted. Please retry later.",
+		ErrorCode:   "BUDGET_EXCEEDED",
+		Params:      make(map[string]interface{}),
+	}
+	// httpBodyTooLarge indicates that the request body exceeds the size limit.
+	httpBodyTooLarge = api.HTTPError{
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "The request body is too large.",
+		ErrorCode:   "BODY_TOO_LARGE",
+		Params:      make(map[string]interface{}),
+	}
+	// httpQuotaExceeded indicates that the caller exhausted its quota.
+	httpQuotaExceeded = api.HTTPError{
+		Status:      http.StatusTooManyRequests,
+		Description: "Your extraction quota is exhausted. Please retry later.",
+		ErrorCode:   "QUOTA_EXCEEDED",
+		Params:      make(map[string]interface{}),
+	}
+)
+
+// NextCursorHeader is the response header carrying the next cursor of the history, for the formats
+// rendering the runs alone.
+const NextCursorHeader = "X-Next-Cursor"
+
+// NewHTTPHandler returns a new HTTP handler for the service. The responses are rendered in the media type
+// the Accept header prefers among JSON, XML, CSV, and NDJSON for the lists. The history pages hold
+// historyMaxPageSize runs at most, and by default.
+func NewHTTPHandler(service Service, negotiator *render.Negotiator, historyMaxPageSize int) http.Handler {
+	h := &httpHandler{
+		service:            service,
+		render:             negotiator,
+		historyMaxPageSize: historyMaxPageSize,
+	}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(append(render.ObjectMediaTypes, mediaVCard)...),
+		auth.RequireScope(negotiator, apikeys.ScopeExtract),
+	).Post("/", h.ExtractAndPersistFromURL)
+	router.With(
+		negotiator.Negotiate(append(render.ListMediaTypes, mediaVCard)...),
+		auth.RequireScope(negotiator, apikeys.ScopeReadHistory),
+	).Post("/history", h.GetExtractedDataHistory)
+	router.With(
+		negotiator.Negotiate(append(render.ListMediaTypes, mediaVCard)...),
+		auth.RequireScope(negotiator, apikeys.ScopeReadHistory),
+	).Get("/history", h.ListExtractedData)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeReadHistory),
+	).Get("/diff", h.DiffExtractedData)
+	router.With(
+		negotiator.Negotiate(append(render.ObjectMediaTypes, mediaVCard)...),
+		auth.RequireScope(negotiator, apikeys.ScopeReadHistory),
+	).Get("/runs/{id}", h.GetExtractedData)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Delete("/runs/{id}", h.DeleteExtractedData)
+	// The exports pick their format from the query, their errors are rendered in JSON.
+	router.With(auth.RequireScope(negotiator, apikeys.ScopeReadHistory)).Get("/export", h.ExportExtractedData)
+
+	return router
+}
+
+// NewUsageHTTPHandler returns a new HTTP handler exposing the OpenAI usage of the service.
+func NewUsageHTTPHandler(service Service, negotiator *render.Negotiator) http.Handler {
 	h := &httpHandler{
 		service: service,
-		json:    json,
+		render:  negotiator,
+	}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Get("/", h.GetUsage)
+
+	return router
+}
+
+// NewSearchHTTPHandler returns a new HTTP handler searching the people and companies of the runs. The matches
+// are capped at the history page size.
+func NewSearchHTTPHandler(service Service, negotiator *render.Negotiator, historyMaxPageSize int) http.Handler {
+	h := &httpHandler{
+		service:            service,
+		render:             negotiator,
+		historyMaxPageSize: historyMaxPageSize,
 	}
 
 	router := chi.NewRouter()
-	router.Post("/", h.ExtractAndPersistFromURL)
-	router.Post("/history", h.GetExtractedDataHistory)
+	// The matches mix people and companies, which share no CSV columns.
+	router.With(
+		negotiator.Negotiate(render.MediaJSON, render.MediaXML, render.MediaNDJSON),
+		auth.RequireScope(negotiator, apikeys.ScopeReadHistory),
+	).Get("/", h.SearchEntities)
 
 	return router
 }
 
 type httpHandler struct {
-	service Service
-	json    *api.JSON
+	service            Service
+	render             render.Renderer
+	historyMaxPageSize int
 }
 
 func (h *httpHandler) ExtractAndPersistFromURL(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	// Quotas apply per API key, the client header only tells apart the clients sharing the bootstrap key.
+	clientID := r.Header.Get(ClientIDHeader)
+	if apiKey := apikeys.FromContext(r.Context()); apiKey != nil && apiKey.ID != 0 {
+		clientID = "key-" + strconv.FormatUint(apiKey.ID, 10)
+	}
+	ctx := WithClientID(r.Context(), clientID)
+
+	// The format is checked before extracting, not to spend an extraction on a request bound to fail.
+	encoder, err := contactEncoder(r)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		return
+	}
 
 	result, err := h.service.ExtractAndPersistFromURL(ctx, r.URL.Query().Get("url"))
 	if err != nil {
 		switch err {
 		case ErrPageNotFound:
-			h.json.RenderError(ctx, w, api.HTTPNotFound, err)
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		case ErrBudgetExceeded:
+			h.render.RenderError(ctx, w, httpBudgetExceeded, err)
+		case ErrQuotaExceeded:
+			h.render.RenderError(ctx, w, httpQuotaExceeded, err)
 		default:
-			h.json.RenderError(ctx, w, api.HTTPInternal, err)
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
 		}
 		return
 	}
 
-	h.json.Render(ctx, w, http.StatusOK, result)
+	if encoder != nil {
+		h.renderContacts(ctx, w, encoder, []extracteddata.ExtractedData{*result})
+		return
+	}
+	h.render.Render(ctx, w, http.StatusOK, result)
 }
 
 func (h *httpHandler) GetExtractedDataHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	encoder, err := contactEncoder(r)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		return
+	}
+
 	var req struct {
 		URL           string    `json:"url"`
 		CreatedAtFrom time.Time `json:"created_at_from"`
@@ -56,15 +188,349 @@ func (h *httpHandler) GetExtractedDataHistory(w http.ResponseWriter, r *http.Req
 		Offset        int       `json:"offset"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.json.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.render.RenderError(ctx, w, httpBodyTooLarge, err)
+			return
+		}
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
 		return
 	}
 
 	result, err := h.service.GetExtractedDataHistory(ctx, req.URL, req.CreatedAtFrom, req.CreatedAtTo, req.Limit, req.Offset)
 	if err != nil {
-		h.json.RenderError(ctx, w, api.HTTPInternal, err)
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	if encoder != nil {
+		h.renderContacts(ctx, w, encoder, result)
+		return
+	}
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) ListExtractedData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	encoder, err := contactEncoder(r)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		return
+	}
+
+	req := HistoryRequest{
+		URL:          query.Get("url"),
+		DomainPrefix: query.Get("domain_prefix"),
+		PersonName:   query.Get("person_name"),
+		JobTitle:     query.Get("job_title"),
+		EmailDomain:  query.Get("email_domain"),
+		CompanyName:  query.Get("company_name"),
+		Industry:     query.Get("industry"),
+		TechStack:    query.Get("tech_stack"),
+		Limit:        h.historyMaxPageSize,
+		Cursor:       query.Get("cursor"),
+	}
+	if value := query.Get("limit"); value != "" {
+		if req.Limit, err = strconv.Atoi(value); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		if req.Limit > h.historyMaxPageSize {
+			h.render.RenderError(ctx, w, api.HTTPValidation, fmt.Errorf("limit cannot exceed %d", h.historyMaxPageSize))
+			return
+		}
+	}
+	for name, t := range map[string]*time.Time{"from": &req.CreatedAtFrom, "to": &req.CreatedAtTo} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		*t = parsed
+	}
+
+	result, err := h.service.ListExtractedData(ctx, req)
+	if err != nil {
+		switch err {
+		case ErrInvalidLimit, ErrInvalidCursor, ErrInvalidDomainPrefix:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	if result.NextCursor != "" {
+		w.Header().Set(NextCursorHeader, result.NextCursor)
+	}
+	switch mediaType := render.MediaTypeFromContext(ctx); {
+	case encoder != nil:
+		h.renderContacts(ctx, w, encoder, result.Runs)
+	case mediaType == render.MediaNDJSON || mediaType == render.MediaCSV:
+		// The rows of these formats are the runs, the next cursor is only in the header.
+		h.render.Render(ctx, w, http.StatusOK, result.Runs)
+	default:
+		h.render.Render(ctx, w, http.StatusOK, result)
+	}
+}
+
+func (h *httpHandler) GetExtractedData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+	encoder, err := contactEncoder(r)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		return
+	}
+
+	result, err := h.service.GetExtractedData(ctx, id)
+	if err != nil {
+		switch err {
+		case extracteddata.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
 		return
 	}
 
-	h.json.Render(ctx, w, http.StatusOK, result)
+	if encoder != nil {
+		h.renderContacts(ctx, w, encoder, []extracteddata.ExtractedData{*result})
+		return
+	}
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) DeleteExtractedData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	if err := h.service.DeleteExtractedData(ctx, id); err != nil {
+		switch err {
+		case extracteddata.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// contactEncoder returns the encoder of the contact format asked by the format query parameter,
+// or negotiated for vCards. It is nil when the runs are rendered by the negotiated renderer.
+func contactEncoder(r *http.Request) (*ContactEncoder, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" && render.MediaTypeFromContext(r.Context()) == mediaVCard {
+		format = FormatVCard
+	}
+	return NewContactEncoder(format, r.URL.Query().Get("mapping"))
+}
+
+// renderContacts renders the people of the runs with a contact encoder.
+func (h *httpHandler) renderContacts(ctx context.Context, w http.ResponseWriter, encoder *ContactEncoder, runs []extracteddata.ExtractedData) {
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, runs); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *httpHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		*t = parsed
+	}
+
+	result, err := h.service.GetUsage(ctx, from, to, r.URL.Query().Get("group_by"))
+	if err != nil {
+		switch err {
+		case ErrInvalidGroupBy:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) SearchEntities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	req := SearchRequest{
+		Query: query.Get("q"),
+		Kind:  query.Get("kind"),
+		Limit: h.historyMaxPageSize,
+	}
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if req.Limit, err = strconv.Atoi(value); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		if req.Limit > h.historyMaxPageSize {
+			h.render.RenderError(ctx, w, api.HTTPValidation, fmt.Errorf("limit cannot exceed %d", h.historyMaxPageSize))
+			return
+		}
+	}
+
+	result, err := h.service.SearchEntities(ctx, req)
+	if err != nil {
+		switch err {
+		case ErrQueryRequired, ErrInvalidEntityKind, ErrInvalidLimit:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) DiffExtractedData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var fromID, toID uint64
+	for name, id := range map[string]*uint64{"from_id": &fromID, "to_id": &toID} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		*id = parsed
+	}
+
+	result, err := h.service.DiffExtractedData(ctx, query.Get("url"), fromID, toID)
+	if err != nil {
+		switch err {
+		case ErrURLRequired:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		case ErrRunNotFound, ErrNotEnoughRuns:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) ExportExtractedData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	req := ExportRequest{
+		Kind:   query.Get("kind"),
+		Format: query.Get("format"),
+		URL:    query.Get("url"),
+	}
+	if req.Kind == "" {
+		req.Kind = ExportHistory
+	}
+	if req.Format == "" {
+		req.Format = FormatCSV
+	}
+	if value := query.Get("columns"); value != "" {
+		req.Columns = strings.Split(value, ",")
+	}
+	for name, t := range map[string]*time.Time{"from": &req.CreatedAtFrom, "to": &req.CreatedAtTo} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+		*t = parsed
+	}
+
+	ew := &exportResponseWriter{
+		ResponseWriter: w,
+		contentType:    ExportContentTypes[req.Format],
+		filename:       fmt.Sprintf("extracted-data-%s.%s", req.Kind, req.Format),
+	}
+	err := h.service.ExportExtractedData(ctx, ew, req)
+	switch {
+	case err == nil:
+		// An export without rows nor header is still a successful export.
+		ew.writeHeader()
+	case ew.wroteHeader:
+		// The status is sent already, the client must see the export as truncated rather than complete.
+		otelutil.RecordError(trace.SpanFromContext(ctx), err)
+		panic(http.ErrAbortHandler)
+	default:
+		switch err {
+		case ErrInvalidExportKind, ErrInvalidExportFormat, ErrInvalidExportColumn:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+	}
+}
+
+// exportResponseWriter sends the export headers with its first bytes, so that
+// the errors raised before can still be rendered as JSON.
+type exportResponseWriter struct {
+	http.ResponseWriter
+	contentType string
+	filename    string
+	wroteHeader bool
+}
+
+func (w *exportResponseWriter) Write(b []byte) (int, error) {
+	w.writeHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *exportResponseWriter) writeHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Set("Content-Type", w.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,286 @@
+package dataprotection
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/privacy"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+// Erasure modes. Erasing removes the people of a subject, while redacting keeps their job titles
+// and replaces their identifiers.
+const (
+	ModeErase  = "erase"
+	ModeRedact = "redact"
+)
+
+var (
+	ErrSubjectRequired = errors.New("an email, a name or a phone is required")
+	ErrInvalidMode     = errors.New("mode must be erase or redact")
+	ErrInvalidLimit    = errors.New("limit cannot be negative")
+)
+
+// Service represents the data protection service interface, honoring the access and erasure requests
+// of the people found in the extracted data. Every method works in the workspace of the context, and
+// every access to the data of a subject is recorded in the audit log.
+type Service interface {
+	// SearchSubject returns the people of a subject found in the runs, the watch changes and the
+	// webhook deliveries, in this order and most recent first.
+	SearchSubject(ctx context.Context, subject Subject) ([]Record, error)
+	// EraseSubject erases or redacts a subject from the runs, the watch changes and the webhook deliveries,
+	// and suppresses their identifiers so that they are not ingested again.
+	EraseSubject(ctx context.Context, subject Subject, mode string) (*privacy.AuditEntry, error)
+	ListSuppressions(ctx context.Context) ([]privacy.Suppression, error)
+	DeleteSuppression(ctx context.Context, id uint64) error
+	ListAuditEntries(ctx context.Context, limit int) ([]privacy.AuditEntry, error)
+	// Suppressed tells whether a person holds a suppressed identifier.
+	Suppressed(ctx context.Context, person people.Person) (bool, error)
+}
+
+// NewService returns a new instance of the data protection service.
+func NewService(
+	l log.Logger,
+	extractedDataRepo extracteddata.Repository,
+	watchesRepo watches.Repository,
+	webhooksRepo webhooks.Repository,
+	privacyRepo privacy.Repository,
+) Service {
+	return &service{
+		l:                 l,
+		extractedDataRepo: extractedDataRepo,
+		watchesRepo:       watchesRepo,
+		webhooksRepo:      webhooksRepo,
+		privacyRepo:       privacyRepo,
+	}
+}
+
+type service struct {
+	l                 log.Logger
+	extractedDataRepo extracteddata.Repository
+	watchesRepo       watches.Repository
+	webhooksRepo      webhooks.Repository
+	privacyRepo       privacy.Repository
+}
+
+// SearchSubject returns the people of a subject. The identifiers only matching a value, like the email
+// field changes of a diff, count in the audit log but return no record.
+func (s *service) SearchSubject(ctx context.Context, subject Subject) ([]Record, error) {
+	subject.normalize()
+	if subject.empty() {
+		return nil, ErrSubjectRequired
+	}
+	workspaceID := workspaces.IDFromContext(ctx)
+	entry := &privacy.AuditEntry{Action: privacy.ActionSearch}
+
+	records := []Record{}
+	runs, err := s.extractedDataRepo.Find(ctx, subject.search(workspaceID))
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		_, found := subject.scrubPeople(run.People, "")
+		if len(found) == 0 {
+			continue
+		}
+		entry.Runs++
+		for _, person := range found {
+			records = append(records, Record{Source: SourceRun, ID: run.ID, URL: run.URL, CreatedAt: run.CreatedAt, Person: person})
+		}
+	}
+
+	changes, err := s.watchesRepo.FindChanges(ctx, watches.ChangeSearch{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		_, found, matched, err := subject.scrubJSON(change.Diff, "")
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		entry.WatchChanges++
+		for _, person := range found {
+			records = append(records, Record{Source: SourceWatchChange, ID: change.ID, URL: change.URL, CreatedAt: change.CreatedAt, Person: person})
+		}
+	}
+
+	deliveries, err := s.webhooksRepo.FindDeliveries(ctx, webhooks.DeliverySearch{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+	for _, delivery := range deliveries {
+		_, found, matched, err := subject.scrubJSON(delivery.Payload, "")
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		entry.WebhookDeliveries++
+		for _, person := range found {
+			records = append(records, Record{Source: SourceWebhookDelivery, ID: delivery.ID, CreatedAt: delivery.CreatedAt, Person: person})
+		}
+	}
+
+	if _, err := s.audit(ctx, entry, subject.hashes()); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// EraseSubject erases or redacts a subject. The identifiers are suppressed first, so that the
+// extractions running meanwhile do not ingest the subject again.
+func (s *service) EraseSubject(ctx context.Context, subject Subject, mode string) (*privacy.AuditEntry, error) {
+	subject.normalize()
+	if subject.empty() {
+		return nil, ErrSubjectRequired
+	}
+	entry := &privacy.AuditEntry{}
+	switch mode {
+	case "", ModeErase:
+		mode = ModeErase
+		entry.Action = privacy.ActionErase
+	case ModeRedact:
+		entry.Action = privacy.ActionRedact
+	default:
+		return nil, ErrInvalidMode
+	}
+	workspaceID := workspaces.IDFromContext(ctx)
+
+	for _, identifier := range subject.identifiers() {
+		if _, err := s.privacyRepo.InsertSuppression(ctx, &privacy.Suppression{
+			WorkspaceID: workspaceID,
+			Kind:        identifier[0],
+			Hash:        privacy.Hash(identifier[0], identifier[1]),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	runs, err := s.extractedDataRepo.Find(ctx, subject.search(workspaceID))
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		scrubbed, found := subject.scrubPeople(run.People, mode)
+		if len(found) == 0 {
+			continue
+		}
+		run.People = scrubbed
+		// A run deleted meanwhile has no people anymore.
+		if err := s.extractedDataRepo.UpdateEntities(ctx, &run); err != nil && err != extracteddata.ErrNotFound {
+			return nil, err
+		}
+		entry.Runs++
+	}
+
+	changes, err := s.watchesRepo.FindChanges(ctx, watches.ChangeSearch{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		diff, _, matched, err := subject.scrubJSON(change.Diff, mode)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		change.Diff = diff
+		if err := s.watchesRepo.UpdateChangeDiff(ctx, &change); err != nil && err != watches.ErrNotFound {
+			return nil, err
+		}
+		entry.WatchChanges++
+	}
+
+	deliveries, err := s.webhooksRepo.FindDeliveries(ctx, webhooks.DeliverySearch{WorkspaceID: workspaceID})
+	if err != nil {
+		return nil, err
+	}
+	for _, delivery := range deliveries {
+		payload, _, matched, err := subject.scrubJSON(delivery.Payload, mode)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		delivery.Payload = payload
+		if err := s.webhooksRepo.UpdateDeliveryPayload(ctx, &delivery); err != nil && err != webhooks.ErrDeliveryNotFound {
+			return nil, err
+		}
+		entry.WebhookDeliveries++
+	}
+
+	return s.audit(ctx, entry, subject.hashes())
+}
+
+// ListSuppressions returns every suppression, most recent first.
+func (s *service) ListSuppressions(ctx context.Context) ([]privacy.Suppression, error) {
+	return s.privacyRepo.FindSuppressions(ctx, privacy.SuppressionSearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+	})
+}
+
+// DeleteSuppression lifts a suppression, so that its identifier can be ingested again.
+func (s *service) DeleteSuppression(ctx context.Context, id uint64) error {
+	workspaceID := workspaces.IDFromContext(ctx)
+	suppressions, err := s.privacyRepo.FindSuppressions(ctx, privacy.SuppressionSearch{WorkspaceID: workspaceID, ID: id})
+	if err != nil {
+		return err
+	}
+	if len(suppressions) == 0 {
+		return privacy.ErrNotFound
+	}
+	if err := s.privacyRepo.DeleteSuppression(ctx, workspaceID, id); err != nil {
+		return err
+	}
+	_, err = s.audit(ctx, &privacy.AuditEntry{Action: privacy.ActionUnsuppress}, []string{suppressions[0].Hash})
+	return err
+}
+
+// ListAuditEntries returns the most recent entries of the audit log, every entry if limit is zero.
+func (s *service) ListAuditEntries(ctx context.Context, limit int) ([]privacy.AuditEntry, error) {
+	if limit < 0 {
+		return nil, ErrInvalidLimit
+	}
+	return s.privacyRepo.FindAuditEntries(ctx, privacy.AuditSearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		Limit:       limit,
+	})
+}
+
+// Suppressed tells whether a person holds a suppressed identifier.
+func (s *service) Suppressed(ctx context.Context, person people.Person) (bool, error) {
+	hashes := privacy.PersonHashes(person)
+	if len(hashes) == 0 {
+		return false, nil
+	}
+	suppressions, err := s.privacyRepo.FindSuppressions(ctx, privacy.SuppressionSearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		Hashes:      hashes,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(suppressions) > 0, nil
+}
+
+// audit records an action in the audit log, along with the API key making it.
+func (s *service) audit(ctx context.Context, entry *privacy.AuditEntry, subjectHashes []string) (*privacy.AuditEntry, error) {
+	entry.WorkspaceID = workspaces.IDFromContext(ctx)
+	entry.SubjectHashes = subjectHashes
+	if apiKey := apikeys.FromContext(ctx); apiKey != nil {
+		entry.APIKeyID = apiKey.ID
+	}
+	return s.privacyRepo.InsertAuditEntry(ctx, entry)
+}
@@ -0,0 +1,250 @@
+package dataprotection
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/privacy"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+var (
+	jane = people.Person{FullName: "Jane Doe", JobTitle: "CTO", Contact: people.Contact{Email: "jane@acme.test", Phone: "+1 (555) 010-2030"}}
+	john = people.Person{FullName: "John Smith", JobTitle: "CEO", Contact: people.Contact{Email: "john@acme.test"}}
+)
+
+// fixture holds a run of Jane and John, a watch change adding Jane and holding her email in a field change,
+// and a webhook delivery of the run.
+type fixture struct {
+	service           Service
+	extractedDataRepo extracteddata.Repository
+	watchesRepo       watches.Repository
+	webhooksRepo      webhooks.Repository
+	privacyRepo       privacy.Repository
+	run               *extracteddata.ExtractedData
+	change            *watches.Change
+	delivery          *webhooks.Delivery
+}
+
+func newFixture(t *testing.T, ctx context.Context) *fixture {
+	t.Helper()
+	f := &fixture{
+		extractedDataRepo: extracteddata.NewMemoryRepository(),
+		watchesRepo:       watches.NewMemoryRepository(),
+		webhooksRepo:      webhooks.NewMemoryRepository(),
+		privacyRepo:       privacy.NewMemoryRepository(),
+	}
+	f.service = NewService(log.NewNopLogger(), f.extractedDataRepo, f.watchesRepo, f.webhooksRepo, f.privacyRepo)
+	workspaceID := workspaces.IDFromContext(ctx)
+
+	var err error
+	f.run, err = f.extractedDataRepo.Insert(ctx, &extracteddata.ExtractedData{
+		WorkspaceID: workspaceID,
+		URL:         "https://acme.test/team",
+		People:      []people.Person{jane, john},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watch, err := f.watchesRepo.Insert(ctx, &watches.Watch{WorkspaceID: workspaceID, URL: f.run.URL, Schedule: "@every 6h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := json.Marshal(map[string]any{
+		"url": f.run.URL,
+		"people": map[string]any{
+			"added": []people.Person{jane},
+			"changed": []map[string]any{{
+				"from":   john,
+				"to":     john,
+				"fields": []map[string]any{{"field": "contact.email", "from": "j.doe@old.test", "to": "JANE@acme.test"}},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.change, err = f.watchesRepo.InsertChange(ctx, &watches.Change{WatchID: watch.ID, WorkspaceID: workspaceID, URL: f.run.URL, Diff: diff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription, err := f.webhooksRepo.InsertSubscription(ctx, &webhooks.Subscription{WorkspaceID: workspaceID, URL: "https://hooks.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(map[string]any{"type": webhooks.EventExtractionCompleted, "data": f.run})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.delivery, err = f.webhooksRepo.InsertDelivery(ctx, &webhooks.Delivery{
+		SubscriptionID: subscription.ID,
+		WorkspaceID:    workspaceID,
+		Event:          webhooks.EventExtractionCompleted,
+		Payload:        payload,
+		Status:         webhooks.StatusPending,
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestSearchSubject(t *testing.T) {
+	ctx := apikeys.WithAPIKey(workspaces.WithID(context.Background(), "acme"), &apikeys.APIKey{ID: 7})
+	f := newFixture(t, ctx)
+
+	if _, err := f.service.SearchSubject(ctx, Subject{Email: " "}); err != ErrSubjectRequired {
+		t.Errorf("err = %v, want %v", err, ErrSubjectRequired)
+	}
+
+	records, err := f.service.SearchSubject(ctx, Subject{Phone: "1-555-010-2030"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []string{}
+	for _, record := range records {
+		if record.Person.FullName != jane.FullName {
+			t.Errorf("record = %+v, want Jane", record)
+		}
+		sources = append(sources, record.Source)
+	}
+	if got, want := strings.Join(sources, ","), "run,watch_change,webhook_delivery"; got != want {
+		t.Errorf("sources = %s, want %s", got, want)
+	}
+
+	entries, err := f.service.ListAuditEntries(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v, want the search", entries)
+	}
+	if got := entries[0]; got.Action != privacy.ActionSearch || got.APIKeyID != 7 || got.Runs != 1 || got.WatchChanges != 1 || got.WebhookDeliveries != 1 ||
+		len(got.SubjectHashes) != 1 || got.SubjectHashes[0] != privacy.Hash(privacy.KindPhone, jane.Contact.Phone) {
+		t.Errorf("entry = %+v", got)
+	}
+}
+
+func TestEraseSubject(t *testing.T) {
+	for _, mode := range []string{ModeErase, ModeRedact} {
+		t.Run(mode, func(t *testing.T) {
+			ctx := workspaces.WithID(context.Background(), "acme")
+			f := newFixture(t, ctx)
+
+			if _, err := f.service.EraseSubject(ctx, Subject{Email: "jane@acme.test"}, "forget"); err != ErrInvalidMode {
+				t.Errorf("err = %v, want %v", err, ErrInvalidMode)
+			}
+
+			entry, err := f.service.EraseSubject(ctx, Subject{Email: "Jane@Acme.test"}, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Action != mode || entry.Runs != 1 || entry.WatchChanges != 1 || entry.WebhookDeliveries != 1 {
+				t.Errorf("entry = %+v", entry)
+			}
+
+			// The run keeps John, and Jane's job title when redacted.
+			run, err := f.extractedDataRepo.GetByID(ctx, "acme", f.run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []people.Person{john}
+			if mode == ModeRedact {
+				want = []people.Person{{FullName: redacted, JobTitle: jane.JobTitle}, john}
+			}
+			if got, _ := json.Marshal(run.People); string(got) != string(mustMarshal(t, want)) {
+				t.Errorf("people = %s, want %s", got, mustMarshal(t, want))
+			}
+
+			// Nothing holds Jane's identifiers anymore.
+			changes, err := f.watchesRepo.FindChanges(ctx, watches.ChangeSearch{WorkspaceID: "acme"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			deliveries, err := f.webhooksRepo.FindDeliveries(ctx, webhooks.DeliverySearch{WorkspaceID: "acme"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, raw := range []json.RawMessage{changes[0].Diff, deliveries[0].Payload} {
+				lower := strings.ToLower(string(raw))
+				if strings.Contains(lower, "jane") || strings.Contains(lower, "555") {
+					t.Errorf("%s still holds Jane", raw)
+				}
+				if !strings.Contains(string(raw), "John Smith") {
+					t.Errorf("%s lost John", raw)
+				}
+			}
+			records, err := f.service.SearchSubject(ctx, Subject{Email: jane.Contact.Email, Phone: jane.Contact.Phone})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 0 {
+				t.Errorf("records = %+v, want none", records)
+			}
+
+			// Jane is not ingested again, until her suppression is lifted.
+			suppressed, err := f.service.Suppressed(ctx, people.Person{FullName: "J. Doe", Contact: people.Contact{Email: "JANE@acme.test"}})
+			if err != nil || !suppressed {
+				t.Errorf("suppressed = %t, %v, want true", suppressed, err)
+			}
+			suppressions, err := f.service.ListSuppressions(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(suppressions) != 1 || suppressions[0].Kind != privacy.KindEmail {
+				t.Fatalf("suppressions = %+v, want Jane's email", suppressions)
+			}
+			if err := f.service.DeleteSuppression(ctx, suppressions[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.service.DeleteSuppression(ctx, suppressions[0].ID); err != privacy.ErrNotFound {
+				t.Errorf("err = %v, want %v", err, privacy.ErrNotFound)
+			}
+			if suppressed, err := f.service.Suppressed(ctx, jane); err != nil || suppressed {
+				t.Errorf("suppressed = %t, %v, want false", suppressed, err)
+			}
+
+			entries, err := f.service.ListAuditEntries(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || entries[0].Action != privacy.ActionUnsuppress || entries[1].Action != privacy.ActionSearch {
+				t.Errorf("entries = %+v, want the unsuppression and the search", entries)
+			}
+		})
+	}
+}
+
+func TestSuppressedWithoutIdentifiers(t *testing.T) {
+	ctx := workspaces.WithID(context.Background(), "acme")
+	f := newFixture(t, ctx)
+	if _, err := f.service.EraseSubject(ctx, Subject{Name: "Jane Doe"}, ModeErase); err != nil {
+		t.Fatal(err)
+	}
+
+	// A person without identifiers matches no suppression, rather than all of them.
+	if suppressed, err := f.service.Suppressed(ctx, people.Person{JobTitle: "CTO"}); err != nil || suppressed {
+		t.Errorf("suppressed = %t, %v, want false", suppressed, err)
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,202 @@
+package dataprotection
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/privacy"
+)
+
+// redacted replaces the identifiers of the redacted people.
+const redacted = "[redacted]"
+
+// Subject identifies a data subject by any of their email, full name or phone. Emails and names match
+// regardless of case, and phones regardless of formatting.
+type Subject struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+// Sources of the records.
+const (
+	SourceRun             = "run"
+	SourceWatchChange     = "watch_change"
+	SourceWebhookDelivery = "webhook_delivery"
+)
+
+// Record is a person of a subject, found in a run or in the data derived from it.
+type Record struct {
+	Source    string        `json:"source" xml:"source"`
+	ID        uint64        `json:"id" xml:"id"`
+	URL       string        `json:"url,omitempty" xml:"url,omitempty"`
+	CreatedAt time.Time     `json:"created_at" xml:"created_at"`
+	Person    people.Person `json:"person" xml:"person"`
+}
+
+// normalize trims the identifiers, and keeps the digits of the phone.
+func (s *Subject) normalize() {
+	s.Email = strings.TrimSpace(s.Email)
+	s.Name = strings.TrimSpace(s.Name)
+	s.Phone = people.NormalizePhone(s.Phone)
+}
+
+func (s *Subject) empty() bool {
+	return s.Email == "" && s.Name == "" && s.Phone == ""
+}
+
+// search returns the search of the runs holding the subject.
+func (s *Subject) search(workspaceID string) extracteddata.Search {
+	return extracteddata.Search{
+		WorkspaceID: workspaceID,
+		PersonEmail: s.Email,
+		PersonName:  s.Name,
+		PersonPhone: s.Phone,
+	}
+}
+
+// identifiers returns the kinds and values of the identifiers of the subject.
+func (s *Subject) identifiers() [][2]string {
+	identifiers := [][2]string{}
+	for _, identifier := range [][2]string{
+		{privacy.KindEmail, s.Email},
+		{privacy.KindName, s.Name},
+		{privacy.KindPhone, s.Phone},
+	} {
+		if identifier[1] != "" {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	return identifiers
+}
+
+// hashes returns the hashes of the identifiers of the subject.
+func (s *Subject) hashes() []string {
+	hashes := []string{}
+	for _, identifier := range s.identifiers() {
+		hashes = append(hashes, privacy.Hash(identifier[0], identifier[1]))
+	}
+	return hashes
+}
+
+// matchesPerson tells whether a person is the subject.
+func (s *Subject) matchesPerson(person people.Person) bool {
+	return (s.Email != "" && strings.EqualFold(strings.TrimSpace(person.Contact.Email), s.Email)) ||
+		(s.Name != "" && strings.EqualFold(strings.TrimSpace(person.FullName), s.Name)) ||
+		(s.Phone != "" && people.NormalizePhone(person.Contact.Phone) == s.Phone)
+}
+
+// matchesValue tells whether a value is one of the identifiers of the subject.
+func (s *Subject) matchesValue(value string) bool {
+	return s.matchesPerson(people.Person{FullName: value, Contact: people.Contact{Email: value, Phone: value}})
+}
+
+// redactPerson keeps the job title of a person, and replaces their identifiers.
+func redactPerson(person people.Person) people.Person {
+	return people.Person{FullName: redacted, JobTitle: person.JobTitle}
+}
+
+// scrubPeople erases or redacts the subject from people, and returns the people of the subject found.
+func (s *Subject) scrubPeople(list []people.Person, mode string) ([]people.Person, []people.Person) {
+	scrubbed, found := []people.Person{}, []people.Person{}
+	for _, person := range list {
+		if !s.matchesPerson(person) {
+			scrubbed = append(scrubbed, person)
+			continue
+		}
+		found = append(found, person)
+		if mode == ModeRedact {
+			scrubbed = append(scrubbed, redactPerson(person))
+		}
+	}
+	return scrubbed, found
+}
+
+// scrubJSON erases or redacts the subject from arbitrary JSON, like the diffs of the watch changes and
+// the payloads of the webhook deliveries, and returns the people of the subject found. The JSON is only
+// inspected with an empty mode. It tells whether the subject was found at all, as a person or a value.
+func (s *Subject) scrubJSON(raw json.RawMessage, mode string) (json.RawMessage, []people.Person, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, false, err
+	}
+
+	sc := &scrubber{subject: s, mode: mode}
+	v, _ = sc.scrub(v)
+	if !sc.matched || mode == "" {
+		return raw, sc.found, sc.matched, nil
+	}
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return scrubbed, sc.found, true, nil
+}
+
+type scrubber struct {
+	subject *Subject
+	mode    string
+	found   []people.Person
+	matched bool
+}
+
+// scrub returns a value scrubbed, and whether it holds the subject: the subject itself, one of their
+// identifiers, or an object with a field holding them. Erasing drops these values from their arrays,
+// like the people of a run or the changes of a diff, and redacts the others.
+func (sc *scrubber) scrub(v any) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		if person, ok := asPerson(v); ok && sc.subject.matchesPerson(person) {
+			sc.found = append(sc.found, person)
+			sc.matched = true
+			return redactPerson(person), true
+		}
+		holds := false
+		for key, value := range v {
+			scrubbed, h := sc.scrub(value)
+			v[key] = scrubbed
+			holds = holds || h
+		}
+		return v, holds
+	case []any:
+		scrubbed := []any{}
+		for _, value := range v {
+			value, h := sc.scrub(value)
+			if h && sc.mode == ModeErase {
+				continue
+			}
+			scrubbed = append(scrubbed, value)
+		}
+		return scrubbed, false
+	case string:
+		if sc.subject.matchesValue(v) {
+			sc.matched = true
+			return redacted, true
+		}
+	}
+	return v, false
+}
+
+// asPerson decodes an object shaped like a person.
+func asPerson(v map[string]any) (people.Person, bool) {
+	_, hasFullName := v["full_name"]
+	_, hasContact := v["contact"].(map[string]any)
+	if !hasFullName || !hasContact {
+		return people.Person{}, false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return people.Person{}, false
+	}
+	var person people.Person
+	if err := json.Unmarshal(b, &person); err != nil {
+		return people.Person{}, false
+	}
+	return person, true
+}
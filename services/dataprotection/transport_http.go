@@ -0,0 +1,165 @@
+package dataprotection
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/privacy"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/services/auth"
+	"github.com/solher/toolbox/api"
+)
+
+// NewHTTPHandler returns a new HTTP handler for the data subject requests, the suppression list and
+// the audit log. The subjects are given in the request bodies, keeping their identifiers out of the
+// URLs and so of the access logs. The records of a subject render in the media type the Accept header
+// prefers, for their export.
+func NewHTTPHandler(service Service, negotiator *render.Negotiator) http.Handler {
+	h := &httpHandler{
+		service: service,
+		render:  negotiator,
+	}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Post("/subjects/search", h.SearchSubject)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Post("/subjects/erase", h.EraseSubject)
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Get("/suppressions", h.ListSuppressions)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Delete("/suppressions/{id}", h.DeleteSuppression)
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeAdmin),
+	).Get("/audit", h.ListAuditEntries)
+
+	return router
+}
+
+type httpHandler struct {
+	service Service
+	render  render.Renderer
+}
+
+func (h *httpHandler) SearchSubject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var subject Subject
+	if err := json.NewDecoder(r.Body).Decode(&subject); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		return
+	}
+
+	result, err := h.service.SearchSubject(ctx, subject)
+	if err != nil {
+		switch err {
+		case ErrSubjectRequired:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) EraseSubject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Subject
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		return
+	}
+
+	result, err := h.service.EraseSubject(ctx, req.Subject, req.Mode)
+	if err != nil {
+		switch err {
+		case ErrSubjectRequired, ErrInvalidMode:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := h.service.ListSuppressions(ctx)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) DeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	if err := h.service.DeleteSuppression(ctx, id); err != nil {
+		switch err {
+		case privacy.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *httpHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var (
+		limit int
+		err   error
+	)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+	}
+
+	result, err := h.service.ListAuditEntries(ctx, limit)
+	if err != nil {
+		switch err {
+		case ErrInvalidLimit:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
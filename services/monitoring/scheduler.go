@@ -0,0 +1,220 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// SchedulerConfig tunes the runs of the watches.
+type SchedulerConfig struct {
+	// PollInterval is the time between two lookups of the due watches.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of watches run per lookup.
+	BatchSize int
+	// Lease is the time a claimed batch has to run before other schedulers can claim its watches again.
+	Lease time.Duration
+}
+
+// Validate checks the watches can be polled.
+func (c SchedulerConfig) Validate() error {
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("invalid watch poll interval %s", c.PollInterval)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("invalid watch batch size %d", c.BatchSize)
+	}
+	if c.Lease <= 0 {
+		return fmt.Errorf("invalid watch lease %s", c.Lease)
+	}
+	return nil
+}
+
+// Scheduler runs the due watches, and notifies the changes found to their target.
+type Scheduler interface {
+	// Run runs the due watches every poll interval, until the context is canceled.
+	Run(ctx context.Context) error
+	// RunDue runs the watches due now, and returns how many were run.
+	RunDue(ctx context.Context) (int, error)
+}
+
+// NewScheduler returns a new scheduler. Several schedulers can share a repository,
+// each watch being claimed by a single one at a time.
+func NewScheduler(
+	l log.Logger,
+	dataExtractionService dataextraction.Service,
+	publisher dataextraction.Publisher,
+	watchesRepo watches.Repository,
+	config SchedulerConfig,
+) Scheduler {
+	return &scheduler{
+		l:                     l,
+		dataExtractionService: dataExtractionService,
+		publisher:             publisher,
+		watchesRepo:           watchesRepo,
+		config:                config,
+	}
+}
+
+type scheduler struct {
+	l                     log.Logger
+	dataExtractionService dataextraction.Service
+	publisher             dataextraction.Publisher
+	watchesRepo           watches.Repository
+	config                SchedulerConfig
+}
+
+func (s *scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+			s.l.Log("msg", "watch scheduling failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) RunDue(ctx context.Context) (int, error) {
+	due, err := s.watchesRepo.ClaimDue(ctx, time.Now().UTC(), s.config.Lease, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, watch := range due {
+		if err := s.run(ctx, &watch); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// run extracts a watched URL again, notifies the changes since the last run, and schedules the next one.
+// The failures of the run are recorded on the watch, only the repository errors are returned.
+func (s *scheduler) run(ctx context.Context, watch *watches.Watch) error {
+	// The run is made in the workspace of the watch, and attributed to it for the quotas.
+	ctx = workspaces.WithID(ctx, watch.WorkspaceID)
+	ctx = dataextraction.WithClientID(ctx, fmt.Sprintf("watch-%d", watch.ID))
+	// The schedules can be shorter than the cache freshness, the page is always extracted again.
+	ctx = dataextraction.WithRefresh(ctx)
+
+	runErr := s.extractAndNotify(ctx, watch)
+	if runErr != nil && ctx.Err() != nil {
+		// The scheduler is stopping, the watch runs again at the end of its lease.
+		return nil
+	}
+
+	now := time.Now().UTC()
+	watch.LastRunAt = &now
+	watch.LastError = ""
+	if runErr != nil {
+		watch.LastError = runErr.Error()
+	}
+	_, schedule, err := ParseSchedule(watch.Schedule)
+	if err != nil {
+		return err
+	}
+	watch.NextRunAt = schedule.Next(now).UTC()
+
+	s.l.Log(
+		"msg", "watch run",
+		"watch_id", watch.ID,
+		"url", watch.URL,
+		"run_id", watch.LastRunID,
+		"next_run_at", watch.NextRunAt,
+		"err", watch.LastError,
+	)
+
+	_, err = s.watchesRepo.UpdateRun(ctx, watch)
+	if err == watches.ErrNotFound {
+		// The watch was deleted during the run.
+		return nil
+	}
+	return err
+}
+
+// extractAndNotify extracts the watched URL, and notifies its diff with the last run of the watch.
+// The first run is compared to the run preceding it, if any.
+// The last run of the watch only moves once the change is notified, so that a failed notification is retried.
+func (s *scheduler) extractAndNotify(ctx context.Context, watch *watches.Watch) error {
+	extractedData, err := s.dataExtractionService.ExtractAndPersistFromURL(ctx, watch.URL)
+	if err != nil {
+		return err
+	}
+	if extractedData.ID == watch.LastRunID {
+		// The run was served from the cache, nothing changed.
+		return nil
+	}
+
+	diff, err := s.dataExtractionService.DiffExtractedData(ctx, watch.URL, watch.LastRunID, extractedData.ID)
+	switch err {
+	case nil:
+	case dataextraction.ErrNotEnoughRuns, dataextraction.ErrRunNotFound:
+		// Nothing to compare to, the run becomes the reference of the next one.
+		watch.LastRunID = extractedData.ID
+		return nil
+	default:
+		return err
+	}
+
+	if !diff.Empty() {
+		if err := s.notify(ctx, watch, diff); err != nil {
+			return err
+		}
+	}
+	watch.LastRunID = extractedData.ID
+	return nil
+}
+
+// notify sends a change to the target of its watch.
+func (s *scheduler) notify(ctx context.Context, watch *watches.Watch, diff *dataextraction.Diff) error {
+	rawDiff, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	change := &watches.Change{
+		WatchID:     watch.ID,
+		WorkspaceID: watch.WorkspaceID,
+		URL:         watch.URL,
+		FromID:      diff.FromID,
+		ToID:        diff.ToID,
+		Diff:        rawDiff,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	switch watch.Target {
+	case watches.TargetWebhook:
+		return s.publisher.Publish(ctx, webhooks.EventWatchChanged, change)
+	case watches.TargetFeed:
+		_, err := s.watchesRepo.InsertChange(ctx, change)
+		return err
+	default:
+		s.l.Log(
+			"msg", "watched url changed",
+			"watch_id", watch.ID,
+			"url", watch.URL,
+			"from_id", diff.FromID,
+			"to_id", diff.ToID,
+			"people_added", len(diff.People.Added),
+			"people_removed", len(diff.People.Removed),
+			"people_changed", len(diff.People.Changed),
+			"companies_added", len(diff.Companies.Added),
+			"companies_removed", len(diff.Companies.Removed),
+			"companies_changed", len(diff.Companies.Changed),
+		)
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/people"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+	"github.com/solher/hunterio-test/services/dataextraction"
+)
+
+// stubExtractionService persists a run holding the next title on each extraction,
+// and relies on the real service for the diffs.
+type stubExtractionService struct {
+	dataextraction.Service
+	repo     extracteddata.Repository
+	titles   []string
+	err      error
+	clientID string
+	refresh  bool
+}
+
+func (s *stubExtractionService) ExtractAndPersistFromURL(ctx context.Context, url string) (*extracteddata.ExtractedData, error) {
+	s.clientID = dataextraction.ClientIDFromContext(ctx)
+	s.refresh = dataextraction.RefreshFromContext(ctx)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.titles) == 0 {
+		// Served from the cache.
+		return s.repo.GetLastByURL(ctx, workspaces.IDFromContext(ctx), url)
+	}
+	title := s.titles[0]
+	s.titles = s.titles[1:]
+	return s.repo.Insert(ctx, &extracteddata.ExtractedData{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		URL:         url,
+		People:      []people.Person{{FullName: "Jane Doe", JobTitle: title}},
+	})
+}
+
+type stubPublisher struct {
+	events []string
+	data   []any
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event string, data any) error {
+	p.events = append(p.events, event)
+	p.data = append(p.data, data)
+	return nil
+}
+
+func TestSchedulerConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		config SchedulerConfig
+		valid  bool
+	}{
+		{SchedulerConfig{PollInterval: time.Second, BatchSize: 5, Lease: time.Minute}, true},
+		{SchedulerConfig{BatchSize: 5, Lease: time.Minute}, false},
+		{SchedulerConfig{PollInterval: time.Second, Lease: time.Minute}, false},
+		{SchedulerConfig{PollInterval: time.Second, BatchSize: 5}, false},
+	} {
+		if err := tc.config.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %t", tc.config, err, tc.valid)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	extractedDataRepo := extracteddata.NewMemoryRepository()
+	extraction := &stubExtractionService{
+		Service: dataextraction.NewService(log.NewNopLogger(), nil, nil, extractedDataRepo, nil),
+		repo:    extractedDataRepo,
+	}
+	publisher := &stubPublisher{}
+	watchesRepo := watches.NewMemoryRepository()
+	scheduler := NewScheduler(log.NewNopLogger(), extraction, publisher, watchesRepo, SchedulerConfig{
+		BatchSize: 10,
+		Lease:     time.Minute,
+	})
+
+	insertWatch := func(target string) *watches.Watch {
+		t.Helper()
+		watch, err := watchesRepo.Insert(ctx, &watches.Watch{
+			WorkspaceID: "acme",
+			URL:         "https://acme-robotics.test/" + target,
+			Schedule:    "@every 6h",
+			Target:      target,
+			NextRunAt:   time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return watch
+	}
+	// runWatch makes the watch due, and runs it.
+	runWatch := func(watch *watches.Watch) watches.Watch {
+		t.Helper()
+		found, err := watchesRepo.Find(ctx, watches.Search{WorkspaceID: watch.WorkspaceID, ID: watch.ID})
+		if err != nil || len(found) != 1 {
+			t.Fatalf("found %+v, %v", found, err)
+		}
+		found[0].NextRunAt = time.Now().UTC()
+		if _, err := watchesRepo.UpdateRun(ctx, &found[0]); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := scheduler.RunDue(ctx); err != nil || n != 1 {
+			t.Fatalf("ran %d watches, %v, want 1", n, err)
+		}
+		found, err = watchesRepo.Find(ctx, watches.Search{WorkspaceID: watch.WorkspaceID, ID: watch.ID})
+		if err != nil || len(found) != 1 {
+			t.Fatalf("found %+v, %v", found, err)
+		}
+		return found[0]
+	}
+
+	t.Run("Feed", func(t *testing.T) {
+		watch := insertWatch(watches.TargetFeed)
+		changes := func() []watches.Change {
+			t.Helper()
+			changes, err := watchesRepo.FindChanges(ctx, watches.ChangeSearch{WorkspaceID: "acme", WatchID: watch.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return changes
+		}
+
+		// The first run has nothing to compare to.
+		extraction.titles = []string{"CTO", "CTO", "CEO"}
+		first := runWatch(watch)
+		if first.LastRunID == 0 || first.LastRunAt == nil || first.LastError != "" || len(changes()) != 0 {
+			t.Fatalf("watch = %+v, changes = %+v", first, changes())
+		}
+		if next := time.Until(first.NextRunAt); next < 5*time.Hour || next > 6*time.Hour {
+			t.Errorf("next run in %s, want 6h", next)
+		}
+		if extraction.clientID != "watch-1" {
+			t.Errorf("client ID = %q, want watch-1", extraction.clientID)
+		}
+		if !extraction.refresh {
+			t.Error("expected the watch run to bypass the cache")
+		}
+
+		// The second run holds the same data.
+		second := runWatch(watch)
+		if second.LastRunID == first.LastRunID || len(changes()) != 0 {
+			t.Fatalf("watch = %+v, changes = %+v", second, changes())
+		}
+
+		third := runWatch(watch)
+		found := changes()
+		if len(found) != 1 || found[0].FromID != second.LastRunID || found[0].ToID != third.LastRunID {
+			t.Fatalf("changes = %+v, want the change from %d to %d", found, second.LastRunID, third.LastRunID)
+		}
+		var diff dataextraction.Diff
+		if err := json.Unmarshal(found[0].Diff, &diff); err != nil || len(diff.People.Changed) != 1 {
+			t.Errorf("diff = %s", found[0].Diff)
+		}
+
+		// A run served from the cache changes nothing.
+		cached := runWatch(watch)
+		if cached.LastRunID != third.LastRunID || len(changes()) != 1 {
+			t.Errorf("watch = %+v, changes = %+v", cached, changes())
+		}
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		watch := insertWatch(watches.TargetWebhook)
+
+		extraction.titles = []string{"Engineer", "CTO"}
+		runWatch(watch)
+		runWatch(watch)
+		if len(publisher.events) != 1 || publisher.events[0] != webhooks.EventWatchChanged {
+			t.Fatalf("events = %v, want a single %s", publisher.events, webhooks.EventWatchChanged)
+		}
+		if change, ok := publisher.data[0].(*watches.Change); !ok || change.WatchID != watch.ID {
+			t.Errorf("data = %+v", publisher.data[0])
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		watch := insertWatch(watches.TargetLog)
+
+		extraction.err = errors.New("page not found")
+		defer func() { extraction.err = nil }()
+		failed := runWatch(watch)
+		if failed.LastError != "page not found" || failed.LastRunID != 0 || !failed.NextRunAt.After(time.Now()) {
+			t.Errorf("watch = %+v, want the error recorded and the next run scheduled", failed)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/robfig/cron/v3"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+// minInterval is the shortest time allowed between two runs of a watch.
+const minInterval = time.Minute
+
+var (
+	ErrInvalidURL      = errors.New("url must be an absolute http or https url")
+	ErrInvalidSchedule = errors.New("schedule must be a cron expression or an interval of at least 1m, like 6h")
+	ErrInvalidTarget   = errors.New("target must be log, webhook or feed")
+)
+
+// Service represents the URL monitoring service interface.
+// Every method works in the workspace of the context.
+type Service interface {
+	CreateWatch(ctx context.Context, url string, schedule string, target string) (*watches.Watch, error)
+	ListWatches(ctx context.Context) ([]watches.Watch, error)
+	DeleteWatch(ctx context.Context, id uint64) error
+	ListChanges(ctx context.Context, watchID uint64, limit int) ([]watches.Change, error)
+}
+
+// NewService returns a new instance of the URL monitoring service.
+func NewService(
+	l log.Logger,
+	watchesRepo watches.Repository,
+) Service {
+	return &service{
+		l:           l,
+		watchesRepo: watchesRepo,
+	}
+}
+
+type service struct {
+	l           log.Logger
+	watchesRepo watches.Repository
+}
+
+// CreateWatch watches a URL on a schedule, notifying its changes to a target, the change feed if none is given.
+// The first run is due at the first occurrence of the schedule.
+func (s *service) CreateWatch(ctx context.Context, endpoint string, schedule string, target string) (*watches.Watch, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	schedule, parsed, err := ParseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	if target == "" {
+		target = watches.TargetFeed
+	}
+	if !slices.Contains(watches.Targets, target) {
+		return nil, ErrInvalidTarget
+	}
+
+	return s.watchesRepo.Insert(ctx, &watches.Watch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		URL:         endpoint,
+		Schedule:    schedule,
+		Target:      target,
+		NextRunAt:   parsed.Next(time.Now()).UTC(),
+	})
+}
+
+// ListWatches returns every watch.
+func (s *service) ListWatches(ctx context.Context) ([]watches.Watch, error) {
+	return s.watchesRepo.Find(ctx, watches.Search{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+	})
+}
+
+// DeleteWatch deletes a watch along with its changes.
+func (s *service) DeleteWatch(ctx context.Context, id uint64) error {
+	return s.watchesRepo.Delete(ctx, workspaces.IDFromContext(ctx), id)
+}
+
+// ListChanges returns the change feed, most recent first, optionally filtered by watch.
+func (s *service) ListChanges(ctx context.Context, watchID uint64, limit int) ([]watches.Change, error) {
+	workspaceID := workspaces.IDFromContext(ctx)
+
+	if watchID != 0 {
+		found, err := s.watchesRepo.Find(ctx, watches.Search{WorkspaceID: workspaceID, ID: watchID})
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, watches.ErrNotFound
+		}
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.watchesRepo.FindChanges(ctx, watches.ChangeSearch{
+		WorkspaceID: workspaceID,
+		WatchID:     watchID,
+		Limit:       limit,
+	})
+}
+
+// ParseSchedule parses a standard cron expression, a descriptor like @daily, or an interval like 6h.
+// It returns the schedule as stored, intervals being turned into @every descriptors.
+func ParseSchedule(schedule string) (string, cron.Schedule, error) {
+	schedule = strings.TrimSpace(schedule)
+	if interval, err := time.ParseDuration(schedule); err == nil {
+		schedule = "@every " + interval.String()
+	}
+
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return "", nil, ErrInvalidSchedule
+	}
+	if every, ok := parsed.(cron.ConstantDelaySchedule); ok && every.Delay < minInterval {
+		return "", nil, ErrInvalidSchedule
+	}
+	return schedule, parsed, nil
+}
@@ -0,0 +1,83 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     string
+		err      error
+	}{
+		{schedule: "6h", want: "@every 6h0m0s"},
+		{schedule: " @every 30m ", want: "@every 30m"},
+		{schedule: "0 9 * * 1-5", want: "0 9 * * 1-5"},
+		{schedule: "@daily", want: "@daily"},
+		{schedule: "30s", err: ErrInvalidSchedule},
+		{schedule: "@every 1s", err: ErrInvalidSchedule},
+		{schedule: "every monday", err: ErrInvalidSchedule},
+		{schedule: "", err: ErrInvalidSchedule},
+	}
+	for _, tt := range tests {
+		got, _, err := ParseSchedule(tt.schedule)
+		if err != tt.err || got != tt.want {
+			t.Errorf("ParseSchedule(%q) = %q, %v, want %q, %v", tt.schedule, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCreateWatch(t *testing.T) {
+	ctx := workspaces.WithID(context.Background(), "acme")
+	service := NewService(log.NewNopLogger(), watches.NewMemoryRepository())
+
+	tests := []struct {
+		url, schedule, target string
+		err                   error
+	}{
+		{url: "ftp://acme.test", schedule: "6h", err: ErrInvalidURL},
+		{url: "https://acme.test", schedule: "sometimes", err: ErrInvalidSchedule},
+		{url: "https://acme.test", schedule: "6h", target: "email", err: ErrInvalidTarget},
+	}
+	for _, tt := range tests {
+		if _, err := service.CreateWatch(ctx, tt.url, tt.schedule, tt.target); err != tt.err {
+			t.Errorf("CreateWatch(%q, %q, %q) err = %v, want %v", tt.url, tt.schedule, tt.target, err, tt.err)
+		}
+	}
+
+	before := time.Now()
+	watch, err := service.CreateWatch(ctx, "https://acme.test", "6h", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watch.WorkspaceID != "acme" || watch.Target != watches.TargetFeed || watch.Schedule != "@every 6h0m0s" {
+		t.Errorf("watch = %+v", watch)
+	}
+	if next := watch.NextRunAt.Sub(before); next < 6*time.Hour-time.Second || next > 6*time.Hour+time.Second {
+		t.Errorf("next run in %s, want 6h", next)
+	}
+
+	found, err := service.ListWatches(workspaces.WithID(context.Background(), "globex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("found %d watches in another workspace, want 0", len(found))
+	}
+
+	if _, err := service.ListChanges(ctx, watch.ID+1, 0); err != watches.ErrNotFound {
+		t.Errorf("err = %v, want %v", err, watches.ErrNotFound)
+	}
+	if err := service.DeleteWatch(ctx, watch.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteWatch(ctx, watch.ID); err != watches.ErrNotFound {
+		t.Errorf("err = %v, want %v", err, watches.ErrNotFound)
+	}
+}
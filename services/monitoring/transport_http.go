@@ -0,0 +1,148 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/watches"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/services/auth"
+	"github.com/solher/toolbox/api"
+)
+
+// NewHTTPHandler returns a new HTTP handler for the watches and their change feed.
+func NewHTTPHandler(service Service, negotiator *render.Negotiator) http.Handler {
+	h := &httpHandler{
+		service: service,
+		render:  negotiator,
+	}
+	// The diffs of the changes share no CSV columns.
+	changeMediaTypes := []string{render.MediaJSON, render.MediaXML, render.MediaNDJSON}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWatches),
+	).Post("/", h.CreateWatch)
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWatches),
+	).Get("/", h.ListWatches)
+	router.With(
+		negotiator.Negotiate(changeMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWatches),
+	).Get("/changes", h.ListChanges)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWatches),
+	).Delete("/{id}", h.DeleteWatch)
+	router.With(
+		negotiator.Negotiate(changeMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWatches),
+	).Get("/{id}/changes", h.ListChanges)
+
+	return router
+}
+
+type httpHandler struct {
+	service Service
+	render  render.Renderer
+}
+
+func (h *httpHandler) CreateWatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		URL      string `json:"url"`
+		Schedule string `json:"schedule"`
+		Target   string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		return
+	}
+
+	result, err := h.service.CreateWatch(ctx, req.URL, req.Schedule, req.Target)
+	if err != nil {
+		switch err {
+		case ErrInvalidURL, ErrInvalidSchedule, ErrInvalidTarget:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusCreated, result)
+}
+
+func (h *httpHandler) ListWatches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := h.service.ListWatches(ctx)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	if err := h.service.DeleteWatch(ctx, id); err != nil {
+		switch err {
+		case watches.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListChanges serves the change feed of the workspace, or of a single watch under /{id}/changes.
+func (h *httpHandler) ListChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var watchID uint64
+	var limit int
+	var err error
+	if value := chi.URLParam(r, "id"); value != "" {
+		if watchID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+	}
+
+	result, err := h.service.ListChanges(ctx, watchID, limit)
+	if err != nil {
+		switch err {
+		case watches.ErrNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
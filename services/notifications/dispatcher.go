@@ -0,0 +1,215 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/webhooks"
+)
+
+// Headers of the requests made to the subscriptions.
+const (
+	EventHeader     = "X-Hunterio-Event"
+	EventIDHeader   = "X-Hunterio-Event-ID"
+	DeliveryHeader  = "X-Hunterio-Delivery"
+	SignatureHeader = "X-Hunterio-Signature"
+)
+
+// DispatcherConfig tunes the sending of the deliveries.
+type DispatcherConfig struct {
+	// PollInterval is the time between two lookups of the due deliveries.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries sent per lookup.
+	BatchSize int
+	// Timeout bounds each request made to a subscription.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is marked as failed.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled at each attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Validate checks the deliveries can be polled.
+func (c DispatcherConfig) Validate() error {
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("invalid webhook poll interval %s", c.PollInterval)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("invalid webhook batch size %d", c.BatchSize)
+	}
+	return nil
+}
+
+// Dispatcher sends the pending deliveries to their subscriptions.
+type Dispatcher interface {
+	// Run dispatches the due deliveries every poll interval, until the context is canceled.
+	Run(ctx context.Context) error
+	// Dispatch sends the deliveries due now, and returns how many were attempted.
+	Dispatch(ctx context.Context) (int, error)
+}
+
+// NewDispatcher returns a new dispatcher. Several dispatchers can share a repository,
+// each delivery being claimed by a single one at a time.
+func NewDispatcher(
+	l log.Logger,
+	httpCli *http.Client,
+	webhooksRepo webhooks.Repository,
+	config DispatcherConfig,
+) Dispatcher {
+	return &dispatcher{
+		l:            l,
+		httpCli:      httpCli,
+		webhooksRepo: webhooksRepo,
+		config:       config,
+	}
+}
+
+type dispatcher struct {
+	l            log.Logger
+	httpCli      *http.Client
+	webhooksRepo webhooks.Repository
+	config       DispatcherConfig
+}
+
+func (d *dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			d.l.Log("msg", "webhook dispatch failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *dispatcher) Dispatch(ctx context.Context) (int, error) {
+	// The deliveries are sent one after the other, the lease must outlast the whole batch.
+	lease := d.config.Timeout * time.Duration(d.config.BatchSize+1)
+	deliveries, err := d.webhooksRepo.ClaimDeliveries(ctx, time.Now().UTC(), lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, delivery := range deliveries {
+		if err := d.send(ctx, &delivery); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// send makes an attempt to deliver an event, and records its outcome.
+func (d *dispatcher) send(ctx context.Context, delivery *webhooks.Delivery) error {
+	subscriptions, err := d.webhooksRepo.FindSubscriptions(ctx, webhooks.SubscriptionSearch{
+		WorkspaceID: delivery.WorkspaceID,
+		ID:          delivery.SubscriptionID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		// The subscription was deleted along with its deliveries after the claim.
+		return nil
+	}
+	subscription := subscriptions[0]
+
+	delivery.Attempts++
+	delivery.ResponseStatus, err = d.post(ctx, &subscription, delivery)
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		delivery.Status = webhooks.StatusSucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = webhooks.StatusFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	d.l.Log(
+		"msg", "webhook delivery attempted",
+		"delivery_id", delivery.ID,
+		"subscription_id", delivery.SubscriptionID,
+		"event", delivery.Event,
+		"attempt", delivery.Attempts,
+		"status", delivery.Status,
+		"response_status", delivery.ResponseStatus,
+		"err", delivery.LastError,
+	)
+
+	_, err = d.webhooksRepo.UpdateDelivery(ctx, delivery)
+	return err
+}
+
+// post sends the payload of a delivery to its subscription, and returns the response status.
+// Any status other than 2xx is an error.
+func (d *dispatcher) post(ctx context.Context, subscription *webhooks.Subscription, delivery *webhooks.Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.httpCli.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt, after the given number of attempts.
+func (d *dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.Backoff
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+// Sign returns the signature header of a payload sent at a given time: the Unix timestamp and the
+// hex encoded HMAC-SHA256 of "<timestamp>.<payload>" keyed with the subscription secret.
+// Receivers recompute it to authenticate the request, and check the timestamp to refuse replays.
+func Sign(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
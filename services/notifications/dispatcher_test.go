@@ -0,0 +1,180 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestDispatch(t *testing.T) {
+	ctx := workspaces.WithID(context.Background(), "acme")
+
+	// The endpoint fails until it has been called failures times.
+	var calls, failures atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := r.Header.Get(SignatureHeader)
+		timestamp, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+		at, _ := strconv.ParseInt(timestamp, 10, 64)
+		if signature != Sign("0123456789abcdef", time.Unix(at, 0), body) {
+			t.Errorf("signature %q does not match", signature)
+		}
+		if r.Header.Get(EventHeader) != webhooks.EventExtractionCompleted || r.Header.Get(EventIDHeader) == "" {
+			t.Errorf("headers = %v", r.Header)
+		}
+
+		if calls.Add(1) <= failures.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := webhooks.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), repo)
+	dispatcher := NewDispatcher(log.NewNopLogger(), server.Client(), repo, DispatcherConfig{
+		BatchSize:   10,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+		MaxBackoff:  time.Hour,
+	})
+	if _, err := service.CreateSubscription(ctx, server.URL, "0123456789abcdef", nil); err != nil {
+		t.Fatal(err)
+	}
+	lastDelivery := func() webhooks.Delivery {
+		t.Helper()
+		deliveries, err := service.ListDeliveries(ctx, 0, "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deliveries[0]
+	}
+	// dispatchDue makes the pending deliveries due, then dispatches them.
+	dispatchDue := func() {
+		t.Helper()
+		deliveries, err := repo.FindDeliveries(ctx, webhooks.DeliverySearch{WorkspaceID: "acme", Status: webhooks.StatusPending})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, delivery := range deliveries {
+			delivery.NextAttemptAt = time.Now().Add(-time.Second)
+			if _, err := repo.UpdateDelivery(ctx, &delivery); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := dispatcher.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Succeeded", func(t *testing.T) {
+		if err := service.Publish(ctx, webhooks.EventExtractionCompleted, nil); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := dispatcher.Dispatch(ctx); err != nil || n != 1 {
+			t.Fatalf("dispatched %d, %v, want 1", n, err)
+		}
+		delivery := lastDelivery()
+		if delivery.Status != webhooks.StatusSucceeded || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent || delivery.DeliveredAt == nil {
+			t.Errorf("delivery = %+v, want a successful one", delivery)
+		}
+	})
+
+	t.Run("Retried", func(t *testing.T) {
+		calls.Store(0)
+		failures.Store(1)
+		if err := service.Publish(ctx, webhooks.EventExtractionCompleted, nil); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if _, err := dispatcher.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		delivery := lastDelivery()
+		if delivery.Status != webhooks.StatusPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusServiceUnavailable {
+			t.Errorf("delivery = %+v, want a pending one", delivery)
+		}
+		if wait := delivery.NextAttemptAt.Sub(start); wait < time.Minute || wait > time.Minute+time.Second {
+			t.Errorf("next attempt in %s, want 1m", wait)
+		}
+
+		// The delivery is not due yet.
+		if n, err := dispatcher.Dispatch(ctx); err != nil || n != 0 {
+			t.Fatalf("dispatched %d, %v, want 0", n, err)
+		}
+		dispatchDue()
+		if delivery := lastDelivery(); delivery.Status != webhooks.StatusSucceeded || delivery.Attempts != 2 {
+			t.Errorf("delivery = %+v, want a successful one", delivery)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		calls.Store(0)
+		failures.Store(100)
+		if err := service.Publish(ctx, webhooks.EventExtractionCompleted, nil); err != nil {
+			t.Fatal(err)
+		}
+		for range 3 {
+			dispatchDue()
+		}
+		delivery := lastDelivery()
+		if delivery.Status != webhooks.StatusFailed || delivery.Attempts != 3 || delivery.LastError == "" {
+			t.Errorf("delivery = %+v, want a failed one", delivery)
+		}
+
+		// The replay is sent again.
+		failures.Store(0)
+		if _, err := service.ReplayDelivery(ctx, delivery.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dispatcher.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if replay := lastDelivery(); replay.ReplayOf != delivery.ID || replay.Status != webhooks.StatusSucceeded {
+			t.Errorf("replay = %+v, want a successful one", replay)
+		}
+	})
+}
+
+func TestDispatcherConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		config DispatcherConfig
+		valid  bool
+	}{
+		{DispatcherConfig{PollInterval: time.Second, BatchSize: 10}, true},
+		{DispatcherConfig{BatchSize: 10}, false},
+		{DispatcherConfig{PollInterval: -time.Second, BatchSize: 10}, false},
+		{DispatcherConfig{PollInterval: time.Second}, false},
+	} {
+		if err := tc.config.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %t", tc.config, err, tc.valid)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &dispatcher{config: DispatcherConfig{Backoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}}
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		5:  5 * time.Minute,
+		50: 5 * time.Minute,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff after %d attempts = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+const (
+	// secretPrefix starts every generated secret.
+	secretPrefix = "whsec_"
+	// minSecretLength is the length under which a given secret is deemed too weak to sign.
+	minSecretLength = 16
+	// eventIDPrefix starts every event ID.
+	eventIDPrefix = "evt_"
+)
+
+var (
+	ErrInvalidURL    = errors.New("url must be an absolute http or https url")
+	ErrInvalidEvent  = errors.New("events must be extraction.completed, extraction.failed or watch.changed")
+	ErrInvalidSecret = errors.New("secret must be at least 16 characters long")
+)
+
+// CreatedSubscription is a subscription along with its secret, only available at creation.
+type CreatedSubscription struct {
+	webhooks.Subscription
+	Secret string `json:"secret" xml:"secret"`
+}
+
+// Event is the body of the requests made to the subscriptions.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	WorkspaceID string    `json:"workspace_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Data        any       `json:"data"`
+}
+
+// Service represents the webhook notifications service interface.
+// Every method works in the workspace of the context.
+type Service interface {
+	CreateSubscription(ctx context.Context, url string, secret string, events []string) (*CreatedSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error)
+	DeleteSubscription(ctx context.Context, id uint64) error
+	ListDeliveries(ctx context.Context, subscriptionID uint64, status string, limit int) ([]webhooks.Delivery, error)
+	ReplayDelivery(ctx context.Context, id uint64) (*webhooks.Delivery, error)
+	Publish(ctx context.Context, event string, data any) error
+}
+
+// NewService returns a new instance of the webhook notifications service.
+func NewService(
+	l log.Logger,
+	webhooksRepo webhooks.Repository,
+) Service {
+	return &service{
+		l:            l,
+		webhooksRepo: webhooksRepo,
+	}
+}
+
+type service struct {
+	l            log.Logger
+	webhooksRepo webhooks.Repository
+}
+
+// CreateSubscription subscribes an endpoint to events, every event if none is given.
+// A secret is generated if none is given.
+func (s *service) CreateSubscription(ctx context.Context, endpoint string, secret string, events []string) (*CreatedSubscription, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	for _, event := range events {
+		if !slices.Contains(webhooks.Events, event) {
+			return nil, ErrInvalidEvent
+		}
+	}
+	switch {
+	case secret == "":
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		secret = secretPrefix + hex.EncodeToString(b)
+	case len(secret) < minSecretLength:
+		return nil, ErrInvalidSecret
+	}
+
+	subscription, err := s.webhooksRepo.InsertSubscription(ctx, &webhooks.Subscription{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		URL:         endpoint,
+		Secret:      secret,
+		Events:      events,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedSubscription{Subscription: *subscription, Secret: secret}, nil
+}
+
+// ListSubscriptions returns every subscription.
+func (s *service) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	return s.webhooksRepo.FindSubscriptions(ctx, webhooks.SubscriptionSearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+	})
+}
+
+// DeleteSubscription deletes a subscription along with its deliveries.
+func (s *service) DeleteSubscription(ctx context.Context, id uint64) error {
+	return s.webhooksRepo.DeleteSubscription(ctx, workspaces.IDFromContext(ctx), id)
+}
+
+// ListDeliveries returns the delivery log, most recent first, optionally filtered by subscription and status.
+func (s *service) ListDeliveries(ctx context.Context, subscriptionID uint64, status string, limit int) ([]webhooks.Delivery, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.webhooksRepo.FindDeliveries(ctx, webhooks.DeliverySearch{
+		WorkspaceID:    workspaces.IDFromContext(ctx),
+		SubscriptionID: subscriptionID,
+		Status:         status,
+		Limit:          limit,
+	})
+}
+
+// ReplayDelivery sends the event of a delivery again, as a new delivery.
+func (s *service) ReplayDelivery(ctx context.Context, id uint64) (*webhooks.Delivery, error) {
+	deliveries, err := s.webhooksRepo.FindDeliveries(ctx, webhooks.DeliverySearch{
+		WorkspaceID: workspaces.IDFromContext(ctx),
+		ID:          id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, webhooks.ErrDeliveryNotFound
+	}
+	delivery := deliveries[0]
+
+	return s.webhooksRepo.InsertDelivery(ctx, &webhooks.Delivery{
+		SubscriptionID: delivery.SubscriptionID,
+		WorkspaceID:    delivery.WorkspaceID,
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         webhooks.StatusPending,
+		ReplayOf:       delivery.ID,
+		NextAttemptAt:  time.Now().UTC(),
+	})
+}
+
+// Publish queues an event for delivery to the subscriptions wanting it.
+// The deliveries are sent asynchronously by the dispatcher.
+func (s *service) Publish(ctx context.Context, event string, data any) error {
+	workspaceID := workspaces.IDFromContext(ctx)
+
+	subscriptions, err := s.webhooksRepo.FindSubscriptions(ctx, webhooks.SubscriptionSearch{WorkspaceID: workspaceID})
+	if err != nil {
+		return err
+	}
+	subscriptions = slices.DeleteFunc(subscriptions, func(subscription webhooks.Subscription) bool {
+		return !subscription.Matches(event)
+	})
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	eventID := eventIDPrefix + hex.EncodeToString(b)
+	now := time.Now().UTC()
+	payload, err := json.Marshal(Event{
+		ID:          eventID,
+		Type:        event,
+		WorkspaceID: workspaceID,
+		CreatedAt:   now,
+		Data:        data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		_, err := s.webhooksRepo.InsertDelivery(ctx, &webhooks.Delivery{
+			SubscriptionID: subscription.ID,
+			WorkspaceID:    workspaceID,
+			EventID:        eventID,
+			Event:          event,
+			Payload:        payload,
+			Status:         webhooks.StatusPending,
+			NextAttemptAt:  now,
+		})
+		switch {
+		case err == webhooks.ErrSubscriptionNotFound:
+			// The subscription was deleted in the meantime, it does not want the event anymore.
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
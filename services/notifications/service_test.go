@@ -0,0 +1,92 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	service := NewService(log.NewNopLogger(), webhooks.NewMemoryRepository())
+
+	tests := []struct {
+		url    string
+		secret string
+		events []string
+		want   error
+	}{
+		{url: "crm.test/hooks", want: ErrInvalidURL},
+		{url: "ftp://crm.test/hooks", want: ErrInvalidURL},
+		{url: "https://crm.test/hooks", events: []string{"extraction.started"}, want: ErrInvalidEvent},
+		{url: "https://crm.test/hooks", secret: "short", want: ErrInvalidSecret},
+		{url: "https://crm.test/hooks", events: []string{webhooks.EventExtractionFailed}},
+	}
+	for _, tt := range tests {
+		if _, err := service.CreateSubscription(context.Background(), tt.url, tt.secret, tt.events); err != tt.want {
+			t.Errorf("%s %q %v: err = %v, want %v", tt.url, tt.secret, tt.events, err, tt.want)
+		}
+	}
+}
+
+func TestPublishReplay(t *testing.T) {
+	ctx := workspaces.WithID(context.Background(), "acme")
+	repo := webhooks.NewMemoryRepository()
+	service := NewService(log.NewNopLogger(), repo)
+
+	completed, err := service.CreateSubscription(ctx, "https://crm.test/completed", "", []string{webhooks.EventExtractionCompleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(completed.Secret, secretPrefix) {
+		t.Errorf("secret = %q, want a generated one", completed.Secret)
+	}
+	all, err := service.CreateSubscription(ctx, "https://crm.test/all", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another workspace does not see the events of acme.
+	if _, err := service.CreateSubscription(workspaces.WithID(ctx, "globex"), "https://globex.test/all", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Publish(ctx, webhooks.EventExtractionFailed, map[string]string{"url": "https://acme.test"}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := service.ListDeliveries(ctx, 0, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].SubscriptionID != all.ID || deliveries[0].Status != webhooks.StatusPending {
+		t.Fatalf("deliveries = %+v, want a pending one to the subscription to every event", deliveries)
+	}
+	var event Event
+	if err := json.Unmarshal(deliveries[0].Payload, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != deliveries[0].EventID || event.Type != webhooks.EventExtractionFailed || event.WorkspaceID != "acme" {
+		t.Errorf("event = %+v", event)
+	}
+	globex, err := service.ListDeliveries(workspaces.WithID(ctx, "globex"), 0, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(globex) != 0 {
+		t.Errorf("globex deliveries = %+v, want none", globex)
+	}
+
+	replay, err := service.ReplayDelivery(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ID == deliveries[0].ID || replay.ReplayOf != deliveries[0].ID || replay.EventID != deliveries[0].EventID || string(replay.Payload) != string(deliveries[0].Payload) {
+		t.Errorf("replay = %+v, want a new delivery of %+v", replay, deliveries[0])
+	}
+	if _, err := service.ReplayDelivery(workspaces.WithID(ctx, "globex"), deliveries[0].ID); err != webhooks.ErrDeliveryNotFound {
+		t.Errorf("err = %v, want %v when replaying from another workspace", err, webhooks.ErrDeliveryNotFound)
+	}
+}
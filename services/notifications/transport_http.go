@@ -0,0 +1,166 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/solher/hunterio-test/entities/apikeys"
+	"github.com/solher/hunterio-test/entities/webhooks"
+	"github.com/solher/hunterio-test/lib/render"
+	"github.com/solher/hunterio-test/services/auth"
+	"github.com/solher/toolbox/api"
+)
+
+// NewHTTPHandler returns a new HTTP handler for the webhook subscriptions and deliveries.
+func NewHTTPHandler(service Service, negotiator *render.Negotiator) http.Handler {
+	h := &httpHandler{
+		service: service,
+		render:  negotiator,
+	}
+	// The payloads of the deliveries differ by event, and share no CSV columns.
+	deliveryMediaTypes := []string{render.MediaJSON, render.MediaXML, render.MediaNDJSON}
+
+	router := chi.NewRouter()
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWebhooks),
+	).Post("/subscriptions", h.CreateSubscription)
+	router.With(
+		negotiator.Negotiate(render.ListMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWebhooks),
+	).Get("/subscriptions", h.ListSubscriptions)
+	router.With(
+		negotiator.Negotiate(render.ObjectMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWebhooks),
+	).Delete("/subscriptions/{id}", h.DeleteSubscription)
+	router.With(
+		negotiator.Negotiate(deliveryMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWebhooks),
+	).Get("/deliveries", h.ListDeliveries)
+	router.With(
+		negotiator.Negotiate(deliveryMediaTypes...),
+		auth.RequireScope(negotiator, apikeys.ScopeWebhooks),
+	).Post("/deliveries/{id}/replay", h.ReplayDelivery)
+
+	return router
+}
+
+type httpHandler struct {
+	service Service
+	render  render.Renderer
+}
+
+func (h *httpHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.render.RenderError(ctx, w, api.HTTPBodyDecoding, err)
+		return
+	}
+
+	result, err := h.service.CreateSubscription(ctx, req.URL, req.Secret, req.Events)
+	if err != nil {
+		switch err {
+		case ErrInvalidURL, ErrInvalidEvent, ErrInvalidSecret:
+			h.render.RenderError(ctx, w, api.HTTPValidation, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusCreated, result)
+}
+
+func (h *httpHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := h.service.ListSubscriptions(ctx)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	if err := h.service.DeleteSubscription(ctx, id); err != nil {
+		switch err {
+		case webhooks.ErrSubscriptionNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *httpHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var subscriptionID uint64
+	var limit int
+	var err error
+	if value := query.Get("subscription_id"); value != "" {
+		if subscriptionID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+			return
+		}
+	}
+
+	result, err := h.service.ListDeliveries(ctx, subscriptionID, query.Get("status"), limit)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusOK, result)
+}
+
+func (h *httpHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.render.RenderError(ctx, w, api.HTTPQueryParam, err)
+		return
+	}
+
+	result, err := h.service.ReplayDelivery(ctx, id)
+	if err != nil {
+		switch err {
+		case webhooks.ErrDeliveryNotFound, webhooks.ErrSubscriptionNotFound:
+			h.render.RenderError(ctx, w, api.HTTPNotFound, err)
+		default:
+			h.render.RenderError(ctx, w, api.HTTPInternal, err)
+		}
+		return
+	}
+
+	h.render.Render(ctx, w, http.StatusAccepted, result)
+}
@@ -0,0 +1,191 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+// MinRetention is the shortest time the runs can be kept. The purged runs are not accounted anymore,
+// and the monthly budgets and quotas need every run of the month.
+const MinRetention = 31 * 24 * time.Hour
+
+// PurgerConfig sets the retention policy of the runs, and tunes their purge.
+type PurgerConfig struct {
+	// RawRuns is how long every run is kept. Older runs are purged, unless they are the latest of their URL,
+	// which hold its latest people and companies. Zero disables the purge.
+	RawRuns time.Duration
+	// LatestRuns is how long the latest run of each URL is kept, forever if zero. It is refused until the people
+	// and companies are kept aggregated, as the latest runs hold their only copy.
+	LatestRuns time.Duration
+	// Interval is the time between two purges.
+	Interval time.Duration
+	// BatchSize is the maximum number of runs deleted per transaction, so that the locks are held briefly.
+	BatchSize int
+	// PartitionsAhead is the number of monthly partitions created ahead of the current month.
+	PartitionsAhead int
+}
+
+// Validate checks the retention policy keeps the runs long enough.
+func (c PurgerConfig) Validate() error {
+	if c.RawRuns != 0 && c.RawRuns < MinRetention {
+		return fmt.Errorf("the runs must be kept at least %s", MinRetention)
+	}
+	if c.LatestRuns != 0 {
+		return fmt.Errorf("the latest runs cannot expire, as they hold the only copy of the people and companies")
+	}
+	if c.PartitionsAhead < 0 {
+		return fmt.Errorf("invalid number of partitions ahead %d", c.PartitionsAhead)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("invalid purge batch size %d", c.BatchSize)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("invalid purge interval %s", c.Interval)
+	}
+	return nil
+}
+
+// Report counts the runs purged, or that would be purged by a dry run. The runs of the detached partitions
+// are not counted, unless by a dry run.
+type Report struct {
+	DryRun             bool     `json:"dry_run"`
+	DetachedPartitions []string `json:"detached_partitions"`
+	SupersededRuns     int64    `json:"superseded_runs"`
+	LatestRuns         int64    `json:"latest_runs"`
+}
+
+// Partitions maintains the monthly partitions of the runs, when the storage partitions them.
+type Partitions interface {
+	Create(ctx context.Context, monthsAhead int) (int, error)
+	Expired(ctx context.Context, before time.Time) ([]string, error)
+	Detach(ctx context.Context, name string) error
+}
+
+// Purger enforces the retention policy, across every workspace.
+type Purger interface {
+	// Run creates the partitions ahead and purges the runs every interval, until the context is canceled.
+	Run(ctx context.Context) error
+	// Purge deletes the runs past their retention, or only counts them with dryRun. The partitions whose
+	// runs all expired are detached at once.
+	Purge(ctx context.Context, dryRun bool) (*Report, error)
+}
+
+// NewPurger returns a new purger. The partitions are nil if the storage does not partition the runs.
+// Several purgers can share a repository, each batch skipping the runs locked by the others.
+func NewPurger(l log.Logger, extractedDataRepo extracteddata.Repository, partitions Partitions, config PurgerConfig) Purger {
+	return &purger{
+		l:                 l,
+		extractedDataRepo: extractedDataRepo,
+		partitions:        partitions,
+		config:            config,
+	}
+}
+
+type purger struct {
+	l                 log.Logger
+	extractedDataRepo extracteddata.Repository
+	partitions        Partitions
+	config            PurgerConfig
+}
+
+func (p *purger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if p.partitions != nil {
+			created, err := p.partitions.Create(ctx, p.config.PartitionsAhead)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				p.l.Log("msg", "partitions creation failed", "err", err)
+			case created > 0:
+				p.l.Log("msg", "partitions created", "partitions", created)
+			}
+		}
+		if _, err := p.Purge(ctx, false); err != nil && ctx.Err() == nil {
+			p.l.Log("msg", "purge failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *purger) Purge(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun, DetachedPartitions: []string{}}
+	if p.config.RawRuns == 0 {
+		return report, nil
+	}
+
+	now := time.Now().UTC()
+	var err error
+	if p.partitions != nil && p.config.LatestRuns != 0 {
+		// Every run of the partitions ended before the retention of the latest runs is purged anyway.
+		report.DetachedPartitions, err = p.partitions.Expired(ctx, now.Add(-p.config.LatestRuns))
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			for _, name := range report.DetachedPartitions {
+				if err := p.partitions.Detach(ctx, name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	report.SupersededRuns, err = p.purge(ctx, extracteddata.PurgeSuperseded, now.Add(-p.config.RawRuns), dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.LatestRuns != 0 {
+		report.LatestRuns, err = p.purge(ctx, extracteddata.PurgeLatest, now.Add(-p.config.LatestRuns), dryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !dryRun && (report.SupersededRuns+report.LatestRuns > 0 || len(report.DetachedPartitions) > 0) {
+		p.l.Log(
+			"msg", "runs purged",
+			"detached_partitions", strings.Join(report.DetachedPartitions, ","),
+			"superseded_runs", report.SupersededRuns,
+			"latest_runs", report.LatestRuns,
+		)
+	}
+	return report, nil
+}
+
+// purge deletes the runs of a kind created before a date, batch by batch, and returns how many were deleted.
+func (p *purger) purge(ctx context.Context, kind string, createdBefore time.Time, dryRun bool) (int64, error) {
+	purge := extracteddata.Purge{
+		WorkspaceID:   workspaces.AllID,
+		Kind:          kind,
+		CreatedBefore: createdBefore,
+		Limit:         p.config.BatchSize,
+		DryRun:        dryRun,
+	}
+	if dryRun {
+		return p.extractedDataRepo.Purge(ctx, purge)
+	}
+
+	var total int64
+	for {
+		n, err := p.extractedDataRepo.Purge(ctx, purge)
+		total += n
+		if err != nil || n < int64(purge.Limit) {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
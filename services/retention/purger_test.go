@@ -0,0 +1,176 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/solher/hunterio-test/entities/extracteddata"
+	"github.com/solher/hunterio-test/entities/workspaces"
+)
+
+func TestPurger(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	insert := func(workspaceID, url string) *extracteddata.ExtractedData {
+		t.Helper()
+		extractedData, err := repo.Insert(ctx, &extracteddata.ExtractedData{WorkspaceID: workspaceID, URL: url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		return extractedData
+	}
+	count := func() int {
+		t.Helper()
+		found, err := repo.Find(ctx, extracteddata.Search{WorkspaceID: workspaces.AllID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(found)
+	}
+	for range 3 {
+		insert("acme", "https://acme-robotics.test/about")
+	}
+	latest := insert("acme", "https://acme-robotics.test/about")
+	insert("acme", "https://acme-robotics.test/team")
+	insert("globex", "https://acme-robotics.test/about")
+
+	// Every run is past its retention, the latest runs excepted.
+	config := PurgerConfig{RawRuns: time.Nanosecond, BatchSize: 2}
+
+	t.Run("DryRun", func(t *testing.T) {
+		report, err := NewPurger(log.NewNopLogger(), repo, nil, config).Purge(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.DryRun || report.SupersededRuns != 3 || report.LatestRuns != 0 {
+			t.Errorf("report = %+v, want 3 superseded runs", report)
+		}
+		if n := count(); n != 6 {
+			t.Errorf("%d runs left, want 6", n)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		report, err := NewPurger(log.NewNopLogger(), repo, nil, PurgerConfig{BatchSize: 2}).Purge(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.SupersededRuns != 0 || report.LatestRuns != 0 || count() != 6 {
+			t.Errorf("report = %+v, want nothing purged", report)
+		}
+	})
+
+	t.Run("Superseded", func(t *testing.T) {
+		report, err := NewPurger(log.NewNopLogger(), repo, nil, config).Purge(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.SupersededRuns != 3 || report.LatestRuns != 0 {
+			t.Errorf("report = %+v, want the 3 superseded runs purged, in two batches", report)
+		}
+		if _, err := repo.GetByID(ctx, "acme", latest.ID); err != nil {
+			t.Errorf("err = %v, want the latest run kept", err)
+		}
+		if n := count(); n != 3 {
+			t.Errorf("%d runs left, want 3", n)
+		}
+	})
+
+	t.Run("Latest", func(t *testing.T) {
+		config := config
+		config.LatestRuns = time.Nanosecond
+		report, err := NewPurger(log.NewNopLogger(), repo, nil, config).Purge(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.SupersededRuns != 0 || report.LatestRuns != 3 {
+			t.Errorf("report = %+v, want the 3 latest runs purged", report)
+		}
+		if n := count(); n != 0 {
+			t.Errorf("%d runs left, want 0", n)
+		}
+	})
+}
+
+type stubPartitions struct {
+	names    []string
+	before   time.Time
+	detached []string
+}
+
+func (p *stubPartitions) Create(ctx context.Context, monthsAhead int) (int, error) {
+	return 0, nil
+}
+
+func (p *stubPartitions) Expired(ctx context.Context, before time.Time) ([]string, error) {
+	p.before = before
+	return p.names, nil
+}
+
+func (p *stubPartitions) Detach(ctx context.Context, name string) error {
+	p.detached = append(p.detached, name)
+	return nil
+}
+
+func TestPurgerPartitions(t *testing.T) {
+	ctx := context.Background()
+	repo := extracteddata.NewMemoryRepository()
+	partitions := &stubPartitions{names: []string{"extracted_data_2024_01", "extracted_data_2024_02"}}
+
+	// The partitions are kept as long as the latest runs are.
+	purger := NewPurger(log.NewNopLogger(), repo, partitions, PurgerConfig{RawRuns: 90 * 24 * time.Hour, BatchSize: 10})
+	report, err := purger.Purge(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.DetachedPartitions) != 0 || !partitions.before.IsZero() {
+		t.Errorf("report = %+v, want no partition detached", report)
+	}
+
+	purger = NewPurger(log.NewNopLogger(), repo, partitions, PurgerConfig{
+		RawRuns:    90 * 24 * time.Hour,
+		LatestRuns: 365 * 24 * time.Hour,
+		BatchSize:  10,
+	})
+	report, err = purger.Purge(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.DetachedPartitions) != 2 || len(partitions.detached) != 0 {
+		t.Errorf("report = %+v, detached %v, want 2 partitions listed and none detached", report, partitions.detached)
+	}
+	if got := time.Since(partitions.before); got < 365*24*time.Hour || got > 366*24*time.Hour {
+		t.Errorf("expired before %s, want a year ago", partitions.before)
+	}
+
+	if _, err := purger.Purge(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions.detached) != 2 || partitions.detached[0] != "extracted_data_2024_01" {
+		t.Errorf("detached %v, want the 2 expired partitions", partitions.detached)
+	}
+}
+
+func TestPurgerConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		config PurgerConfig
+		valid  bool
+	}{
+		{PurgerConfig{Interval: time.Hour, BatchSize: 100}, true},
+		{PurgerConfig{RawRuns: 90 * 24 * time.Hour, Interval: time.Hour, BatchSize: 100}, true},
+		{PurgerConfig{RawRuns: 90 * 24 * time.Hour, LatestRuns: 365 * 24 * time.Hour, Interval: time.Hour, BatchSize: 100}, false},
+		{PurgerConfig{RawRuns: 7 * 24 * time.Hour, Interval: time.Hour, BatchSize: 100}, false},
+		{PurgerConfig{RawRuns: 90 * 24 * time.Hour, LatestRuns: 60 * 24 * time.Hour, Interval: time.Hour, BatchSize: 100}, false},
+		{PurgerConfig{RawRuns: 90 * 24 * time.Hour, Interval: time.Hour}, false},
+		{PurgerConfig{LatestRuns: 365 * 24 * time.Hour, Interval: time.Hour, BatchSize: 100}, false},
+		{PurgerConfig{Interval: time.Hour, BatchSize: 100, PartitionsAhead: -1}, false},
+		{PurgerConfig{BatchSize: 100}, false},
+	} {
+		if err := tc.config.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %t", tc.config, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE extracted_data (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  url TEXT NOT NULL,
+  people TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(people)),
+  companies TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(companies)),
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX extracted_data_by_url ON extracted_data (url, created_at);
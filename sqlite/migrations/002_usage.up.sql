@@ -0,0 +1,7 @@
+ALTER TABLE extracted_data ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE extracted_data ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE extracted_data ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE extracted_data ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE extracted_data ADD COLUMN cost_usd REAL NOT NULL DEFAULT 0;
+
+CREATE INDEX extracted_data_by_created_at ON extracted_data (created_at);
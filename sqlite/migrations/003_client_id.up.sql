@@ -0,0 +1,3 @@
+ALTER TABLE extracted_data ADD COLUMN client_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX extracted_data_by_client_id ON extracted_data (client_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE extracted_data ADD COLUMN api_key_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX extracted_data_by_api_key_id ON extracted_data (api_key_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE extracted_data ADD COLUMN workspace_id TEXT NOT NULL DEFAULT 'default';
+
+DROP INDEX extracted_data_by_url;
+CREATE INDEX extracted_data_by_url ON extracted_data (workspace_id, url, created_at);
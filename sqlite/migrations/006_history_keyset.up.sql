@@ -0,0 +1,2 @@
+-- The history is paginated by keyset on (created_at, id), most recent first.
+CREATE INDEX extracted_data_by_workspace_id ON extracted_data (workspace_id, created_at, id);
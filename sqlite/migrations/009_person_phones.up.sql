@@ -0,0 +1,18 @@
+-- The runs hold the phone numbers of their people normalized to their digits, as people.NormalizePhone does, so
+-- that the phones are searched as in Go and Postgres. The existing runs are backfilled by keeping the digits of
+-- each phone, character by character.
+ALTER TABLE extracted_data ADD COLUMN person_phones TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(person_phones));
+
+UPDATE extracted_data
+SET person_phones = (
+  WITH RECURSIVE phones (n, phone, i, digits) AS (
+    SELECT p.key, json_extract(p.value, '$.contact.phone'), 1, ''
+    FROM json_each(CASE WHEN json_type(extracted_data.people) = 'array' THEN extracted_data.people ELSE '[]' END) p
+    WHERE json_type(p.value, '$.contact.phone') = 'text'
+    UNION ALL
+    SELECT n, phone, i + 1, digits || CASE WHEN substr(phone, i, 1) BETWEEN '0' AND '9' THEN substr(phone, i, 1) ELSE '' END
+    FROM phones
+    WHERE i <= length(phone)
+  )
+  SELECT json_group_array(digits) FROM (SELECT digits FROM phones WHERE i = length(phone) + 1 AND digits <> '' ORDER BY n)
+);